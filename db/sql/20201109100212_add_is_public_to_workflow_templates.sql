@@ -0,0 +1,5 @@
+-- +goose Up
+ALTER TABLE workflow_templates ADD COLUMN is_public BOOLEAN NOT NULL DEFAULT false;
+
+-- +goose Down
+ALTER TABLE workflow_templates DROP COLUMN is_public;
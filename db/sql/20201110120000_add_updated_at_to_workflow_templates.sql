@@ -0,0 +1,9 @@
+-- +goose Up
+ALTER TABLE workflow_templates ADD COLUMN updated_at timestamp NOT NULL DEFAULT (NOW() at time zone 'utc');
+UPDATE workflow_templates wt SET updated_at = COALESCE(
+    (SELECT MAX(wtv.created_at) FROM workflow_template_versions wtv WHERE wtv.workflow_template_id = wt.id),
+    wt.created_at
+);
+
+-- +goose Down
+ALTER TABLE workflow_templates DROP COLUMN updated_at;
@@ -0,0 +1,17 @@
+-- +goose Up
+CREATE TABLE workflow_template_audit_logs
+(
+    id                   serial PRIMARY KEY,
+    workflow_template_id integer NOT NULL REFERENCES workflow_templates ON DELETE CASCADE,
+    action               varchar(30) NOT NULL,
+    actor                text NOT NULL DEFAULT '',
+    version              bigint,
+
+    -- auditing info
+    created_at           timestamp NOT NULL DEFAULT (NOW() at time zone 'utc')
+);
+
+CREATE INDEX workflow_template_audit_logs_workflow_template_id_idx ON workflow_template_audit_logs (workflow_template_id);
+
+-- +goose Down
+DROP TABLE workflow_template_audit_logs;
@@ -0,0 +1,17 @@
+-- +goose Up
+CREATE TABLE workflow_template_fragments
+(
+    id                   serial PRIMARY KEY,
+    workflow_template_id integer NOT NULL REFERENCES workflow_templates ON DELETE CASCADE,
+    name                 varchar(255) NOT NULL,
+    manifest             text NOT NULL,
+
+    -- auditing info
+    created_at           timestamp NOT NULL DEFAULT (NOW() at time zone 'utc'),
+    modified_at          timestamp,
+
+    UNIQUE (workflow_template_id, name)
+);
+
+-- +goose Down
+DROP TABLE workflow_template_fragments;
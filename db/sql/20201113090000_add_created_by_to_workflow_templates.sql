@@ -0,0 +1,5 @@
+-- +goose Up
+ALTER TABLE workflow_templates ADD COLUMN created_by TEXT NOT NULL DEFAULT '';
+
+-- +goose Down
+ALTER TABLE workflow_templates DROP COLUMN created_by;
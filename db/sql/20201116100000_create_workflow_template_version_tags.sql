@@ -0,0 +1,17 @@
+-- +goose Up
+CREATE TABLE workflow_template_version_tags
+(
+    id                           serial PRIMARY KEY,
+    workflow_template_id         integer NOT NULL REFERENCES workflow_templates ON DELETE CASCADE,
+    workflow_template_version_id integer NOT NULL REFERENCES workflow_template_versions ON DELETE CASCADE,
+    tag                          varchar(255) NOT NULL,
+
+    -- auditing info
+    created_at                   timestamp NOT NULL DEFAULT (NOW() at time zone 'utc'),
+    modified_at                  timestamp,
+
+    UNIQUE (workflow_template_id, tag)
+);
+
+-- +goose Down
+DROP TABLE workflow_template_version_tags;
@@ -0,0 +1,9 @@
+-- +goose Up
+ALTER TABLE workflow_templates ADD COLUMN archived_at TIMESTAMP;
+
+UPDATE workflow_templates
+    SET archived_at = updated_at
+    WHERE is_archived = true;
+
+-- +goose Down
+ALTER TABLE workflow_templates DROP COLUMN archived_at;
@@ -0,0 +1,7 @@
+-- +goose Up
+ALTER TABLE workflow_templates ADD COLUMN idempotency_key TEXT;
+CREATE UNIQUE INDEX workflow_templates_namespace_idempotency_key_key ON workflow_templates (namespace, idempotency_key) WHERE idempotency_key IS NOT NULL;
+
+-- +goose Down
+DROP INDEX workflow_templates_namespace_idempotency_key_key;
+ALTER TABLE workflow_templates DROP COLUMN idempotency_key;
@@ -0,0 +1,5 @@
+-- +goose Up
+ALTER TABLE workflow_templates ADD COLUMN base_template_uid TEXT NOT NULL DEFAULT '';
+
+-- +goose Down
+ALTER TABLE workflow_templates DROP COLUMN base_template_uid;
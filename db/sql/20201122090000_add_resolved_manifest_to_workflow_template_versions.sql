@@ -0,0 +1,5 @@
+-- +goose Up
+ALTER TABLE workflow_template_versions ADD COLUMN resolved_manifest TEXT NOT NULL DEFAULT '';
+
+-- +goose Down
+ALTER TABLE workflow_template_versions DROP COLUMN resolved_manifest;
@@ -0,0 +1,23 @@
+-- +goose Up
+ALTER TABLE workflow_template_audit_logs ADD COLUMN namespace varchar(30) NOT NULL DEFAULT '';
+ALTER TABLE workflow_template_audit_logs ADD COLUMN uid varchar(30) NOT NULL DEFAULT '';
+UPDATE workflow_template_audit_logs al SET namespace = wt.namespace, uid = wt.uid
+FROM workflow_templates wt
+WHERE wt.id = al.workflow_template_id;
+CREATE INDEX workflow_template_audit_logs_namespace_uid_idx ON workflow_template_audit_logs (namespace, uid);
+
+ALTER TABLE workflow_template_audit_logs ALTER COLUMN workflow_template_id DROP NOT NULL;
+ALTER TABLE workflow_template_audit_logs DROP CONSTRAINT workflow_template_audit_logs_workflow_template_id_fkey;
+ALTER TABLE workflow_template_audit_logs ADD CONSTRAINT workflow_template_audit_logs_workflow_template_id_fkey
+    FOREIGN KEY (workflow_template_id) REFERENCES workflow_templates ON DELETE SET NULL;
+
+-- +goose Down
+DELETE FROM workflow_template_audit_logs WHERE workflow_template_id IS NULL;
+ALTER TABLE workflow_template_audit_logs DROP CONSTRAINT workflow_template_audit_logs_workflow_template_id_fkey;
+ALTER TABLE workflow_template_audit_logs ADD CONSTRAINT workflow_template_audit_logs_workflow_template_id_fkey
+    FOREIGN KEY (workflow_template_id) REFERENCES workflow_templates ON DELETE CASCADE;
+ALTER TABLE workflow_template_audit_logs ALTER COLUMN workflow_template_id SET NOT NULL;
+
+DROP INDEX workflow_template_audit_logs_namespace_uid_idx;
+ALTER TABLE workflow_template_audit_logs DROP COLUMN uid;
+ALTER TABLE workflow_template_audit_logs DROP COLUMN namespace;
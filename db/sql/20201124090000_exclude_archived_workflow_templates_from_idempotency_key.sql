@@ -0,0 +1,7 @@
+-- +goose Up
+DROP INDEX workflow_templates_namespace_idempotency_key_key;
+CREATE UNIQUE INDEX workflow_templates_namespace_idempotency_key_key ON workflow_templates (namespace, idempotency_key) WHERE idempotency_key IS NOT NULL AND NOT is_archived;
+
+-- +goose Down
+DROP INDEX workflow_templates_namespace_idempotency_key_key;
+CREATE UNIQUE INDEX workflow_templates_namespace_idempotency_key_key ON workflow_templates (namespace, idempotency_key) WHERE idempotency_key IS NOT NULL;
@@ -0,0 +1,8 @@
+-- +goose Up
+ALTER TABLE workflow_template_versions ADD COLUMN manifest_hash varchar(64);
+UPDATE workflow_template_versions SET manifest_hash = encode(sha256(convert_to(manifest, 'UTF8')), 'hex');
+CREATE INDEX workflow_template_versions_workflow_template_id_manifest_hash_idx ON workflow_template_versions (workflow_template_id, manifest_hash);
+
+-- +goose Down
+DROP INDEX workflow_template_versions_workflow_template_id_manifest_hash_idx;
+ALTER TABLE workflow_template_versions DROP COLUMN manifest_hash;
@@ -3,15 +3,18 @@ package v1
 import (
 	"fmt"
 	sq "github.com/Masterminds/squirrel"
+	wfv1 "github.com/argoproj/argo/pkg/apis/workflow/v1alpha1"
 	argoprojv1alpha1 "github.com/argoproj/argo/pkg/client/clientset/versioned/typed/workflow/v1alpha1"
 	"github.com/jmoiron/sqlx"
 	"github.com/onepanelio/core/pkg/util/gcs"
 	"github.com/onepanelio/core/pkg/util/router"
 	"github.com/onepanelio/core/pkg/util/s3"
 	log "github.com/sirupsen/logrus"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/client-go/kubernetes"
 	"k8s.io/client-go/rest"
 	"k8s.io/client-go/tools/clientcmd"
+	"time"
 )
 
 type Config = rest.Config
@@ -20,16 +23,101 @@ var sb = sq.StatementBuilder.PlaceholderFormat(sq.Dollar)
 
 type Client struct {
 	Token string
+	// Actor is the user the client acts on behalf of. It is recorded in audit logs.
+	Actor string
 	kubernetes.Interface
 	argoprojV1alpha1 argoprojv1alpha1.ArgoprojV1alpha1Interface
+	// argoWatchV1alpha1 is the argo client without the timeout of SetTimeouts, which would cut long running watches.
+	// nil means argoprojV1alpha1 has no timeout and is used.
+	argoWatchV1alpha1 argoprojv1alpha1.ArgoprojV1alpha1Interface
 	*DB
 	systemConfig SystemConfig
+	// NamespaceDefaultLabels are tag labels, keyed by namespace, added to every workflow template created in the namespace.
+	// Labels supplied with the workflow template take precedence.
+	// They are only set on the argo workflow templates, the database keeps the labels supplied with the workflow template,
+	// and they are left out when labels are read back from argo, like when inheriting labels or self healing.
+	NamespaceDefaultLabels map[string]map[string]string
+	// StrictValidation enables workflow template validation checks against resources in the namespace,
+	// like referenced secrets and config maps existing. They are off by default as the resources may be created later.
+	// NewClient sets it from the strictWorkflowTemplateValidation value of the system config.
+	StrictValidation bool
+	// WorkflowTemplateSelfHeal sets how GetWorkflowTemplate recovers a workflow template whose database record is missing
+	// from its argo workflow templates. By default, it does not.
+	WorkflowTemplateSelfHeal WorkflowTemplateSelfHeal
+	// WorkflowTemplateCache caches the workflow templates loaded by GetWorkflowTemplate. nil means there is no cache.
+	WorkflowTemplateCache *WorkflowTemplateCache
+	// argoListLimiter limits how many argo list calls run at once. nil means there is no limit.
+	argoListLimiter chan struct{}
+	// config is the kubernetes config the client was created with, see SetTimeouts.
+	config *Config
 }
 
 func (c *Client) ArgoprojV1alpha1() argoprojv1alpha1.ArgoprojV1alpha1Interface {
 	return c.argoprojV1alpha1
 }
 
+// argoWatchClient returns the argo client to watch resources with, which has no timeout
+func (c *Client) argoWatchClient() argoprojv1alpha1.ArgoprojV1alpha1Interface {
+	if c.argoWatchV1alpha1 != nil {
+		return c.argoWatchV1alpha1
+	}
+
+	return c.argoprojV1alpha1
+}
+
+// SetArgoListConcurrencyLimit sets the maximum number of argo list calls the client runs at once.
+// A limit of 0 or less removes the limit. It should be set before the client is used.
+func (c *Client) SetArgoListConcurrencyLimit(limit int) {
+	if limit <= 0 {
+		c.argoListLimiter = nil
+		return
+	}
+
+	c.argoListLimiter = make(chan struct{}, limit)
+}
+
+// listArgoWorkflowTemplatesLimited lists the argo workflow templates in the namespace,
+// waiting if the argo list concurrency limit has been reached.
+func (c *Client) listArgoWorkflowTemplatesLimited(namespace string, opts metav1.ListOptions) (*wfv1.WorkflowTemplateList, error) {
+	if c.argoListLimiter != nil {
+		c.argoListLimiter <- struct{}{}
+		defer func() { <-c.argoListLimiter }()
+	}
+
+	return c.ArgoprojV1alpha1().WorkflowTemplates(namespace).List(opts)
+}
+
+// SetTimeouts sets the deadline of each database query and argo api call the client makes, so a stuck dependency
+// fails with a DeadlineExceeded error instead of blocking the caller. A timeout of 0 means there is no deadline.
+// The client gets its own copy of the DB, so other clients sharing the connection keep their timeout.
+// Watches of argo resources keep running without a deadline. The client is not changed if an error is returned.
+func (c *Client) SetTimeouts(dbTimeout, argoTimeout time.Duration) error {
+	if c.config == nil {
+		return fmt.Errorf("the client has no kubernetes config to set the argo timeout on")
+	}
+
+	// the argo client does not take a context, so the deadline is set on its http client
+	argoConfig := rest.CopyConfig(c.config)
+	argoConfig.Timeout = argoTimeout
+
+	argoClient, err := argoprojv1alpha1.NewForConfig(argoConfig)
+	if err != nil {
+		return err
+	}
+
+	if c.DB != nil {
+		db := *c.DB
+		db.Timeout = dbTimeout
+		c.DB = &db
+	}
+	if c.argoWatchV1alpha1 == nil {
+		c.argoWatchV1alpha1 = c.argoprojV1alpha1
+	}
+	c.argoprojV1alpha1 = argoClient
+
+	return nil
+}
+
 func NewConfig() (config *Config) {
 	config, err := clientcmd.NewNonInteractiveDeferredLoadingClientConfig(
 		clientcmd.NewDefaultClientConfigLoadingRules(), &clientcmd.ConfigOverrides{}).ClientConfig()
@@ -51,6 +139,7 @@ func GetDefaultClient() (*Client, error) {
 	if err != nil {
 		return nil, err
 	}
+	client.StrictValidation = config.StrictWorkflowTemplateValidation()
 
 	dbDriverName, dbDataSourceName := config.DatabaseConnection()
 	client.DB = NewDB(sqlx.MustConnect(dbDriverName, dbDataSourceName))
@@ -97,6 +186,8 @@ func NewClient(config *Config, db *DB, systemConfig SystemConfig) (client *Clien
 		argoprojV1alpha1: argoClient,
 		DB:               db,
 		systemConfig:     systemConfig,
+		StrictValidation: systemConfig.StrictWorkflowTemplateValidation(),
+		config:           config,
 	}, nil
 }
 
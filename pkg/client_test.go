@@ -4,12 +4,14 @@ import (
 	"flag"
 	"fmt"
 	argoFake "github.com/argoproj/argo/pkg/client/clientset/versioned/fake"
+	argoFakeV1alpha1 "github.com/argoproj/argo/pkg/client/clientset/versioned/typed/workflow/v1alpha1/fake"
 	"github.com/jmoiron/sqlx"
 	"github.com/pressly/goose"
 	v1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/client-go/kubernetes/fake"
+	k8stesting "k8s.io/client-go/testing"
 	"log"
 	"os"
 	"testing"
@@ -97,6 +99,20 @@ func DefaultTestClient() *Client {
 	return NewTestClient(database, mockSystemConfigMap, mockSystemSecret)
 }
 
+// failArgoWorkflowTemplateUpdate makes one argo workflow template update of the test client fail,
+// the one after the first allowed updates. The updates after it succeed again.
+func failArgoWorkflowTemplateUpdate(c *Client, allowed int) {
+	updates := 0
+	c.argoprojV1alpha1.(*argoFakeV1alpha1.FakeArgoprojV1alpha1).PrependReactor("update", "workflowtemplates",
+		func(action k8stesting.Action) (bool, runtime.Object, error) {
+			updates++
+			if updates == allowed+1 {
+				return true, nil, fmt.Errorf("update failed")
+			}
+			return false, nil, nil
+		})
+}
+
 func clearDatabase(t *testing.T) {
 	// We do not delete from goose_db_version as we need it to mark the migrations as ran.
 	query := `
@@ -104,6 +120,9 @@ func clearDatabase(t *testing.T) {
 		DELETE FROM workflow_executions;
 		DELETE FROM cron_workflows;
 		DELETE FROM workspace_templates;
+		DELETE FROM workflow_template_audit_logs;
+		DELETE FROM workflow_template_fragments;
+		DELETE FROM workflow_template_version_tags;
 		DELETE FROM workflow_templates;
 		DELETE FROM workspace_template_versions;
 		DELETE FROM workflow_template_versions;
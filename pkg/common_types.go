@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"github.com/onepanelio/core/pkg/util/ptr"
 	"gopkg.in/yaml.v2"
+	"sort"
 )
 
 // +genclient
@@ -15,16 +16,29 @@ type ParameterOption struct {
 }
 
 type Parameter struct {
-	Name        string             `json:"name" protobuf:"bytes,1,opt,name=name"`
-	Value       *string            `json:"value,omitempty" protobuf:"bytes,2,opt,name=value"`
-	Visibility  *string            `json:"visibility,omitempty"`
-	Type        string             `json:"type,omitempty" protobuf:"bytes,3,opt,name=type"`
-	DisplayName *string            `json:"displayName,omitempty" yaml:"displayName"`
-	Hint        *string            `json:"hint,omitempty" protobuf:"bytes,5,opt,name=hint"`
-	Options     []*ParameterOption `json:"options,omitempty" protobuf:"bytes,6,opt,name=options"`
-	Required    bool               `json:"required,omitempty" protobuf:"bytes,7,opt,name=required"`
+	Name         string             `json:"name" protobuf:"bytes,1,opt,name=name"`
+	Value        *string            `json:"value,omitempty" protobuf:"bytes,2,opt,name=value"`
+	Visibility   *string            `json:"visibility,omitempty"`
+	Type         string             `json:"type,omitempty" protobuf:"bytes,3,opt,name=type"`
+	DisplayName  *string            `json:"displayName,omitempty" yaml:"displayName"`
+	Hint         *string            `json:"hint,omitempty" protobuf:"bytes,5,opt,name=hint"`
+	Options      []*ParameterOption `json:"options,omitempty" protobuf:"bytes,6,opt,name=options"`
+	Required     bool               `json:"required,omitempty" protobuf:"bytes,7,opt,name=required"`
+	DisplayOrder *int               `json:"displayOrder,omitempty" yaml:"displayOrder"`
+	Sensitive    bool               `json:"sensitive,omitempty"` // The value is a secret, like a token or password, see MaskSensitiveParameters.
+	Group        *string            `json:"group,omitempty"`
+	GroupOrder   *int               `json:"groupOrder,omitempty" yaml:"groupOrder"`
 }
 
+// ParameterGroup is a named section of the parameters of a workflow template, see GroupParameters
+type ParameterGroup struct {
+	Name       string // Empty for the parameters without a group.
+	Parameters []Parameter
+}
+
+// SensitiveParameterPlaceholder replaces the values of sensitive parameters, see MaskSensitiveParameters
+const SensitiveParameterPlaceholder = "********"
+
 // IsValidParameter returns nil if the parameter is valid or an error otherwise
 func IsValidParameter(parameter Parameter) error {
 	if parameter.Visibility == nil {
@@ -50,6 +64,29 @@ func IsValidParameters(parameters []Parameter) error {
 	return nil
 }
 
+// MissingRequiredParameters returns the names of the required parameters that have neither a default value
+// nor a value in supplied. Empty values are treated as missing.
+func MissingRequiredParameters(parameters []Parameter, supplied []Parameter) (missing []string) {
+	suppliedValues := make(map[string]bool)
+	for _, param := range supplied {
+		if param.Value != nil && *param.Value != "" {
+			suppliedValues[param.Name] = true
+		}
+	}
+
+	for _, param := range parameters {
+		if !param.Required || suppliedValues[param.Name] {
+			continue
+		}
+
+		if param.Value == nil || *param.Value == "" {
+			missing = append(missing, param.Name)
+		}
+	}
+
+	return
+}
+
 // Arguments are the arguments in a manifest file.
 type Arguments struct {
 	Parameters []Parameter `json:"parameters"`
@@ -94,6 +131,75 @@ func ParseParametersFromManifest(manifest []byte) ([]Parameter, error) {
 	return manifestResult.Arguments.Parameters, nil
 }
 
+// MaskSensitiveParameters returns a copy of the manifest where the values of the parameters marked as sensitive
+// are replaced by SensitiveParameterPlaceholder. Parameters without a value are left as they are.
+// The names of the masked parameters are returned as well.
+func MaskSensitiveParameters(manifest []byte) (result []byte, masked []string, err error) {
+	masked = make([]string, 0)
+
+	data := yaml.MapSlice{}
+	if err = yaml.Unmarshal(manifest, &data); err != nil {
+		return nil, nil, err
+	}
+
+	for _, item := range data {
+		if item.Key != "arguments" {
+			continue
+		}
+
+		arguments, ok := item.Value.(yaml.MapSlice)
+		if !ok {
+			break
+		}
+
+		for _, argument := range arguments {
+			if argument.Key != "parameters" {
+				continue
+			}
+
+			parameters, ok := argument.Value.([]interface{})
+			if !ok {
+				break
+			}
+
+			for _, parameter := range parameters {
+				parameterMap, ok := parameter.(yaml.MapSlice)
+				if !ok {
+					continue
+				}
+
+				name, sensitive := "", false
+				for _, field := range parameterMap {
+					switch field.Key {
+					case "name":
+						name = fmt.Sprintf("%v", field.Value)
+					case "sensitive":
+						sensitive = field.Value == true
+					}
+				}
+				if !sensitive {
+					continue
+				}
+
+				for i := range parameterMap {
+					if parameterMap[i].Key == "value" && parameterMap[i].Value != nil {
+						parameterMap[i].Value = SensitiveParameterPlaceholder
+						masked = append(masked, name)
+					}
+				}
+			}
+		}
+	}
+
+	if len(masked) == 0 {
+		return manifest, masked, nil
+	}
+
+	result, err = yaml.Marshal(data)
+
+	return
+}
+
 // MapParametersByName returns a map where the parameter name is the key and the parameter is the value
 func MapParametersByName(parameters []Parameter) map[string]Parameter {
 	result := make(map[string]Parameter)
@@ -104,3 +210,57 @@ func MapParametersByName(parameters []Parameter) map[string]Parameter {
 
 	return result
 }
+
+// GroupParameters buckets the parameters by their group, so long forms can be shown in sections.
+// Parameters set their section with "group" and the position of the section with "groupOrder".
+//
+// The parameters without a group come first. Groups are ordered by their groupOrder if they have one,
+// otherwise by where their first parameter is. In a group, parameters are ordered by their displayOrder if they have one,
+// otherwise they keep their order. An error is returned if the group orders are not consistent, see validateParameterGroups.
+func GroupParameters(parameters []Parameter) ([]ParameterGroup, error) {
+	if err := validateParameterGroups(parameters); err != nil {
+		return nil, err
+	}
+
+	groups := make([]ParameterGroup, 0)
+	positions := make(map[string]int)
+	orders := make(map[string]int)
+	for _, parameter := range parameters {
+		name := ""
+		if parameter.Group != nil {
+			name = *parameter.Group
+		}
+		if parameter.GroupOrder != nil {
+			orders[name] = *parameter.GroupOrder
+		}
+
+		position, ok := positions[name]
+		if !ok {
+			position = len(groups)
+			positions[name] = position
+			groups = append(groups, ParameterGroup{Name: name})
+		}
+		groups[position].Parameters = append(groups[position].Parameters, parameter)
+	}
+
+	sort.SliceStable(groups, func(i, j int) bool {
+		if groups[i].Name == "" || groups[j].Name == "" {
+			return groups[i].Name == ""
+		}
+
+		return orders[groups[i].Name] < orders[groups[j].Name]
+	})
+
+	for _, group := range groups {
+		groupParameters := group.Parameters
+		sort.SliceStable(groupParameters, func(i, j int) bool {
+			if groupParameters[i].DisplayOrder == nil || groupParameters[j].DisplayOrder == nil {
+				return false
+			}
+
+			return *groupParameters[i].DisplayOrder < *groupParameters[j].DisplayOrder
+		})
+	}
+
+	return groups, nil
+}
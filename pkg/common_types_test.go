@@ -1,6 +1,7 @@
 package v1
 
 import (
+	"github.com/onepanelio/core/pkg/util/ptr"
 	"github.com/stretchr/testify/assert"
 	"testing"
 )
@@ -66,3 +67,114 @@ func TestParseParametersFromManifest(t *testing.T) {
 	// Make sure string values are correctly parsed
 	assert.Equal(t, *keyedParameters["extras"].Value, "none")
 }
+
+// TestMissingRequiredParameters makes sure required parameters without a default or supplied value are returned
+func TestMissingRequiredParameters(t *testing.T) {
+	parameters := []Parameter{
+		{Name: "with-default", Value: ptr.String("default"), Required: true},
+		{Name: "supplied", Required: true},
+		{Name: "missing", Required: true},
+		{Name: "empty", Value: ptr.String(""), Required: true},
+		{Name: "optional"},
+	}
+	supplied := []Parameter{
+		{Name: "supplied", Value: ptr.String("value")},
+	}
+
+	assert.Equal(t, []string{"missing", "empty"}, MissingRequiredParameters(parameters, supplied))
+	assert.Empty(t, MissingRequiredParameters(parameters[:2], supplied))
+}
+
+// TestMaskSensitiveParameters makes sure only the values of sensitive parameters are masked
+func TestMaskSensitiveParameters(t *testing.T) {
+	manifest := `entrypoint: main
+arguments:
+  parameters:
+  - name: api-token
+    value: secret
+    sensitive: true
+  - name: region
+    value: us-west-2
+  - name: password
+    sensitive: true
+templates:
+- name: main
+  container:
+    image: alpine
+`
+	result, masked, err := MaskSensitiveParameters([]byte(manifest))
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"api-token"}, masked)
+
+	parameters, err := ParseParametersFromManifest(result)
+	assert.Nil(t, err)
+	keyedParameters := MapParametersByName(parameters)
+	assert.Equal(t, SensitiveParameterPlaceholder, *keyedParameters["api-token"].Value)
+	assert.True(t, keyedParameters["api-token"].Sensitive)
+	assert.Equal(t, "us-west-2", *keyedParameters["region"].Value)
+	assert.Nil(t, keyedParameters["password"].Value)
+	assert.NotContains(t, string(result), "secret")
+	assert.Contains(t, string(result), "entrypoint: main")
+
+	unchanged, masked, err := MaskSensitiveParameters([]byte("entrypoint: main\n"))
+	assert.Nil(t, err)
+	assert.Empty(t, masked)
+	assert.Equal(t, "entrypoint: main\n", string(unchanged))
+}
+
+// TestGroupParameters makes sure parameters are bucketed into ordered groups and inconsistent group orders are rejected
+func TestGroupParameters(t *testing.T) {
+	manifest := `arguments:
+  parameters:
+  - name: epochs
+    group: training
+    groupOrder: 2
+  - name: region
+  - name: bucket
+    group: data
+    groupOrder: 1
+  - name: batch-size
+    group: training
+  - name: learning-rate
+    group: training
+    groupOrder: 2
+`
+	parameters, err := ParseParametersFromManifest([]byte(manifest))
+	assert.Nil(t, err)
+
+	groups, err := GroupParameters(parameters)
+	assert.Nil(t, err)
+	names := make([]string, 0)
+	for _, group := range groups {
+		names = append(names, group.Name)
+	}
+	assert.Equal(t, []string{"", "data", "training"}, names)
+	assert.Len(t, groups[0].Parameters, 1)
+	assert.Equal(t, "epochs", groups[2].Parameters[0].Name)
+	assert.Equal(t, "learning-rate", groups[2].Parameters[2].Name)
+
+	// without group orders, groups keep the order of their first parameter
+	parameters[2].GroupOrder = nil
+	parameters[0].GroupOrder = nil
+	parameters[4].GroupOrder = nil
+	groups, err = GroupParameters(parameters)
+	assert.Nil(t, err)
+	assert.Equal(t, "training", groups[1].Name)
+
+	parameters[4].GroupOrder = ptr.Int(3)
+	parameters[0].GroupOrder = ptr.Int(2)
+	_, err = GroupParameters(parameters)
+	assert.EqualError(t, err, "group 'training' has different group orders 2 and 3")
+
+	parameters[4].GroupOrder = nil
+	_, err = GroupParameters(parameters)
+	assert.EqualError(t, err, "group 'data' has no group order, it is required once any group has one")
+
+	parameters[2].GroupOrder = ptr.Int(2)
+	_, err = GroupParameters(parameters)
+	assert.EqualError(t, err, "groups 'training' and 'data' have the same group order 2")
+
+	parameters[1].GroupOrder = ptr.Int(1)
+	_, err = GroupParameters(parameters)
+	assert.EqualError(t, err, "parameter 'region' has a group order but no group")
+}
@@ -5,6 +5,8 @@ import (
 	"github.com/onepanelio/core/pkg/util"
 	log "github.com/sirupsen/logrus"
 	"google.golang.org/grpc/codes"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/resource"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"sigs.k8s.io/yaml"
 )
@@ -22,6 +24,55 @@ func (c *Client) getConfigMap(namespace, name string) (configMap *ConfigMap, err
 	return
 }
 
+// GetNamespaceParameterDefaults returns the parameter default values of the namespace, by parameter name.
+// They are read from the parameterDefaults key of the onepanel config map in the namespace, as a YAML map.
+// If the config map or the key do not exist, there are no defaults.
+func (c *Client) GetNamespaceParameterDefaults(namespace string) (defaults map[string]string, err error) {
+	defaults = make(map[string]string)
+
+	configMap, err := c.getConfigMap(namespace, "onepanel")
+	if err != nil {
+		if errors.IsNotFound(err) {
+			return defaults, nil
+		}
+		return nil, err
+	}
+
+	if err := yaml.Unmarshal([]byte(configMap.Data["parameterDefaults"]), &defaults); err != nil {
+		return nil, util.NewUserError(codes.InvalidArgument, "Namespace parameterDefaults config is not a map of parameter names to values.")
+	}
+	if defaults == nil {
+		defaults = make(map[string]string)
+	}
+
+	return defaults, nil
+}
+
+// GetNamespaceMaxVolumeClaimStorage returns the most storage a volume claim template of a workflow template in the namespace
+// may request. It is read from the maxVolumeClaimStorage key of the onepanel config map in the namespace, like 100Gi.
+// If the config map or the key do not exist, there is no limit and nil is returned.
+func (c *Client) GetNamespaceMaxVolumeClaimStorage(namespace string) (*resource.Quantity, error) {
+	configMap, err := c.getConfigMap(namespace, "onepanel")
+	if err != nil {
+		if errors.IsNotFound(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	value, ok := configMap.Data["maxVolumeClaimStorage"]
+	if !ok || value == "" {
+		return nil, nil
+	}
+
+	maxStorage, err := resource.ParseQuantity(value)
+	if err != nil {
+		return nil, util.NewUserError(codes.InvalidArgument, "Namespace maxVolumeClaimStorage config is not a storage quantity, like 100Gi.")
+	}
+
+	return &maxStorage, nil
+}
+
 // ClearSystemConfigCache wipes out the cached system configuration so that the next call to
 // GetSystemConfig will pull it from the resources
 func (c *Client) ClearSystemConfigCache() {
@@ -8,6 +8,7 @@ import (
 	"gopkg.in/yaml.v3"
 	corev1 "k8s.io/api/core/v1"
 	k8yaml "sigs.k8s.io/yaml"
+	"strconv"
 	"strings"
 )
 
@@ -128,6 +129,33 @@ func (s SystemConfig) NodePoolOptionByValue(value string) (option *NodePoolOptio
 	return
 }
 
+// AllowedImageRegistries loads and parses the allowedImageRegistries from the config.
+// These are the prefixes that container images in workflow templates must start with.
+// If there is no data, no prefixes are returned and all images are allowed.
+func (s SystemConfig) AllowedImageRegistries() (prefixes []string, err error) {
+	data, ok := s["allowedImageRegistries"]
+	if !ok {
+		return nil, nil
+	}
+
+	if err = k8yaml.Unmarshal([]byte(data), &prefixes); err != nil {
+		return
+	}
+
+	return
+}
+
+// StrictWorkflowTemplateValidation returns true if the strictWorkflowTemplateValidation value is true.
+// It sets the StrictValidation of the clients created with the config. A missing or invalid value is false.
+func (s SystemConfig) StrictWorkflowTemplateValidation() bool {
+	strict, err := strconv.ParseBool(s["strictWorkflowTemplateValidation"])
+	if err != nil {
+		return false
+	}
+
+	return strict
+}
+
 // DatabaseDriverName gets the databaseDriverName value, or nil.
 func (s SystemConfig) DatabaseDriverName() *string {
 	return s.GetValue("databaseDriverName")
@@ -143,6 +171,9 @@ func (s SystemConfig) DatabaseConnection() (driverName, dataSourceName string) {
 	return
 }
 
+// nodePoolParameterName is the name of the parameter whose options are set from the applicationNodePoolOptions
+const nodePoolParameterName = "sys-node-pool"
+
 // UpdateNodePoolOptions will update the sys-node-pool parameter's options with runtime values
 // The original slice is unmodified, the returned slice has the updated values
 // If sys-node-pool is not present, nothing happens.
@@ -154,7 +185,7 @@ func (s SystemConfig) UpdateNodePoolOptions(parameters []Parameter) ([]Parameter
 	// Copy the original parameters, skipping sys-node-pool
 	for i := range parameters {
 		parameter := parameters[i]
-		if parameter.Name == "sys-node-pool" {
+		if parameter.Name == nodePoolParameterName {
 			nodePoolParameter = &parameter
 			continue
 		}
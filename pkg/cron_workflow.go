@@ -36,6 +36,8 @@ func (c *Client) UpdateCronWorkflow(namespace string, uid string, cronWorkflow *
 		return nil, util.NewUserError(codes.NotFound, "Error with getting workflow template.")
 	}
 
+	warnIfCanNotBeScheduled(namespace, workflowTemplate)
+
 	// TODO: Need to pull system parameters from k8s config/secret here, example: HOST
 	opts := &WorkflowExecutionOptions{}
 	opts.GenerateName, err = uid2.GenerateUID(workflowTemplate.Name, 63)
@@ -119,6 +121,18 @@ func (c *Client) UpdateCronWorkflow(namespace string, uid string, cronWorkflow *
 	return cronWorkflow, nil
 }
 
+// warnIfCanNotBeScheduled logs a warning if the workflow template will not complete without manual intervention, see CanBeScheduled.
+// Scheduling it is still allowed.
+func warnIfCanNotBeScheduled(namespace string, workflowTemplate *WorkflowTemplate) {
+	if ok, reason, err := CanBeScheduled(workflowTemplate); err == nil && !ok {
+		log.WithFields(log.Fields{
+			"Namespace":        namespace,
+			"WorkflowTemplate": workflowTemplate.LogEntry(),
+			"Reason":           reason,
+		}).Warn("Scheduling a workflow template that will not complete without manual intervention.")
+	}
+}
+
 func (c *Client) CreateCronWorkflow(namespace string, cronWorkflow *CronWorkflow) (*CronWorkflow, error) {
 	workflow := cronWorkflow.WorkflowExecution
 	workflowTemplate, err := c.GetWorkflowTemplate(namespace, workflow.WorkflowTemplate.UID, workflow.WorkflowTemplate.Version)
@@ -131,6 +145,8 @@ func (c *Client) CreateCronWorkflow(namespace string, cronWorkflow *CronWorkflow
 		return nil, util.NewUserError(codes.NotFound, "Error with getting workflow template.")
 	}
 
+	warnIfCanNotBeScheduled(namespace, workflowTemplate)
+
 	// TODO: Need to pull system parameters from k8s config/secret here, example: HOST
 	opts := &WorkflowExecutionOptions{
 		Labels: make(map[string]string),
@@ -232,6 +248,48 @@ func (c *Client) CreateCronWorkflow(namespace string, cronWorkflow *CronWorkflow
 	return cronWorkflow, nil
 }
 
+// CreateWorkflowTemplateWithCron creates the workflow template and a cron workflow that runs its first version.
+// If the cron workflow can not be created, the workflow template is deleted again, so either both exist or neither does.
+// The workflow template of the cron workflow's WorkflowExecution is set to the created workflow template.
+func (c *Client) CreateWorkflowTemplateWithCron(namespace string, workflowTemplate *WorkflowTemplate, cronWorkflow *CronWorkflow) (*WorkflowTemplate, *CronWorkflow, error) {
+	createdWorkflowTemplate, err := c.CreateWorkflowTemplate(namespace, workflowTemplate)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if cronWorkflow.WorkflowExecution == nil {
+		cronWorkflow.WorkflowExecution = &WorkflowExecution{}
+	}
+	cronWorkflow.WorkflowExecution.WorkflowTemplate = &WorkflowTemplate{
+		UID:     createdWorkflowTemplate.UID,
+		Version: createdWorkflowTemplate.Version,
+	}
+
+	createdCronWorkflow, err := c.CreateCronWorkflow(namespace, cronWorkflow)
+	if err == nil {
+		return createdWorkflowTemplate, createdCronWorkflow, nil
+	}
+
+	// The argo cron workflow may have been created before the failure
+	cronLabelSelector := fmt.Sprintf("%v=%v", workflowTemplateUIDLabelKey, createdWorkflowTemplate.UID)
+	errCleanup := c.ArgoprojV1alpha1().CronWorkflows(namespace).DeleteCollection(&metav1.DeleteOptions{}, metav1.ListOptions{
+		LabelSelector: cronLabelSelector,
+	})
+	if errCleanup == nil {
+		errCleanup = c.deleteWorkflowTemplate(namespace, createdWorkflowTemplate)
+	}
+	if errCleanup != nil {
+		log.WithFields(log.Fields{
+			"Namespace": namespace,
+			"UID":       createdWorkflowTemplate.UID,
+			"Error":     errCleanup.Error(),
+		}).Error("Could not delete workflow template after the cron workflow failed to be created.")
+		err = fmt.Errorf("%w; %s", err, errCleanup)
+	}
+
+	return nil, nil, err
+}
+
 // GetCronWorkflow gets information about a cron workflow uniquely identified by a namespace/uid
 func (c *Client) GetCronWorkflow(namespace, uid string) (cronWorkflow *CronWorkflow, err error) {
 	cronWorkflow = &CronWorkflow{}
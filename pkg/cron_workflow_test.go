@@ -0,0 +1,48 @@
+package v1
+
+import (
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+// TestClient_CreateWorkflowTemplateWithCron_RollBack makes sure the workflow template is removed if the cron workflow fails
+func TestClient_CreateWorkflowTemplateWithCron_RollBack(t *testing.T) {
+	c := DefaultTestClient()
+	clearDatabase(t)
+
+	namespace := "onepanel"
+
+	_, _, err := c.CreateWorkflowTemplateWithCron(namespace, &WorkflowTemplate{
+		Name:     "test",
+		Manifest: defaultWorkflowTemplate,
+	}, &CronWorkflow{
+		Manifest: "{ invalid",
+	})
+	assert.NotNil(t, err)
+
+	count, err := c.CountWorkflowTemplatesByName(namespace, "test", nil)
+	assert.Nil(t, err)
+	assert.Equal(t, uint64(0), count)
+
+	argoWorkflowTemplates, err := c.listArgoWorkflowTemplates(namespace, "test")
+	assert.Nil(t, err)
+	assert.Empty(t, *argoWorkflowTemplates)
+}
+
+// TestClient_CreateWorkflowTemplateWithCron_RequiredParameters makes sure scheduled executions are not rejected
+// for required parameters, which are only checked for executions submitted through the API
+func TestClient_CreateWorkflowTemplateWithCron_RequiredParameters(t *testing.T) {
+	c := DefaultTestClient()
+	clearDatabase(t)
+
+	namespace := "onepanel"
+
+	_, cronWorkflow, err := c.CreateWorkflowTemplateWithCron(namespace, &WorkflowTemplate{
+		Name:     "test",
+		Manifest: requiredParameterWorkflowTemplate,
+	}, &CronWorkflow{
+		Manifest: "schedule: '0 0 * * *'",
+	})
+	assert.Nil(t, err)
+	assert.NotNil(t, cronWorkflow)
+}
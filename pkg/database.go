@@ -1,6 +1,10 @@
 package v1
 
 import (
+	"context"
+	"database/sql"
+	"time"
+
 	sq "github.com/Masterminds/squirrel"
 	"github.com/jmoiron/sqlx"
 )
@@ -8,15 +12,149 @@ import (
 // DB represents a database connection. It wraps a sqlx.DB to provide convenience methods.
 type DB struct {
 	sqlx.DB
+	// Timeout is the deadline for each query and statement. 0 means there is no deadline.
+	// A query that runs past it fails with context.DeadlineExceeded. For rows it only covers starting the query,
+	// and for transactions it covers each statement, as they are used after the call returns.
+	Timeout time.Duration
+}
+
+// Tx is a transaction started by DB.Begin. Each statement runs with the Timeout of the DB.
+type Tx struct {
+	*sql.Tx
+	timeout time.Duration
+	cancel  context.CancelFunc
+}
+
+// Row is the result of QueryRow. The context of the query is cancelled once the row is scanned.
+type Row struct {
+	*sql.Row
+	cancel context.CancelFunc
+}
+
+// Rows is the result of Rowsx. The context of the query is cancelled once the rows are closed.
+type Rows struct {
+	*sqlx.Rows
+	cancel context.CancelFunc
 }
 
 // NewDB creates a new DB using an existing sqlx.DB connection.
 func NewDB(db *sqlx.DB) *DB {
 	return &DB{
-		*db,
+		DB: *db,
+	}
+}
+
+// timeoutContext returns the context to run a query with, which has a deadline if timeout is greater than 0
+func timeoutContext(timeout time.Duration) (context.Context, context.CancelFunc) {
+	if timeout <= 0 {
+		return context.WithCancel(context.Background())
+	}
+
+	return context.WithTimeout(context.Background(), timeout)
+}
+
+// queryContext returns the context to run a query with, which has a deadline if the DB has a Timeout
+func (db *DB) queryContext() (context.Context, context.CancelFunc) {
+	return timeoutContext(db.Timeout)
+}
+
+// Exec runs the query with the Timeout. It is also used by squirrel builders run with the DB.
+func (db *DB) Exec(query string, args ...interface{}) (sql.Result, error) {
+	ctx, cancel := db.queryContext()
+	defer cancel()
+
+	return db.ExecContext(ctx, query, args...)
+}
+
+// Get runs the query with the Timeout and scans the single row into dest.
+func (db *DB) Get(dest interface{}, query string, args ...interface{}) error {
+	ctx, cancel := db.queryContext()
+	defer cancel()
+
+	return db.GetContext(ctx, dest, query, args...)
+}
+
+// Select runs the query with the Timeout and scans the rows into dest.
+func (db *DB) Select(dest interface{}, query string, args ...interface{}) error {
+	ctx, cancel := db.queryContext()
+	defer cancel()
+
+	return db.SelectContext(ctx, dest, query, args...)
+}
+
+// QueryRow runs the query with the Timeout. It is also used by squirrel builders run with the DB.
+func (db *DB) QueryRow(query string, args ...interface{}) sq.RowScanner {
+	ctx, cancel := db.queryContext()
+
+	return &Row{
+		Row:    db.QueryRowContext(ctx, query, args...),
+		cancel: cancel,
 	}
 }
 
+// Begin starts a transaction. The transaction itself has no deadline, each statement run with it has the Timeout.
+func (db *DB) Begin() (*Tx, error) {
+	ctx, cancel := context.WithCancel(context.Background())
+
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		cancel()
+		return nil, err
+	}
+
+	return &Tx{
+		Tx:      tx,
+		timeout: db.Timeout,
+		cancel:  cancel,
+	}, nil
+}
+
+// Scan copies the columns of the row into dest and cancels the context of the query.
+func (r *Row) Scan(dest ...interface{}) error {
+	defer r.cancel()
+
+	return r.Row.Scan(dest...)
+}
+
+// Close closes the rows and cancels the context of the query.
+func (r *Rows) Close() error {
+	defer r.cancel()
+
+	return r.Rows.Close()
+}
+
+// Exec runs the statement in the transaction with the Timeout. It is also used by squirrel builders run with the Tx.
+func (tx *Tx) Exec(query string, args ...interface{}) (sql.Result, error) {
+	ctx, cancel := timeoutContext(tx.timeout)
+	defer cancel()
+
+	return tx.ExecContext(ctx, query, args...)
+}
+
+// QueryRow runs the query in the transaction with the Timeout. It is also used by squirrel builders run with the Tx.
+func (tx *Tx) QueryRow(query string, args ...interface{}) sq.RowScanner {
+	ctx, cancel := timeoutContext(tx.timeout)
+
+	return &Row{
+		Row:    tx.QueryRowContext(ctx, query, args...),
+		cancel: cancel,
+	}
+}
+
+// Commit commits the transaction and releases its context.
+func (tx *Tx) Commit() error {
+	defer tx.cancel()
+
+	return tx.Tx.Commit()
+}
+
+// Rollback aborts the transaction and releases its context. It returns sql.ErrTxDone if the transaction is done.
+func (tx *Tx) Rollback() error {
+	defer tx.cancel()
+
+	return tx.Tx.Rollback()
+}
+
 // Selectx performs a select query using a squirrel SelectBuilder as an argument.
 //
 // This is a convenience wrapper. Any errors from squirrel or sqlx are returned as is.
@@ -26,7 +164,10 @@ func (db *DB) Selectx(dest interface{}, builder sq.SelectBuilder) error {
 		return err
 	}
 
-	return db.Select(dest, sql, args...)
+	ctx, cancel := db.queryContext()
+	defer cancel()
+
+	return db.SelectContext(ctx, dest, sql, args...)
 }
 
 // Getx performs a get query using a squirrel SelectBuilder as an argument.
@@ -38,5 +179,44 @@ func (db *DB) Getx(dest interface{}, builder sq.SelectBuilder) error {
 		return err
 	}
 
-	return db.Get(dest, query, args...)
+	ctx, cancel := db.queryContext()
+	defer cancel()
+
+	return db.GetContext(ctx, dest, query, args...)
+}
+
+// Rowsx performs a query using a squirrel SelectBuilder as an argument and returns the rows, so they can be
+// scanned one at a time. The caller must close the rows. The Timeout covers starting the query, not reading the rows.
+//
+// This is a convenience wrapper. Any errors from squirrel or sqlx are returned as is.
+func (db *DB) Rowsx(builder sq.SelectBuilder) (*Rows, error) {
+	query, args, err := builder.ToSql()
+	if err != nil {
+		return nil, err
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	var timer *time.Timer
+	if db.Timeout > 0 {
+		timer = time.AfterFunc(db.Timeout, cancel)
+	}
+
+	rows, err := db.QueryxContext(ctx, query, args...)
+	if timer != nil && !timer.Stop() {
+		// the timeout passed while the query started, so the query was cancelled
+		if err == nil {
+			rows.Close()
+		}
+		cancel()
+		return nil, context.DeadlineExceeded
+	}
+	if err != nil {
+		cancel()
+		return nil, err
+	}
+
+	return &Rows{
+		Rows:   rows,
+		cancel: cancel,
+	}, nil
 }
@@ -0,0 +1,67 @@
+package v1
+
+import (
+	sq "github.com/Masterminds/squirrel"
+	"github.com/onepanelio/core/pkg/util"
+	"github.com/stretchr/testify/assert"
+	"testing"
+	"time"
+)
+
+// TestDB_Timeout makes sure a query that runs past the DB timeout fails with a timeout error
+func TestDB_Timeout(t *testing.T) {
+	db := NewDB(database)
+	db.Timeout = 50 * time.Millisecond
+
+	result := ""
+	err := db.Getx(&result, sq.Select("pg_sleep(1)::text"))
+	assert.NotNil(t, err)
+	assert.True(t, util.IsTimeout(err))
+
+	_, err = db.Exec("SELECT pg_sleep(1)")
+	assert.True(t, util.IsTimeout(err))
+
+	err = sq.Select("pg_sleep(1)::text").RunWith(db).PlaceholderFormat(sq.Dollar).QueryRow().Scan(&result)
+	assert.True(t, util.IsTimeout(err))
+
+	tx, err := db.Begin()
+	assert.Nil(t, err)
+	_, err = tx.Exec("SELECT pg_sleep(1)")
+	assert.NotNil(t, err)
+	tx.Rollback()
+
+	rows, err := db.Rowsx(sq.Select("pg_sleep(1)::text"))
+	assert.Nil(t, rows)
+	assert.True(t, util.IsTimeout(err))
+
+	db.Timeout = 0
+	assert.Nil(t, db.Getx(&result, sq.Select("'done'")))
+	assert.Equal(t, "done", result)
+}
+
+// TestClient_SetTimeouts makes sure watches do not use the argo client with the timeout
+func TestClient_SetTimeouts(t *testing.T) {
+	c := DefaultTestClient()
+	c.config = &Config{Host: "localhost"}
+	defaultArgoClient := c.ArgoprojV1alpha1()
+
+	assert.Nil(t, c.SetTimeouts(0, time.Second))
+	assert.NotEqual(t, defaultArgoClient, c.ArgoprojV1alpha1())
+	assert.Equal(t, defaultArgoClient, c.argoWatchClient())
+}
+
+// TestClient_SetTimeouts_DB makes sure the database timeout is not shared with other clients
+// and that the client is not changed when the timeouts can not be set
+func TestClient_SetTimeouts_DB(t *testing.T) {
+	c := DefaultTestClient()
+	other := &Client{DB: c.DB}
+
+	assert.NotNil(t, c.SetTimeouts(time.Second, time.Second))
+	assert.Equal(t, other.DB, c.DB)
+	assert.Equal(t, time.Duration(0), c.DB.Timeout)
+
+	c.config = &Config{Host: "localhost"}
+	assert.Nil(t, c.SetTimeouts(time.Second, time.Second))
+	assert.Equal(t, time.Second, c.DB.Timeout)
+	assert.Equal(t, time.Duration(0), other.DB.Timeout)
+}
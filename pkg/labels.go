@@ -4,9 +4,12 @@ import (
 	"fmt"
 	sq "github.com/Masterminds/squirrel"
 	"github.com/argoproj/argo/pkg/apis/workflow/v1alpha1"
+	"github.com/lib/pq"
+	"github.com/onepanelio/core/pkg/util"
 	"github.com/onepanelio/core/pkg/util/label"
 	"github.com/onepanelio/core/pkg/util/mapping"
 	"github.com/onepanelio/core/pkg/util/types"
+	"google.golang.org/grpc/codes"
 	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"strings"
 )
@@ -123,6 +126,10 @@ func (c *Client) ListAvailableLabels(query *SelectLabelsQuery) (result []*Label,
 }
 
 func (c *Client) AddLabels(namespace, resource, uid string, keyValues map[string]string) error {
+	if resource == TypeWorkflowTemplate {
+		return c.updateWorkflowTemplateLabels(namespace, uid, sq.Expr("COALESCE(NULLIF(labels, 'null'::jsonb), '{}'::jsonb) || ?::jsonb", types.JSONLabels(keyValues)))
+	}
+
 	source, meta, err := c.GetK8sLabelResource(namespace, resource, uid)
 	if err != nil {
 		return err
@@ -157,7 +164,7 @@ func (c *Client) ReplaceLabels(namespace, resource, uid string, keyValues map[st
 			sq.Eq{"uid": uid},
 			sq.NotEq{"phase": "Terminated"},
 		}
-	} else if resource == TypeWorkspaceTemplate || resource == TypeWorkflowExecution {
+	} else if resource == TypeWorkspaceTemplate || resource == TypeWorkflowExecution || resource == TypeWorkflowTemplate {
 		whereCondition =
 			sq.Eq{
 				"uid":         uid,
@@ -176,6 +183,12 @@ func (c *Client) ReplaceLabels(namespace, resource, uid string, keyValues map[st
 		return err
 	}
 
+	if resource == TypeWorkflowTemplate {
+		if err := c.recordWorkflowTemplateLabelChange(tx, namespace, uid); err != nil {
+			return err
+		}
+	}
+
 	if err := tx.Commit(); err != nil {
 		return err
 	}
@@ -186,6 +199,10 @@ func (c *Client) ReplaceLabels(namespace, resource, uid string, keyValues map[st
 // ReplaceLabelsUsingKnownID updates the k8s resource labels for the given resource/uid
 // deprecated
 func (c *Client) ReplaceLabelsUsingKnownID(namespace, resource string, uid string, keyValues map[string]string) error {
+	if resource == TypeWorkflowTemplate {
+		defer c.InvalidateWorkflowTemplateCache(namespace, uid)
+	}
+
 	source, meta, err := c.GetK8sLabelResource(namespace, resource, uid)
 	if err != nil {
 		return err
@@ -205,6 +222,10 @@ func (c *Client) ReplaceLabelsUsingKnownID(namespace, resource string, uid strin
 }
 
 func (c *Client) DeleteLabels(namespace, resource, uid string, keyValues map[string]string) error {
+	if resource == TypeWorkflowTemplate {
+		return c.updateWorkflowTemplateLabels(namespace, uid, sq.Expr("COALESCE(NULLIF(labels, 'null'::jsonb), '{}'::jsonb) - ?::text[]", pq.Array(mapping.PluckKeysStr(keyValues))))
+	}
+
 	tx, err := c.DB.Begin()
 	if err != nil {
 		return err
@@ -266,6 +287,42 @@ func (c *Client) DeleteLabels(namespace, resource, uid string, keyValues map[str
 	return nil
 }
 
+// updateWorkflowTemplateLabels sets the labels of the non-archived workflow template with the uid to the labels expression,
+// which is evaluated against the current labels. The updated_at and the audit log are updated in the same transaction.
+func (c *Client) updateWorkflowTemplateLabels(namespace, uid string, labels sq.Sqlizer) error {
+	defer c.InvalidateWorkflowTemplateCache(namespace, uid)
+
+	tx, err := c.DB.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	result, err := sb.Update("workflow_templates").
+		Set("labels", labels).
+		Where(sq.Eq{
+			"namespace":   namespace,
+			"uid":         uid,
+			"is_archived": false,
+		}).
+		RunWith(tx).
+		Exec()
+	if err != nil {
+		return err
+	}
+	if rowsAffected, err := result.RowsAffected(); err != nil {
+		return err
+	} else if rowsAffected == 0 {
+		return util.NewUserError(codes.NotFound, "Workflow template not found.")
+	}
+
+	if err := c.recordWorkflowTemplateLabelChange(tx, namespace, uid); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
 // DeleteResourceLabels deletes all of the labels for a specific resource, like workflow templates.
 // NOTE: this does NOT delete k8s labels, and is only meant to be used for special cases.
 func (c *Client) DeleteResourceLabels(runner sq.BaseRunner, resource string) error {
@@ -302,7 +359,7 @@ func (c *Client) GetK8sLabelResource(namespace, resource, uid string) (source in
 func (c *Client) getK8sLabelResourceWorkflowTemplateVersion(namespace, uid string) (source interface{}, result *v1.ObjectMeta, err error) {
 	labelSelect := fmt.Sprintf("%v=%v", label.WorkflowTemplateVersionUid, uid)
 
-	workflowTemplates, err := c.ArgoprojV1alpha1().WorkflowTemplates(namespace).List(v1.ListOptions{
+	workflowTemplates, err := c.listArgoWorkflowTemplatesLimited(namespace, v1.ListOptions{
 		LabelSelector: labelSelect,
 	})
 	if err != nil {
@@ -349,7 +406,7 @@ func (c *Client) getK8sLabelResourceCronWorkflow(namespace, uid string) (source
 func (c *Client) getK8sLabelResourceWorkspaceTemplate(namespace, uid string) (source interface{}, result *v1.ObjectMeta, err error) {
 	labelSelect := fmt.Sprintf("%v=%v", label.WorkspaceTemplateVersionUid, uid)
 
-	workflowTemplates, err := c.ArgoprojV1alpha1().WorkflowTemplates(namespace).List(v1.ListOptions{
+	workflowTemplates, err := c.listArgoWorkflowTemplatesLimited(namespace, v1.ListOptions{
 		LabelSelector: labelSelect,
 	})
 	if err != nil {
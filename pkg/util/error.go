@@ -1,9 +1,11 @@
 package util
 
 import (
+	"context"
 	"errors"
 	"fmt"
 	"google.golang.org/grpc/status"
+	"net"
 
 	"github.com/lib/pq"
 	"google.golang.org/grpc/codes"
@@ -40,6 +42,30 @@ func pqError(err *pq.Error) (code codes.Code) {
 	return
 }
 
+// IsUniqueViolation returns true if the error is from a query that broke a unique constraint, like inserting
+// a row that a concurrent transaction inserted first.
+func IsUniqueViolation(err error) bool {
+	var pqErr *pq.Error
+	return errors.As(err, &pqErr) && pqErr.Code == "23505"
+}
+
+// IsTimeout returns true if the error is from an operation that ran past its deadline,
+// like a query with a context deadline or an http request with a client timeout.
+// Postgres reports a query canceled at its deadline as query_canceled.
+func IsTimeout(err error) bool {
+	if errors.Is(err, context.DeadlineExceeded) {
+		return true
+	}
+
+	var pqErr *pq.Error
+	if errors.As(err, &pqErr) && pqErr.Code == "57014" {
+		return true
+	}
+
+	var netErr net.Error
+	return errors.As(err, &netErr) && netErr.Timeout()
+}
+
 // NewUserErrorWrap wraps pq errors and returns an instance of UserError
 func NewUserErrorWrap(err error, entity string) error {
 	var (
@@ -48,7 +74,10 @@ func NewUserErrorWrap(err error, entity string) error {
 		pqErr   *pq.Error
 		userErr *UserError
 	)
-	if errors.As(err, &pqErr) {
+	if IsTimeout(err) {
+		code = codes.DeadlineExceeded
+		message = fmt.Sprintf("%v operation timed out.", entity)
+	} else if errors.As(err, &pqErr) {
 		code = pqError(pqErr)
 		message = fmt.Sprintf("%v already exists.", entity)
 	} else if errors.As(err, &userErr) {
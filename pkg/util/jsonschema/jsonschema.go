@@ -0,0 +1,219 @@
+package jsonschema
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"regexp"
+	"sort"
+)
+
+// Schema is a parsed JSON schema.
+//
+// Only a subset of the draft-07 keywords is supported:
+// type, enum, const, required, properties, additionalProperties, items, minItems, maxItems,
+// minLength, maxLength, pattern, minimum and maximum. Other keywords are ignored.
+type Schema struct {
+	root map[string]interface{}
+}
+
+// Parse parses the JSON encoded schema
+func Parse(schema []byte) (*Schema, error) {
+	root := make(map[string]interface{})
+	if err := json.Unmarshal(schema, &root); err != nil {
+		return nil, fmt.Errorf("invalid schema: %v", err)
+	}
+
+	return &Schema{root: root}, nil
+}
+
+// Validate validates the document, as decoded by encoding/json, against the schema.
+// Each violation is returned as a message prefixed with the JSON pointer of the offending value.
+func (s *Schema) Validate(document interface{}) (violations []string, err error) {
+	violations = make([]string, 0)
+	if err := validate(s.root, document, "", &violations); err != nil {
+		return nil, err
+	}
+
+	return violations, nil
+}
+
+// validate checks value against schema, appending any violations. An error is returned if the schema itself is invalid.
+func validate(schema map[string]interface{}, value interface{}, path string, violations *[]string) error {
+	addViolation := func(format string, args ...interface{}) {
+		location := path
+		if location == "" {
+			location = "/"
+		}
+		*violations = append(*violations, location+": "+fmt.Sprintf(format, args...))
+	}
+
+	if schemaType, ok := schema["type"]; ok {
+		types, err := stringOrStrings(schemaType)
+		if err != nil {
+			return fmt.Errorf("invalid type at %v: %v", path, err)
+		}
+
+		valueType := typeOf(value)
+		matched := false
+		for _, t := range types {
+			if t == valueType || (t == "number" && valueType == "integer") {
+				matched = true
+				break
+			}
+		}
+		if !matched {
+			addViolation("expected %v, got %v", types, valueType)
+			return nil
+		}
+	}
+
+	if enum, ok := schema["enum"].([]interface{}); ok {
+		found := false
+		for _, option := range enum {
+			if reflect.DeepEqual(option, value) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			addViolation("value is not one of %v", enum)
+		}
+	}
+
+	if constant, ok := schema["const"]; ok && !reflect.DeepEqual(constant, value) {
+		addViolation("value must be %v", constant)
+	}
+
+	switch typed := value.(type) {
+	case map[string]interface{}:
+		return validateObject(schema, typed, path, addViolation, violations)
+	case []interface{}:
+		if minItems, ok := schema["minItems"].(float64); ok && float64(len(typed)) < minItems {
+			addViolation("expected at least %v items", minItems)
+		}
+		if maxItems, ok := schema["maxItems"].(float64); ok && float64(len(typed)) > maxItems {
+			addViolation("expected at most %v items", maxItems)
+		}
+		if items, ok := schema["items"].(map[string]interface{}); ok {
+			for i, item := range typed {
+				if err := validate(items, item, fmt.Sprintf("%v/%v", path, i), violations); err != nil {
+					return err
+				}
+			}
+		}
+	case string:
+		if minLength, ok := schema["minLength"].(float64); ok && float64(len([]rune(typed))) < minLength {
+			addViolation("expected at least %v characters", minLength)
+		}
+		if maxLength, ok := schema["maxLength"].(float64); ok && float64(len([]rune(typed))) > maxLength {
+			addViolation("expected at most %v characters", maxLength)
+		}
+		if pattern, ok := schema["pattern"].(string); ok {
+			re, err := regexp.Compile(pattern)
+			if err != nil {
+				return fmt.Errorf("invalid pattern at %v: %v", path, err)
+			}
+			if !re.MatchString(typed) {
+				addViolation("'%v' does not match pattern '%v'", typed, pattern)
+			}
+		}
+	case float64:
+		if minimum, ok := schema["minimum"].(float64); ok && typed < minimum {
+			addViolation("expected a value of at least %v", minimum)
+		}
+		if maximum, ok := schema["maximum"].(float64); ok && typed > maximum {
+			addViolation("expected a value of at most %v", maximum)
+		}
+	}
+
+	return nil
+}
+
+// validateObject checks the object keywords of the schema
+func validateObject(schema map[string]interface{}, value map[string]interface{}, path string, addViolation func(string, ...interface{}), violations *[]string) error {
+	if required, ok := schema["required"].([]interface{}); ok {
+		for _, name := range required {
+			key, ok := name.(string)
+			if !ok {
+				return fmt.Errorf("invalid required at %v: %v is not a string", path, name)
+			}
+			if _, ok := value[key]; !ok {
+				addViolation("missing required property '%v'", key)
+			}
+		}
+	}
+
+	properties, _ := schema["properties"].(map[string]interface{})
+	keys := make([]string, 0, len(value))
+	for key := range value {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	for _, key := range keys {
+		propertySchema, ok := properties[key].(map[string]interface{})
+		if ok {
+			if err := validate(propertySchema, value[key], path+"/"+key, violations); err != nil {
+				return err
+			}
+			continue
+		}
+
+		switch additional := schema["additionalProperties"].(type) {
+		case bool:
+			if !additional {
+				addViolation("property '%v' is not allowed", key)
+			}
+		case map[string]interface{}:
+			if err := validate(additional, value[key], path+"/"+key, violations); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+// stringOrStrings returns the value as a list of strings, if it is a string or a list of strings
+func stringOrStrings(value interface{}) ([]string, error) {
+	switch typed := value.(type) {
+	case string:
+		return []string{typed}, nil
+	case []interface{}:
+		result := make([]string, 0, len(typed))
+		for _, item := range typed {
+			str, ok := item.(string)
+			if !ok {
+				return nil, fmt.Errorf("%v is not a string", item)
+			}
+			result = append(result, str)
+		}
+		return result, nil
+	}
+
+	return nil, fmt.Errorf("%v is not a string or a list of strings", value)
+}
+
+// typeOf returns the JSON schema type name of a value decoded by encoding/json
+func typeOf(value interface{}) string {
+	switch typed := value.(type) {
+	case nil:
+		return "null"
+	case bool:
+		return "boolean"
+	case string:
+		return "string"
+	case float64:
+		if typed == float64(int64(typed)) {
+			return "integer"
+		}
+		return "number"
+	case []interface{}:
+		return "array"
+	case map[string]interface{}:
+		return "object"
+	}
+
+	return fmt.Sprintf("%T", value)
+}
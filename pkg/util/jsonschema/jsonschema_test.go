@@ -0,0 +1,64 @@
+package jsonschema
+
+import (
+	"encoding/json"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+const testSchema = `{
+  "type": "object",
+  "required": ["entrypoint", "templates"],
+  "properties": {
+    "entrypoint": {"type": "string", "pattern": "^[a-z-]+$"},
+    "templates": {
+      "type": "array",
+      "minItems": 1,
+      "items": {
+        "type": "object",
+        "required": ["name"],
+        "properties": {"name": {"type": "string", "maxLength": 10}}
+      }
+    },
+    "parallelism": {"type": "integer", "minimum": 1}
+  },
+  "additionalProperties": false
+}`
+
+func validateDocument(t *testing.T, document string) []string {
+	schema, err := Parse([]byte(testSchema))
+	assert.Nil(t, err)
+
+	var value interface{}
+	assert.Nil(t, json.Unmarshal([]byte(document), &value))
+
+	violations, err := schema.Validate(value)
+	assert.Nil(t, err)
+
+	return violations
+}
+
+func TestSchema_Validate(t *testing.T) {
+	violations := validateDocument(t, `{"entrypoint": "main", "templates": [{"name": "main"}], "parallelism": 2}`)
+	assert.Empty(t, violations)
+
+	violations = validateDocument(t, `{"entrypoint": "Main", "templates": [{"name": "a-very-long-name"}, {}], "parallelism": 0.5, "extra": true}`)
+	assert.ElementsMatch(t, []string{
+		"/entrypoint: 'Main' does not match pattern '^[a-z-]+$'",
+		"/templates/0/name: expected at most 10 characters",
+		"/templates/1: missing required property 'name'",
+		"/parallelism: expected [integer], got number",
+		"/: property 'extra' is not allowed",
+	}, violations)
+
+	violations = validateDocument(t, `{"templates": []}`)
+	assert.ElementsMatch(t, []string{
+		"/: missing required property 'entrypoint'",
+		"/templates: expected at least 1 items",
+	}, violations)
+}
+
+func TestParse_Invalid(t *testing.T) {
+	_, err := Parse([]byte("not json"))
+	assert.NotNil(t, err)
+}
@@ -1,12 +1,14 @@
 package label
 
 import (
+	"sort"
 	"strings"
 )
 
 const (
 	OnepanelPrefix              = "onepanel.io/"
 	TagPrefix                   = "tags.onepanel.io/"
+	AnnotationPrefix            = "annotations.onepanel.io/"
 	WorkflowTemplate            = OnepanelPrefix + "workflow-template"
 	WorkflowTemplateUid         = OnepanelPrefix + "workflow-template-uid"
 	WorkflowTemplateVersionUid  = OnepanelPrefix + "workflow-template-version-uid"
@@ -85,3 +87,17 @@ func MergeLabelsPrefix(destination, additions map[string]string, prefix string)
 		return prefix + s
 	})
 }
+
+// PrefixCollisions returns the keys of additions that, once prefixed, already exist in destination.
+// Use it before MergeLabelsPrefix to detect additions that would overwrite existing labels.
+func PrefixCollisions(destination, additions map[string]string, prefix string) (collisions []string) {
+	for key := range additions {
+		if _, ok := destination[prefix+key]; ok {
+			collisions = append(collisions, key)
+		}
+	}
+
+	sort.Strings(collisions)
+
+	return
+}
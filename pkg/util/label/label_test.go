@@ -0,0 +1,23 @@
+package label
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPrefixCollisions(t *testing.T) {
+	destination := map[string]string{
+		Version:             "1",
+		WorkflowTemplateUid: "test",
+		TagPrefix + "team":  "ml",
+	}
+
+	assert.Empty(t, PrefixCollisions(destination, map[string]string{"version": "2"}, TagPrefix))
+	assert.Equal(t, []string{"version", "workflow-template-uid"}, PrefixCollisions(destination, map[string]string{
+		"workflow-template-uid": "other",
+		"version":               "2",
+		"stage":                 "dev",
+	}, OnepanelPrefix))
+	assert.Equal(t, []string{"team"}, PrefixCollisions(destination, map[string]string{"team": "cv"}, TagPrefix))
+}
@@ -6,6 +6,10 @@ func Bool(value bool) *bool {
 	return &value
 }
 
+func Int(value int) *int {
+	return &value
+}
+
 func Int32(value int32) *int32 {
 	return &value
 }
@@ -20,6 +20,7 @@ import (
 	"io/ioutil"
 	"k8s.io/apimachinery/pkg/watch"
 	"net/http"
+	"reflect"
 	"strconv"
 	"strings"
 	"time"
@@ -521,6 +522,8 @@ func (c *Client) ValidateWorkflowExecution(namespace string, manifest []byte) (e
 // CreateWorkflowExecution creates an argo workflow execution and related resources.
 // If workflow.Name is set, it is used instead of a generated name.
 // If there is a parameter named "workflow-execution-name" in workflow.Parameters, it is set as the name.
+// Required parameters are not checked, as workspaces and clones supply their values in other ways.
+// Check user submitted executions with ValidateRequiredParameters first.
 func (c *Client) CreateWorkflowExecution(namespace string, workflow *WorkflowExecution, workflowTemplate *WorkflowTemplate) (*WorkflowExecution, error) {
 	opts := &WorkflowExecutionOptions{
 		Labels:     make(map[string]string),
@@ -829,7 +832,7 @@ func (c *Client) WatchWorkflowExecution(namespace, uid string) (<-chan *Workflow
 	}
 
 	fieldSelector, _ := fields.ParseSelector(fmt.Sprintf("metadata.name=%s", uid))
-	watcher, err := c.ArgoprojV1alpha1().Workflows(namespace).Watch(metav1.ListOptions{
+	watcher, err := c.argoWatchClient().Workflows(namespace).Watch(metav1.ListOptions{
 		FieldSelector: fieldSelector.String(),
 	})
 	if err != nil {
@@ -901,7 +904,7 @@ func (c *Client) WatchWorkflowExecution(namespace, uid string) (<-chan *Workflow
 				}
 
 				if workflow.Status.Phase == wfv1.NodeRunning {
-					watcher, err = c.ArgoprojV1alpha1().Workflows(namespace).Watch(metav1.ListOptions{
+					watcher, err = c.argoWatchClient().Workflows(namespace).Watch(metav1.ListOptions{
 						FieldSelector: fieldSelector.String(),
 					})
 					if err != nil {
@@ -1558,6 +1561,17 @@ func (c *Client) SetWorkflowExecutionLabels(namespace, uid, prefix string, keyVa
 // we delete all labels with that prefix and set the new ones
 // e.g. prefix/my-label-key: my-label-value
 func (c *Client) SetWorkflowTemplateLabels(namespace, uid, prefix string, keyValues map[string]string, deleteOld bool) (workflowLabels map[string]string, err error) {
+	workflowLabels, _, err = c.SetWorkflowTemplateLabelsIfChanged(namespace, uid, prefix, keyValues, deleteOld)
+
+	return
+}
+
+// SetWorkflowTemplateLabelsIfChanged is SetWorkflowTemplateLabels that also returns whether the labels changed.
+// If the resulting labels are the same as the current ones, the argo workflow template is not updated
+// and no label change is recorded.
+func (c *Client) SetWorkflowTemplateLabelsIfChanged(namespace, uid, prefix string, keyValues map[string]string, deleteOld bool) (workflowLabels map[string]string, changed bool, err error) {
+	defer c.InvalidateWorkflowTemplateCache(namespace, uid)
+
 	wf, err := c.getArgoWorkflowTemplate(namespace, uid, "latest")
 	if err != nil {
 		log.WithFields(log.Fields{
@@ -1565,7 +1579,12 @@ func (c *Client) SetWorkflowTemplateLabels(namespace, uid, prefix string, keyVal
 			"UID":       uid,
 			"Error":     err.Error(),
 		}).Error("Workflow Template not found.")
-		return nil, util.NewUserError(codes.NotFound, "Workflow Template not found.")
+		return nil, false, argoWorkflowTemplateUserError(err, "Unable to set workflow template labels.")
+	}
+
+	currentLabels := make(map[string]string)
+	for key, value := range wf.Labels {
+		currentLabels[key] = value
 	}
 
 	if deleteOld {
@@ -1577,15 +1596,33 @@ func (c *Client) SetWorkflowTemplateLabels(namespace, uid, prefix string, keyVal
 	}
 	label.MergeLabelsPrefix(wf.Labels, keyValues, prefix)
 
-	wf, err = c.ArgoprojV1alpha1().WorkflowTemplates(namespace).Update(wf)
-	if err != nil {
-		return nil, err
+	changed = !reflect.DeepEqual(currentLabels, wf.Labels)
+	if changed {
+		// the change is recorded before argo is updated, so a label change is never missing from the audit log
+		tx, err := c.DB.Begin()
+		if err != nil {
+			return nil, false, err
+		}
+		defer tx.Rollback()
+
+		if err := c.recordWorkflowTemplateLabelChange(tx, namespace, uid); err != nil {
+			return nil, false, err
+		}
+
+		wf, err = c.ArgoprojV1alpha1().WorkflowTemplates(namespace).Update(wf)
+		if err != nil {
+			return nil, false, err
+		}
+
+		if err := tx.Commit(); err != nil {
+			return nil, false, err
+		}
 	}
 
 	filteredMap := label.FilterByPrefix(prefix, wf.Labels)
 	filteredMap = label.RemovePrefix(prefix, filteredMap)
 
-	return filteredMap, nil
+	return filteredMap, changed, nil
 }
 
 // GetWorkflowExecutionStatisticsForNamespace loads statistics on workflow executions for the provided namespace
@@ -1613,6 +1650,17 @@ func (c *Client) GetWorkflowExecutionStatisticsForNamespace(namespace string) (r
 	return
 }
 
+// workflowExecutionStatisticsSelect selects the columns of a WorkflowExecutionStatisticReport
+// from workflow executions "we" joined with their workflow template versions "wtv", grouped by the workflow template.
+const workflowExecutionStatisticsSelect = `
+		workflow_template_id,
+		MAX(we.created_at) last_executed,
+		COUNT(*) FILTER (WHERE finished_at IS NULL AND (phase = 'Running' OR phase = 'Pending')) running,
+		COUNT(*) FILTER (WHERE finished_at IS NOT NULL AND phase = 'Succeeded') completed,
+		COUNT(*) FILTER (WHERE finished_at IS NOT NULL AND (phase = 'Failed' OR phase = 'Error')) failed,
+		COUNT(*) FILTER (WHERE phase = 'Terminated') terminated,
+		COUNT(*) total`
+
 // GetWorkflowExecutionStatisticsForTemplates loads statistics on workflow executions for the provided
 // workflowTemplates and sets it as the WorkflowExecutionStatisticReport property
 func (c *Client) GetWorkflowExecutionStatisticsForTemplates(workflowTemplates ...*WorkflowTemplate) (err error) {
@@ -1632,16 +1680,7 @@ func (c *Client) GetWorkflowExecutionStatisticsForTemplates(workflowTemplates ..
 
 	defer tx.Rollback()
 
-	statsSelect := `
-		workflow_template_id,
-		MAX(we.created_at) last_executed,
-		COUNT(*) FILTER (WHERE finished_at IS NULL AND (phase = 'Running' OR phase = 'Pending')) running,
-		COUNT(*) FILTER (WHERE finished_at IS NOT NULL AND phase = 'Succeeded') completed,
-		COUNT(*) FILTER (WHERE finished_at IS NOT NULL AND (phase = 'Failed' OR phase = 'Error')) failed,
-		COUNT(*) FILTER (WHERE phase = 'Terminated') terminated,
-		COUNT(*) total`
-
-	query, args, err := sb.Select(statsSelect).
+	query, args, err := sb.Select(workflowExecutionStatisticsSelect).
 		From("workflow_executions we").
 		Join("workflow_template_versions wtv ON wtv.id = we.workflow_template_version_id").
 		Where(sq.Eq{
@@ -1675,6 +1714,50 @@ func (c *Client) GetWorkflowExecutionStatisticsForTemplates(workflowTemplates ..
 	return
 }
 
+// GetWorkflowExecutionCountForTemplates loads the total number of workflow executions for the provided workflowTemplates
+// and sets it as the WorkflowExecutionStatisticReport property. Only Total is set, use it instead of
+// GetWorkflowExecutionStatisticsForTemplates when the other statistics are not needed.
+func (c *Client) GetWorkflowExecutionCountForTemplates(workflowTemplates ...*WorkflowTemplate) (err error) {
+	if len(workflowTemplates) == 0 {
+		return nil
+	}
+
+	ids := make([]interface{}, len(workflowTemplates))
+	for i, workflowTemplate := range workflowTemplates {
+		ids[i] = workflowTemplate.ID
+	}
+
+	query := sb.Select("wtv.workflow_template_id", "COUNT(*) total").
+		From("workflow_executions we").
+		Join("workflow_template_versions wtv ON wtv.id = we.workflow_template_version_id").
+		Where(sq.Eq{
+			"wtv.workflow_template_id": ids,
+		}).
+		GroupBy("wtv.workflow_template_id")
+
+	result := make([]*WorkflowExecutionStatisticReport, 0)
+	if err = c.DB.Selectx(&result, query); err != nil {
+		return err
+	}
+
+	resultMapping := make(map[uint64]*WorkflowExecutionStatisticReport)
+	for _, report := range result {
+		resultMapping[report.WorkflowTemplateId] = report
+	}
+
+	for _, workflowTemplate := range workflowTemplates {
+		report, ok := resultMapping[workflowTemplate.ID]
+		if !ok {
+			report = &WorkflowExecutionStatisticReport{
+				WorkflowTemplateId: workflowTemplate.ID,
+			}
+		}
+		workflowTemplate.WorkflowExecutionStatisticReport = report
+	}
+
+	return
+}
+
 /**
 Will build a template that makes a CURL request to the onepanel-core API,
 with statistics about the workflow that was just executed.
@@ -1,10 +1,31 @@
 package v1
 
 import (
+	"github.com/onepanelio/core/pkg/util"
 	"github.com/stretchr/testify/assert"
+	"google.golang.org/grpc/codes"
 	"testing"
 )
 
+// requiredParameterWorkflowTemplate has a required parameter without a default value
+const requiredParameterWorkflowTemplate = `entrypoint: main
+arguments:
+  parameters:
+  - name: dataset
+    value: ""
+    required: true
+templates:
+- name: main
+  dag:
+    tasks:
+    - name: run
+      template: run
+- name: run
+  container:
+    image: alpine
+    command: [echo, "{{workflow.parameters.dataset}}"]
+`
+
 // TestClient_CreateWorkflowExecution tests creating a workflow execution
 func TestClient_CreateWorkflowExecution(t *testing.T) {
 	c := DefaultTestClient()
@@ -26,6 +47,32 @@ func TestClient_CreateWorkflowExecution(t *testing.T) {
 	assert.Nil(t, err)
 }
 
+// TestClient_CreateWorkflowExecution_RequiredParameters makes sure required parameters are only checked
+// by ValidateRequiredParameters, so internal callers like clones are not rejected
+func TestClient_CreateWorkflowExecution_RequiredParameters(t *testing.T) {
+	c := DefaultTestClient()
+	clearDatabase(t)
+
+	namespace := "onepanel"
+
+	wt, err := c.CreateWorkflowTemplate(namespace, &WorkflowTemplate{
+		Name:     "test",
+		Manifest: requiredParameterWorkflowTemplate,
+	})
+	assert.Nil(t, err)
+
+	err = c.ValidateRequiredParameters(namespace, wt.UID, wt.Version, nil)
+	userErr, ok := err.(*util.UserError)
+	assert.True(t, ok)
+	assert.Equal(t, codes.InvalidArgument, userErr.Code)
+
+	we, err := c.CreateWorkflowExecution(namespace, &WorkflowExecution{}, wt)
+	assert.Nil(t, err)
+
+	_, err = c.CloneWorkflowExecution(namespace, we.UID)
+	assert.Nil(t, err)
+}
+
 // TestClient_GetWorkflowExecution tests getting a workflow execution that exists
 func TestClient_GetWorkflowExecution(t *testing.T) {
 	c := DefaultTestClient()
@@ -97,3 +144,53 @@ func TestClient_ArchiveWorkflowExecution_Exist(t *testing.T) {
 	err = c.ArchiveWorkflowExecution(namespace, weName)
 	assert.Nil(t, err)
 }
+
+// TestClient_GetWorkflowExecutionCountForTemplates tests counting the executions of workflow templates
+func TestClient_GetWorkflowExecutionCountForTemplates(t *testing.T) {
+	c := DefaultTestClient()
+	clearDatabase(t)
+
+	namespace := "onepanel"
+
+	wt, _ := c.CreateWorkflowTemplate(namespace, &WorkflowTemplate{
+		Name:     "test",
+		Manifest: defaultWorkflowTemplate,
+	})
+	unused, _ := c.CreateWorkflowTemplate(namespace, &WorkflowTemplate{
+		Name:     "test-unused",
+		Manifest: defaultWorkflowTemplate,
+	})
+
+	for _, name := range []string{"test-1", "test-2"} {
+		c.CreateWorkflowExecution(namespace, &WorkflowExecution{Name: name}, wt)
+	}
+
+	err := c.GetWorkflowExecutionCountForTemplates(wt, unused)
+	assert.Nil(t, err)
+	assert.Equal(t, int32(2), wt.WorkflowExecutionStatisticReport.Total)
+	assert.Equal(t, int32(0), unused.WorkflowExecutionStatisticReport.Total)
+}
+
+// TestClient_SetWorkflowTemplateLabelsIfChanged tests that setting the current labels again is not a change
+func TestClient_SetWorkflowTemplateLabelsIfChanged(t *testing.T) {
+	c := DefaultTestClient()
+	clearDatabase(t)
+
+	namespace := "onepanel"
+
+	wt, _ := c.CreateWorkflowTemplate(namespace, &WorkflowTemplate{
+		Name:     "test",
+		Manifest: defaultWorkflowTemplate,
+	})
+
+	keyValues := map[string]string{"a": "b"}
+	labels, changed, err := c.SetWorkflowTemplateLabelsIfChanged(namespace, wt.UID, "tags.onepanel.io/", keyValues, true)
+	assert.Nil(t, err)
+	assert.True(t, changed)
+	assert.Equal(t, keyValues, labels)
+
+	labels, changed, err = c.SetWorkflowTemplateLabelsIfChanged(namespace, wt.UID, "tags.onepanel.io/", keyValues, true)
+	assert.Nil(t, err)
+	assert.False(t, changed)
+	assert.Equal(t, keyValues, labels)
+}
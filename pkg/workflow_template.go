@@ -7,24 +7,121 @@ import (
 	"fmt"
 	"github.com/onepanelio/core/pkg/util/request"
 	pagination "github.com/onepanelio/core/pkg/util/request/pagination"
+	"io"
+	"net"
+	"path"
+	"regexp"
+	"sort"
 	"strconv"
 	"strings"
+	"sync"
 	"time"
 
 	sq "github.com/Masterminds/squirrel"
 	"github.com/argoproj/argo/pkg/apis/workflow/v1alpha1"
 	argojson "github.com/argoproj/pkg/json"
 	"github.com/ghodss/yaml"
+	"github.com/lib/pq"
 	"github.com/onepanelio/core/pkg/util"
 	"github.com/onepanelio/core/pkg/util/label"
+	"github.com/onepanelio/core/pkg/util/mapping"
+	"github.com/onepanelio/core/pkg/util/ptr"
+	"github.com/onepanelio/core/pkg/util/types"
+	"github.com/pmezard/go-difflib/difflib"
 	log "github.com/sirupsen/logrus"
 	"google.golang.org/grpc/codes"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
 	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 )
 
+var (
+	// ErrArgoTemplateNotFound is returned when no argo workflow template matches the query
+	ErrArgoTemplateNotFound = errors.New("argo workflow template not found")
+	// ErrArgoTemplateNotUnique is returned when more than one argo workflow template matches the query
+	ErrArgoTemplateNotUnique = errors.New("argo workflow template not unique")
+	// ErrSystemConfigUnavailable is returned when a workflow template can not be validated as the system config can not be loaded
+	ErrSystemConfigUnavailable = errors.New("system config unavailable")
+)
+
+// argoWorkflowTemplateUserError converts an error from loading an argo workflow template to a UserError.
+// Timeouts use codes.DeadlineExceeded. Other errors that are not ErrArgoTemplateNotFound or ErrArgoTemplateNotUnique
+// use codes.Unknown and the given message.
+func argoWorkflowTemplateUserError(err error, message string) error {
+	switch {
+	case errors.Is(err, ErrArgoTemplateNotFound):
+		return util.NewUserError(codes.NotFound, "Workflow template not found.")
+	case errors.Is(err, ErrArgoTemplateNotUnique):
+		return util.NewUserError(codes.Internal, "Workflow template has more than one matching version.")
+	case util.IsTimeout(err):
+		return util.NewUserError(codes.DeadlineExceeded, "Workflow template operation timed out.")
+	}
+
+	return util.NewUserError(codes.Unknown, message)
+}
+
+// workflowTemplateValidationUserError converts an error from validating a workflow template to a UserError.
+// Errors of the dependencies of the validation use another code, as the workflow template may be valid:
+// ErrSystemConfigUnavailable uses codes.Unavailable, timeouts use codes.DeadlineExceeded, and kubernetes
+// and database errors use codes.Unknown. Other errors use codes.InvalidArgument and the given message.
+func workflowTemplateValidationUserError(err error, message string) error {
+	var (
+		pqErr     *pq.Error
+		statusErr apierrors.APIStatus
+		netErr    net.Error
+	)
+	switch {
+	case errors.Is(err, ErrSystemConfigUnavailable):
+		return util.NewUserError(codes.Unavailable, "Unable to load the system config to validate the workflow template.")
+	case util.IsTimeout(err):
+		return util.NewUserError(codes.DeadlineExceeded, "Workflow template operation timed out.")
+	case errors.As(err, &pqErr), errors.Is(err, sql.ErrNoRows), errors.As(err, &statusErr), errors.As(err, &netErr):
+		return util.NewUserError(codes.Unknown, "Unable to validate workflow template.")
+	}
+
+	return util.NewUserError(codes.InvalidArgument, message)
+}
+
+// workflowTemplateScanUserError converts an error from validating a stored workflow template to a UserError.
+// UserErrors are returned as is, other errors are converted by workflowTemplateValidationUserError,
+// so only errors of the workflow template itself use codes.InvalidArgument.
+func workflowTemplateScanUserError(err error) error {
+	var userErr *util.UserError
+	if errors.As(err, &userErr) {
+		return userErr
+	}
+
+	return workflowTemplateValidationUserError(err, err.Error())
+}
+
 // WorkflowTemplateFilter represents the available ways we can filter WorkflowTemplates
 type WorkflowTemplateFilter struct {
-	Labels []*Label
+	Labels        []*Label
+	CreatedAfter  *time.Time // Only workflow templates created at or after this time
+	CreatedBefore *time.Time // Only workflow templates created before this time
+}
+
+// applyWorkflowTemplateFilter returns a query builder that adds where statements for the WorkflowTemplateFilter
+// in the request, if there is one
+func applyWorkflowTemplateFilter(sb sq.SelectBuilder, request *request.Request) sq.SelectBuilder {
+	sb = applyLabelSelectQuery(sb, request)
+
+	if !request.HasFilter() {
+		return sb
+	}
+
+	filter, ok := request.Filter.(WorkflowTemplateFilter)
+	if !ok {
+		return sb
+	}
+
+	if filter.CreatedAfter != nil {
+		sb = sb.Where(sq.GtOrEq{"wt.created_at": filter.CreatedAfter.UTC()})
+	}
+	if filter.CreatedBefore != nil {
+		sb = sb.Where(sq.Lt{"wt.created_at": filter.CreatedBefore.UTC()})
+	}
+
+	return sb
 }
 
 // applyLabelSelectQuery returns a query builder that adds where statements to filter by labels in the request,
@@ -46,6 +143,7 @@ func applyLabelSelectQuery(sb sq.SelectBuilder, request *request.Request) sq.Sel
 }
 
 // createWorkflowTemplateVersionDB inserts a record into workflow_template_versions using the current time accurate to nanoseconds
+// as the version, unless workflowTemplateVersion.Version is already set.
 // the data is returned in the resulting WorkflowTemplateVersion struct.
 func createWorkflowTemplateVersionDB(runner sq.BaseRunner, workflowTemplateVersion *WorkflowTemplateVersion, parameters []Parameter) (err error) {
 	if workflowTemplateVersion == nil {
@@ -58,7 +156,9 @@ func createWorkflowTemplateVersionDB(runner sq.BaseRunner, workflowTemplateVersi
 		return fmt.Errorf("workflowTemplateVersion.WorkflowTemplate.ID must be > 0. %v given", workflowTemplateVersion.WorkflowTemplate.ID)
 	}
 
-	workflowTemplateVersion.Version = time.Now().UnixNano()
+	if workflowTemplateVersion.Version == 0 {
+		workflowTemplateVersion.Version = time.Now().UnixNano()
+	}
 
 	pj, err := json.Marshal(parameters)
 	if err != nil {
@@ -75,8 +175,10 @@ func createWorkflowTemplateVersionDB(runner sq.BaseRunner, workflowTemplateVersi
 			"version":              workflowTemplateVersion.Version,
 			"is_latest":            true,
 			"manifest":             workflowTemplateVersion.Manifest,
+			"manifest_hash":        workflowTemplateVersion.ManifestHash(),
 			"parameters":           pj,
 			"labels":               workflowTemplateVersion.Labels,
+			"resolved_manifest":    workflowTemplateVersion.ResolvedManifest,
 		}).
 		Suffix("RETURNING id").
 		RunWith(runner).
@@ -94,9 +196,10 @@ func updateWorkflowTemplateVersionDB(runner sq.BaseRunner, wtv *WorkflowTemplate
 	}
 	_, err = sb.Update("workflow_template_versions").
 		SetMap(sq.Eq{
-			"manifest":   wtv.Manifest,
-			"is_latest":  wtv.IsLatest,
-			"parameters": string(pj),
+			"manifest":      wtv.Manifest,
+			"manifest_hash": wtv.ManifestHash(),
+			"is_latest":     wtv.IsLatest,
+			"parameters":    string(pj),
 		}).
 		Where(sq.Eq{
 			"id": wtv.ID,
@@ -104,6 +207,22 @@ func updateWorkflowTemplateVersionDB(runner sq.BaseRunner, wtv *WorkflowTemplate
 	return
 }
 
+// nextWorkflowTemplateVersion returns the version for a new workflow template version created at now.
+// Versions are timestamps, but must be strictly increasing. If the clock went backwards, or a version was edited,
+// so that now is not after maxVersion, maxVersion + 1 is used instead.
+func nextWorkflowTemplateVersion(now, maxVersion int64) int64 {
+	if now > maxVersion {
+		return now
+	}
+
+	log.WithFields(log.Fields{
+		"Now":        now,
+		"MaxVersion": maxVersion,
+	}).Warn("Workflow template version timestamp is not after the current version, using the next version instead.")
+
+	return maxVersion + 1
+}
+
 // createLatestWorkflowTemplateVersionDB creates a new workflow template version and marks all previous versions as not latest.
 func createLatestWorkflowTemplateVersionDB(runner sq.BaseRunner, workflowTemplateVersion *WorkflowTemplateVersion) (err error) {
 	if workflowTemplateVersion == nil {
@@ -116,6 +235,37 @@ func createLatestWorkflowTemplateVersionDB(runner sq.BaseRunner, workflowTemplat
 		return fmt.Errorf("workflowTemplateVersion.WorkflowTemplate.ID must be > 0. %v given", workflowTemplateVersion.WorkflowTemplate.ID)
 	}
 
+	// Lock the workflow template until the transaction ends, so concurrent versions are created one after the other
+	// and each one reads the max version the previous one created.
+	_, err = sb.Select("id").
+		From("workflow_templates").
+		Where(sq.Eq{
+			"id": workflowTemplateVersion.WorkflowTemplate.ID,
+		}).
+		Suffix("FOR UPDATE").
+		RunWith(runner).
+		Exec()
+	if err != nil {
+		return err
+	}
+
+	if workflowTemplateVersion.Version == 0 {
+		maxVersion := int64(0)
+		err = sb.Select("COALESCE(MAX(version), 0)").
+			From("workflow_template_versions").
+			Where(sq.Eq{
+				"workflow_template_id": workflowTemplateVersion.WorkflowTemplate.ID,
+			}).
+			RunWith(runner).
+			QueryRow().
+			Scan(&maxVersion)
+		if err != nil {
+			return err
+		}
+
+		workflowTemplateVersion.Version = nextWorkflowTemplateVersion(time.Now().UnixNano(), maxVersion)
+	}
+
 	_, err = sb.Update("workflow_template_versions").
 		Set("is_latest", false).
 		Where(sq.Eq{
@@ -134,12 +284,19 @@ func createLatestWorkflowTemplateVersionDB(runner sq.BaseRunner, workflowTemplat
 	return createWorkflowTemplateVersionDB(runner, workflowTemplateVersion, params)
 }
 
+// workflowTemplateUIDAttempts is the number of uids createWorkflowTemplate tries before giving up
+const workflowTemplateUIDAttempts = 3
+
 // createWorkflowTemplate creates a WorkflowTemplate and all of the DB/Argo/K8s related resources
 // The returned WorkflowTemplate has the ArgoWorkflowTemplate set to the newly created one.
 func (c *Client) createWorkflowTemplate(namespace string, workflowTemplate *WorkflowTemplate) (*WorkflowTemplate, *WorkflowTemplateVersion, error) {
 	if err := workflowTemplate.GenerateUID(workflowTemplate.Name); err != nil {
 		return nil, nil, util.NewUserError(codes.InvalidArgument, "Template name must be 30 characters or less")
 	}
+	// the uid may get a suffix below, so the final one is invalidated
+	defer func() {
+		c.InvalidateWorkflowTemplateCache(namespace, workflowTemplate.UID)
+	}()
 
 	tx, err := c.DB.Begin()
 	if err != nil {
@@ -147,20 +304,41 @@ func (c *Client) createWorkflowTemplate(namespace string, workflowTemplate *Work
 	}
 	defer tx.Rollback()
 
-	err = sb.Insert("workflow_templates").
-		SetMap(sq.Eq{
-			"uid":       workflowTemplate.UID,
-			"name":      workflowTemplate.Name,
-			"namespace": namespace,
-			"is_system": workflowTemplate.IsSystem,
-			"labels":    workflowTemplate.Labels,
-		}).
-		Suffix("RETURNING id").
-		RunWith(tx).
-		QueryRow().
-		Scan(&workflowTemplate.ID)
-	if err != nil {
-		return nil, nil, err
+	// Different names can generate the same uid, e.g. "My Template" and "my-template".
+	// If that happens, we try again with a random suffix added to the uid.
+	for attempt := 1; ; attempt++ {
+		err = sb.Insert("workflow_templates").
+			SetMap(sq.Eq{
+				"uid":        workflowTemplate.UID,
+				"name":       workflowTemplate.Name,
+				"namespace":  namespace,
+				"is_system":  workflowTemplate.IsSystem,
+				"is_public":  workflowTemplate.IsPublic,
+				"labels":     workflowTemplate.Labels,
+				"created_by": c.Actor,
+				"category":   workflowTemplate.Category,
+				"idempotency_key": sql.NullString{
+					String: workflowTemplate.IdempotencyKey,
+					Valid:  workflowTemplate.IdempotencyKey != "",
+				},
+				"base_template_uid": workflowTemplate.BaseTemplateUID,
+			}).
+			Suffix("ON CONFLICT (uid, namespace) WHERE is_archived = false DO NOTHING RETURNING id").
+			RunWith(tx).
+			QueryRow().
+			Scan(&workflowTemplate.ID)
+		if err == nil {
+			break
+		}
+		if err != sql.ErrNoRows {
+			return nil, nil, err
+		}
+		if attempt == workflowTemplateUIDAttempts {
+			return nil, nil, util.NewUserError(codes.AlreadyExists, fmt.Sprintf("Unable to generate a unique uid for workflow template '%v', try a different name.", workflowTemplate.Name))
+		}
+		if err := workflowTemplate.GenerateUIDWithSuffix(workflowTemplate.Name); err != nil {
+			return nil, nil, err
+		}
 	}
 
 	params, err := ParseParametersFromManifest([]byte(workflowTemplate.Manifest))
@@ -168,10 +346,19 @@ func (c *Client) createWorkflowTemplate(namespace string, workflowTemplate *Work
 		return nil, nil, util.NewUserError(codes.InvalidArgument, err.Error())
 	}
 
+	if err := c.loadWorkflowTemplateBase(namespace, workflowTemplate); err != nil {
+		return nil, nil, err
+	}
+	resolvedManifest, err := workflowTemplate.resolvedManifest()
+	if err != nil {
+		return nil, nil, util.NewUserError(codes.InvalidArgument, err.Error())
+	}
+
 	workflowTemplateVersion := &WorkflowTemplateVersion{
 		WorkflowTemplate: workflowTemplate,
 		Manifest:         workflowTemplate.Manifest,
 		Labels:           workflowTemplate.Labels,
+		ResolvedManifest: resolvedManifest,
 	}
 	err = createWorkflowTemplateVersionDB(tx, workflowTemplateVersion, params)
 	if err != nil {
@@ -179,7 +366,11 @@ func (c *Client) createWorkflowTemplate(namespace string, workflowTemplate *Work
 	}
 	workflowTemplate.WorkflowTemplateVersionID = workflowTemplateVersion.ID
 
-	argoWft, err := createArgoWorkflowTemplate(workflowTemplate, workflowTemplateVersion.Version)
+	if err := c.insertWorkflowTemplateAuditEntry(tx, workflowTemplate.ID, AuditActionCreate, &workflowTemplateVersion.Version); err != nil {
+		return nil, nil, err
+	}
+
+	argoWft, err := createArgoWorkflowTemplate(workflowTemplate, workflowTemplateVersion.Version, c.NamespaceDefaultLabels[namespace])
 	if err != nil {
 		return nil, nil, err
 	}
@@ -204,11 +395,47 @@ func (c *Client) createWorkflowTemplate(namespace string, workflowTemplate *Work
 	}
 
 	workflowTemplate.ArgoWorkflowTemplate = argoWft
+	workflowTemplate.ArgoName = argoWft.Name
 	workflowTemplate.Version = workflowTemplateVersion.Version
 
 	return workflowTemplate, workflowTemplateVersion, nil
 }
 
+// deleteWorkflowTemplate removes a workflow template created by createWorkflowTemplate, including its argo workflow templates.
+// It is used to undo a creation that is part of a larger operation that failed. Versions and fragments
+// are removed with the workflow template by the database, the audit log entries of the creation are removed here.
+func (c *Client) deleteWorkflowTemplate(namespace string, workflowTemplate *WorkflowTemplate) error {
+	argoWorkflowTemplates, err := c.listArgoWorkflowTemplates(namespace, workflowTemplate.UID)
+	if err != nil {
+		return err
+	}
+
+	for _, argoWft := range *argoWorkflowTemplates {
+		if err := c.ArgoprojV1alpha1().WorkflowTemplates(namespace).Delete(argoWft.Name, &v1.DeleteOptions{}); err != nil {
+			return err
+		}
+	}
+
+	_, err = sb.Delete("workflow_template_audit_logs").
+		Where(sq.Eq{
+			"workflow_template_id": workflowTemplate.ID,
+		}).
+		RunWith(c.DB).
+		Exec()
+	if err != nil {
+		return err
+	}
+
+	_, err = sb.Delete("workflow_templates").
+		Where(sq.Eq{
+			"id": workflowTemplate.ID,
+		}).
+		RunWith(c.DB).
+		Exec()
+
+	return err
+}
+
 // baseWorkflowTemplatesSelectBuilder returns a SelectBuilder selecting a WorkflowTemplate from the database for a given namespace
 // no columns are selected.
 func (c *Client) baseWorkflowTemplatesSelectBuilder(namespace string) sq.SelectBuilder {
@@ -252,6 +479,20 @@ func (c *Client) workflowTemplatesVersionSelectBuilder(namespace string) sq.Sele
 	return sb
 }
 
+// latestWorkflowTemplateVersionsSelectBuilder selects the non-archived workflow templates of the namespace
+// with the manifest and version of their latest version
+func (c *Client) latestWorkflowTemplateVersionsSelectBuilder(namespace string) sq.SelectBuilder {
+	sb := c.workflowTemplatesSelectBuilder(namespace).
+		Columns("wtv.manifest", "wtv.version", "wtv.id workflow_template_version_id", "wtv.created_at modified_at").
+		Join("workflow_template_versions wtv ON wt.id = wtv.workflow_template_id").
+		Where(sq.Eq{
+			"wt.is_archived": false,
+			"wtv.is_latest":  true,
+		})
+
+	return sb
+}
+
 // workflowTemplateVersionSelectBuilderAll
 func (c *Client) workflowTemplateVersionSelectBuilderAll() sq.SelectBuilder {
 	sb := sb.Select(getWorkflowTemplateVersionColumns("wtv")...).
@@ -328,6 +569,9 @@ func (c *Client) getWorkflowTemplateById(id uint64) (workflowTemplate *WorkflowT
 	return
 }
 
+// latestWorkflowTemplateVersionColumn selects the version of the latest workflow template version of "wt" as latest_version
+const latestWorkflowTemplateVersionColumn = "COALESCE((SELECT lwtv.version FROM workflow_template_versions lwtv WHERE lwtv.workflow_template_id = wt.id AND lwtv.is_latest), 0) latest_version"
+
 // getWorkflowTemplate gets the workflowtemplate given the input data.
 // it also loads the argo workflow and labels data.
 // If version is <= 0, the latest workflow template is fetched.
@@ -340,7 +584,7 @@ func (c *Client) getWorkflowTemplate(namespace, uid string, version int64) (work
 	// A new workflow template version is created upon a change, so we use it's created_at
 	// as a modified_at for the workflow template.
 	sb := c.workflowTemplatesSelectBuilder(namespace).
-		Columns("wtv.manifest", "wtv.version", "wtv.id workflow_template_version_id", "wtv.created_at modified_at").
+		Columns("wtv.manifest", "wtv.version", "wtv.id workflow_template_version_id", "wtv.created_at modified_at", latestWorkflowTemplateVersionColumn).
 		Join("workflow_template_versions wtv ON wt.id = wtv.workflow_template_id").
 		Where(sq.Eq{
 			"wt.uid":         uid,
@@ -371,13 +615,25 @@ func (c *Client) getWorkflowTemplate(namespace, uid string, version int64) (work
 		return nil, err
 	}
 	workflowTemplate.ArgoWorkflowTemplate = argoWft
+	workflowTemplate.ArgoName = argoWft.Name
+	workflowTemplate.SetAnnotationsFromArgoWorkflowTemplate()
 
+	// the version from the database is kept if the argo label is missing or malformed, so the template can still be read
 	templateVersion, err := strconv.ParseInt(argoWft.Labels[label.Version], 10, 64)
 	if err != nil {
-		return nil, err
+		log.WithFields(log.Fields{
+			"Namespace":    namespace,
+			"UID":          uid,
+			"Version":      workflowTemplate.Version,
+			"VersionLabel": argoWft.Labels[label.Version],
+			"Error":        err.Error(),
+		}).Warn("Argo workflow template version label is not a number, using the database version.")
+	} else {
+		workflowTemplate.Version = templateVersion
 	}
 
-	workflowTemplate.Version = templateVersion
+	workflowTemplate.RequestedVersion = version
+	workflowTemplate.ResolvedLatest = version <= 0
 
 	wtv, err := c.getWorkflowTemplateVersionDB(namespace, workflowTemplate.Name, versionAsString)
 	if err != nil {
@@ -388,6 +644,39 @@ func (c *Client) getWorkflowTemplate(namespace, uid string, version int64) (work
 	return workflowTemplate, nil
 }
 
+// getWorkflowTemplateMetadata is like getWorkflowTemplate, but only loads the data in the database without the manifest.
+// Argo is not called, so the annotations and parameters are not set.
+func (c *Client) getWorkflowTemplateMetadata(namespace, uid string, version int64) (workflowTemplate *WorkflowTemplate, err error) {
+	workflowTemplate = &WorkflowTemplate{}
+
+	sb := c.workflowTemplatesSelectBuilder(namespace).
+		Columns("wtv.version", "wtv.id workflow_template_version_id", "wtv.created_at modified_at", latestWorkflowTemplateVersionColumn).
+		Join("workflow_template_versions wtv ON wt.id = wtv.workflow_template_id").
+		Where(sq.Eq{
+			"wt.uid":         uid,
+			"wt.is_archived": false,
+		})
+
+	if version <= 0 {
+		sb = sb.Where(sq.Eq{"wtv.is_latest": true})
+	} else {
+		sb = sb.Where(sq.Eq{"wtv.version": version})
+	}
+
+	if err = c.Getx(workflowTemplate, sb); err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil
+		}
+
+		return nil, err
+	}
+
+	workflowTemplate.RequestedVersion = version
+	workflowTemplate.ResolvedLatest = version <= 0
+
+	return workflowTemplate, nil
+}
+
 // listWorkflowTemplateVersions grabs WorkflowTemplateVersions and returns them as WorkflowTemplates.
 func (c *Client) listWorkflowTemplateVersions(namespace, uid string) (workflowTemplateVersions []*WorkflowTemplate, err error) {
 	dbVersions, err := c.selectWorkflowTemplateVersionsDB(namespace, uid)
@@ -414,21 +703,57 @@ func (c *Client) listWorkflowTemplateVersions(namespace, uid string) (workflowTe
 	return
 }
 
+// workflowTemplatesInUseJoin selects the ids of workflow templates that are referenced by a non-archived
+// workflow execution or cron workflow. Each id appears at most once.
+const workflowTemplatesInUseJoin = `(
+	SELECT DISTINCT refs.workflow_template_id
+	FROM (
+		SELECT wtv.workflow_template_id
+		FROM workflow_executions we
+		JOIN workflow_template_versions wtv ON wtv.id = we.workflow_template_version_id
+		WHERE we.is_archived = false
+		UNION
+		SELECT wtv.workflow_template_id
+		FROM cron_workflows cw
+		JOIN workflow_template_versions wtv ON wtv.id = cw.workflow_template_version_id
+		WHERE cw.is_archived = false
+	) refs
+) wtu ON wtu.workflow_template_id = wt.id`
+
 func (c *Client) selectWorkflowTemplatesQuery(namespace string, request *request.Request) (sb sq.SelectBuilder) {
 	sb = c.workflowTemplatesSelectBuilder(namespace).
-		Column("COUNT(wtv.*) versions, MAX(wtv.id) workflow_template_version_id").
+		Column("COUNT(wtv.*) versions, MAX(wtv.id) workflow_template_version_id, MAX(wtv.created_at) last_versioned_at").
+		Column("BOOL_OR(wtu.workflow_template_id IS NOT NULL) in_use").
 		Join("workflow_template_versions wtv ON wtv.workflow_template_id = wt.id").
+		LeftJoin(workflowTemplatesInUseJoin).
 		GroupBy("wt.id", "wt.created_at", "wt.uid", "wt.name", "wt.is_archived").
-		OrderBy("wt.created_at DESC")
+		OrderBy("wt.is_deprecated")
 
-	sb = applyLabelSelectQuery(sb, request)
+	return applyWorkflowTemplateListRequest(sb, request)
+}
+
+// applyWorkflowTemplateListRequest applies the sorting, filter and pagination of the request to a query of workflow templates "wt".
+// Without sorting, the newest workflow templates are first.
+func applyWorkflowTemplateListRequest(sb sq.SelectBuilder, request *request.Request) sq.SelectBuilder {
+	if request.HasSorting() {
+		properties := getWorkflowTemplateSortColumnsMap()
+		for _, order := range request.Sort.Properties {
+			if columnName, ok := properties[order.Property]; ok {
+				sb = sb.OrderBy(fmt.Sprintf("wt.%v %v", columnName, order.Direction))
+			}
+		}
+	} else {
+		sb = sb.OrderBy("wt.created_at DESC")
+	}
+
+	sb = applyWorkflowTemplateFilter(sb, request)
 	sb = *request.ApplyPaginationToSelect(&sb)
 
-	return
+	return sb
 }
 
 // selectWorkflowTemplatesDB loads non-archived and non-system workflow templates from the database for the input namespace
-// it also selects the total number of versions and latest version id
+// it also selects the total number of versions, latest version id, when it was last versioned and whether the template is in use
 func (c *Client) selectWorkflowTemplatesDB(namespace string, request *request.Request) (workflowTemplates []*WorkflowTemplate, err error) {
 	workflowTemplates = make([]*WorkflowTemplate, 0)
 
@@ -465,7 +790,7 @@ func (c *Client) CountWorkflowTemplates(namespace string, request *request.Reque
 			"wt.is_system":   false,
 		})
 
-	sb = applyLabelSelectQuery(sb, request)
+	sb = applyWorkflowTemplateFilter(sb, request)
 
 	err = sb.RunWith(c.DB).
 		QueryRow().
@@ -474,56 +799,234 @@ func (c *Client) CountWorkflowTemplates(namespace string, request *request.Reque
 	return
 }
 
+// ApplyNamespaceParameterDefaults sets the parameters of the workflow template's manifest that have no value
+// to the namespace's default for the parameter, see GetNamespaceParameterDefaults and InjectParameterDefaults.
+// It is used for validation and to preview a workflow template as it would run in the namespace.
+func (c *Client) ApplyNamespaceParameterDefaults(namespace string, workflowTemplate *WorkflowTemplate) (injected []string, err error) {
+	defaults, err := c.GetNamespaceParameterDefaults(namespace)
+	if err != nil {
+		return nil, err
+	}
+
+	return workflowTemplate.InjectParameterDefaults(defaults)
+}
+
 func (c *Client) validateWorkflowTemplate(namespace string, workflowTemplate *WorkflowTemplate) (err error) {
-	// validate workflow template
-	finalBytes, err := workflowTemplate.WrapSpec()
+	if err = c.loadWorkflowTemplateFragments(namespace, workflowTemplate); err != nil {
+		return
+	}
+
+	if err = c.loadWorkflowTemplateBase(namespace, workflowTemplate); err != nil {
+		return
+	}
+
+	if err = validateJSONManifest(workflowTemplate.GetManifestBytes()); err != nil {
+		return
+	}
+
+	// validate workflow template, as it will run in the namespace, with the namespace's parameter defaults
+	withDefaults := *workflowTemplate
+	if _, err = c.ApplyNamespaceParameterDefaults(namespace, &withDefaults); err != nil {
+		return
+	}
+	finalBytes, err := withDefaults.WrapSpec()
 	if err != nil {
 		return
 	}
-	err = c.ValidateWorkflowExecution(namespace, finalBytes)
+	// onepanel checks run first as their errors are more specific than argo's
+	err = c.validateWorkflowTemplateManifest(namespace, workflowTemplate)
+	if err == nil {
+		err = c.ValidateWorkflowExecution(namespace, finalBytes)
+	}
 	if err != nil {
 		log.WithFields(log.Fields{
 			"Namespace":        namespace,
-			"WorkflowTemplate": workflowTemplate,
+			"WorkflowTemplate": workflowTemplate.LogEntry(),
 			"Error":            err.Error(),
 		}).Error("Workflow could not be validated.")
+		workflowTemplate.logManifest()
 	}
 
 	return
 }
 
-func (c *Client) CreateWorkflowTemplate(namespace string, workflowTemplate *WorkflowTemplate) (*WorkflowTemplate, error) {
-	// validate workflow template
+// prevalidateWorkflowTemplate prepares and validates a workflow template before it, or a version of it, is created.
+// Empty manifests are rejected, the server managed fields are removed from the manifest, and the manifest is validated
+// with the namespace's parameter defaults. Errors are returned as codes.InvalidArgument.
+func (c *Client) prevalidateWorkflowTemplate(namespace string, workflowTemplate *WorkflowTemplate) error {
+	if workflowTemplate.IsManifestEmpty() {
+		return util.NewUserError(codes.InvalidArgument, ErrEmptyManifest.Error())
+	}
+
+	if err := workflowTemplate.StripServerManagedFields(); err != nil {
+		return util.NewUserError(codes.InvalidArgument, err.Error())
+	}
+
 	if err := c.validateWorkflowTemplate(namespace, workflowTemplate); err != nil {
-		return nil, util.NewUserError(codes.InvalidArgument, err.Error())
+		return workflowTemplateValidationUserError(err, err.Error())
+	}
+
+	if err := c.validateCreatedWorkflowTemplate(namespace, workflowTemplate); err != nil {
+		return util.NewUserError(codes.InvalidArgument, err.Error())
+	}
+
+	return nil
+}
+
+func (c *Client) CreateWorkflowTemplate(namespace string, workflowTemplate *WorkflowTemplate) (*WorkflowTemplate, error) {
+	if err := c.prevalidateWorkflowTemplate(namespace, workflowTemplate); err != nil {
+		return nil, err
 	}
 
 	newWorkflowTemplate, _, err := c.createWorkflowTemplate(namespace, workflowTemplate)
 	if err != nil {
 		log.WithFields(log.Fields{
 			"Namespace":        namespace,
-			"WorkflowTemplate": workflowTemplate,
+			"WorkflowTemplate": workflowTemplate.LogEntry(),
 			"Error":            err.Error(),
 		}).Error("Could not create workflow template.")
+		workflowTemplate.logManifest()
 		return nil, util.NewUserErrorWrap(err, "Workflow template")
 	}
 
 	return newWorkflowTemplate, nil
 }
 
+// CreateWorkflowTemplateWithIdempotencyKey is like CreateWorkflowTemplate, but the idempotencyKey is stored with the workflow template.
+// If a non-archived workflow template in the namespace already has the key, it is returned instead of creating a new one,
+// so clients can safely retry a create whose response was lost. An empty idempotencyKey creates the workflow template as usual.
+func (c *Client) CreateWorkflowTemplateWithIdempotencyKey(namespace, idempotencyKey string, workflowTemplate *WorkflowTemplate) (*WorkflowTemplate, error) {
+	if idempotencyKey == "" {
+		return c.CreateWorkflowTemplate(namespace, workflowTemplate)
+	}
+
+	existing, err := c.getWorkflowTemplateByIdempotencyKey(namespace, idempotencyKey)
+	if err != nil || existing != nil {
+		return existing, err
+	}
+
+	workflowTemplate.IdempotencyKey = idempotencyKey
+	created, err := c.CreateWorkflowTemplate(namespace, workflowTemplate)
+	if err != nil {
+		// a concurrent create with the same key may have won
+		if existing, lookupErr := c.getWorkflowTemplateByIdempotencyKey(namespace, idempotencyKey); lookupErr == nil && existing != nil {
+			return existing, nil
+		}
+		return nil, err
+	}
+
+	return created, nil
+}
+
+// getWorkflowTemplateByIdempotencyKey returns the latest version of the non-archived workflow template with the idempotency key,
+// or nil if there is none.
+func (c *Client) getWorkflowTemplateByIdempotencyKey(namespace, idempotencyKey string) (*WorkflowTemplate, error) {
+	uids := make([]string, 0)
+	query := sb.Select("uid").
+		From("workflow_templates").
+		Where(sq.Eq{
+			"namespace":       namespace,
+			"idempotency_key": idempotencyKey,
+			"is_archived":     false,
+		})
+	if err := c.DB.Selectx(&uids, query); err != nil {
+		return nil, util.NewUserErrorWrap(err, "Workflow template")
+	}
+
+	if len(uids) == 0 {
+		return nil, nil
+	}
+
+	workflowTemplate, err := c.GetWorkflowTemplate(namespace, uids[0], 0)
+	if err != nil {
+		return nil, err
+	}
+	workflowTemplate.IdempotencyKey = idempotencyKey
+
+	return workflowTemplate, nil
+}
+
+// createWorkflowTemplatesBatchParallelism is how many workflow templates CreateWorkflowTemplatesBatch creates at once.
+const createWorkflowTemplatesBatchParallelism = 4
+
+// CreateWorkflowTemplatesBatch creates the workflow templates, see CreateWorkflowTemplate.
+// All workflow templates are validated before any is created, then the valid ones are created in parallel.
+// The results are in the same order as workflowTemplates. A failed workflow template has a nil result and its error,
+// it does not stop the others from being created.
+func (c *Client) CreateWorkflowTemplatesBatch(namespace string, workflowTemplates []*WorkflowTemplate) ([]*WorkflowTemplate, []error) {
+	results := make([]*WorkflowTemplate, len(workflowTemplates))
+	errs := make([]error, len(workflowTemplates))
+
+	for i, workflowTemplate := range workflowTemplates {
+		errs[i] = c.prevalidateWorkflowTemplate(namespace, workflowTemplate)
+	}
+
+	// Each creation runs in its own transaction, the database pool hands out a connection per transaction.
+	limiter := make(chan struct{}, createWorkflowTemplatesBatchParallelism)
+	wg := sync.WaitGroup{}
+	for i, workflowTemplate := range workflowTemplates {
+		if errs[i] != nil {
+			continue
+		}
+
+		wg.Add(1)
+		limiter <- struct{}{}
+		go func(i int, workflowTemplate *WorkflowTemplate) {
+			defer func() {
+				<-limiter
+				wg.Done()
+			}()
+
+			newWorkflowTemplate, _, err := c.createWorkflowTemplate(namespace, workflowTemplate)
+			if err != nil {
+				log.WithFields(log.Fields{
+					"Namespace":        namespace,
+					"WorkflowTemplate": workflowTemplate.LogEntry(),
+					"Error":            err.Error(),
+				}).Error("Could not create workflow template.")
+				errs[i] = util.NewUserErrorWrap(err, "Workflow template")
+				return
+			}
+			results[i] = newWorkflowTemplate
+		}(i, workflowTemplate)
+	}
+	wg.Wait()
+
+	return results, errs
+}
+
 // CreateWorkflowTemplateVersion creates a new workflow template version including argo resources.
 // It marks any older workflow template versions as not latest
 //
 // Pre-condition: a Workflow Template version already exists
 // Post-condition: the input workflow template will have it's fields updated so it matches the new version data.
 func (c *Client) CreateWorkflowTemplateVersion(namespace string, workflowTemplate *WorkflowTemplate) (*WorkflowTemplate, error) {
+	return c.CreateWorkflowTemplateVersionWithOptions(namespace, workflowTemplate, nil)
+}
+
+// CreateWorkflowTemplateVersionWithOptions is like CreateWorkflowTemplateVersion, with the behavior modified by opts.
+// opts may be nil, in which case the defaults are used.
+func (c *Client) CreateWorkflowTemplateVersionWithOptions(namespace string, workflowTemplate *WorkflowTemplate, opts *WorkflowTemplateVersionOptions) (*WorkflowTemplate, error) {
+	if opts == nil {
+		opts = &WorkflowTemplateVersionOptions{}
+	}
+
 	if workflowTemplate.UID == "" {
 		return nil, fmt.Errorf("uid required for CreateWorkflowTemplateVersion")
 	}
+	defer c.InvalidateWorkflowTemplateCache(namespace, workflowTemplate.UID)
 
-	// validate workflow template
-	if err := c.validateWorkflowTemplate(namespace, workflowTemplate); err != nil {
-		return nil, util.NewUserError(codes.InvalidArgument, err.Error())
+	if workflowTemplate.BaseTemplateUID == "" && !opts.ClearBaseTemplate {
+		// a missing workflow template is reported once it is loaded below
+		baseTemplateUID, err := c.getWorkflowTemplateBaseTemplateUID(namespace, workflowTemplate.UID)
+		if userErr, ok := err.(*util.UserError); err != nil && (!ok || userErr.Code != codes.NotFound) {
+			return nil, err
+		}
+		workflowTemplate.BaseTemplateUID = baseTemplateUID
+	}
+
+	if err := c.prevalidateWorkflowTemplate(namespace, workflowTemplate); err != nil {
+		return nil, err
 	}
 
 	tx, err := c.DB.Begin()
@@ -539,13 +1042,46 @@ func (c *Client) CreateWorkflowTemplateVersion(namespace string, workflowTemplat
 		})
 	workflowTemplateDB := &WorkflowTemplate{}
 	if err = c.DB.Getx(workflowTemplateDB, wftSb); err != nil {
+		if err == sql.ErrNoRows {
+			return nil, c.missingWorkflowTemplateUserError(namespace, workflowTemplate.UID)
+		}
 		return nil, err
 	}
 
+	latest, err := c.getArgoWorkflowTemplate(namespace, workflowTemplate.UID, "latest")
+	if err != nil {
+		log.WithFields(log.Fields{
+			"Namespace":        namespace,
+			"WorkflowTemplate": workflowTemplate.LogEntry(),
+			"Error":            err.Error(),
+		}).Error("Could not get latest argo workflow template")
+		workflowTemplate.logManifest()
+
+		return nil, argoWorkflowTemplateUserError(err, "Unable to create workflow template version.")
+	}
+
+	if opts.InheritLabels {
+		inheritedLabels := tagLabelsFromArgo(latest.Labels, c.NamespaceDefaultLabels[namespace])
+		if workflowTemplate.Labels == nil {
+			workflowTemplate.Labels = make(types.JSONLabels)
+		}
+		for key, value := range inheritedLabels {
+			if _, ok := workflowTemplate.Labels[key]; !ok {
+				workflowTemplate.Labels[key] = value
+			}
+		}
+	}
+
+	resolvedManifest, err := workflowTemplate.resolvedManifest()
+	if err != nil {
+		return nil, util.NewUserError(codes.InvalidArgument, err.Error())
+	}
+
 	workflowTemplateVersion := &WorkflowTemplateVersion{
 		WorkflowTemplate: workflowTemplateDB,
 		Manifest:         workflowTemplate.Manifest,
 		Labels:           workflowTemplate.Labels,
+		ResolvedManifest: resolvedManifest,
 	}
 
 	err = createLatestWorkflowTemplateVersionDB(tx, workflowTemplateVersion)
@@ -554,7 +1090,11 @@ func (c *Client) CreateWorkflowTemplateVersion(namespace string, workflowTemplat
 	}
 	workflowTemplate.WorkflowTemplateVersionID = workflowTemplateVersion.ID
 
-	updatedTemplate, err := createArgoWorkflowTemplate(workflowTemplate, workflowTemplateVersion.Version)
+	if err := c.insertWorkflowTemplateAuditEntry(tx, workflowTemplateDB.ID, AuditActionVersion, &workflowTemplateVersion.Version); err != nil {
+		return nil, err
+	}
+
+	updatedTemplate, err := createArgoWorkflowTemplate(workflowTemplate, workflowTemplateVersion.Version, c.NamespaceDefaultLabels[namespace])
 	if err != nil {
 		return nil, err
 	}
@@ -564,7 +1104,7 @@ func (c *Client) CreateWorkflowTemplateVersion(namespace string, workflowTemplat
 	updatedTemplate.ObjectMeta.SetSelfLink("")
 	updatedTemplate.Labels[label.WorkflowTemplateVersionUid] = strconv.FormatInt(workflowTemplateVersion.Version, 10)
 
-	parametersMap, err := workflowTemplate.GetParametersKeyString()
+	parameters, err := workflowTemplate.GetParametersKeyStringOrdered()
 	if err != nil {
 		return nil, err
 	}
@@ -572,23 +1112,14 @@ func (c *Client) CreateWorkflowTemplateVersion(namespace string, workflowTemplat
 	if updatedTemplate.Annotations == nil {
 		updatedTemplate.Annotations = make(map[string]string)
 	}
-	for key, value := range parametersMap {
-		updatedTemplate.Annotations[key] = value
+	for _, parameter := range parameters {
+		updatedTemplate.Annotations[parameter.Name] = parameter.Value
 	}
 
-	latest, err := c.getArgoWorkflowTemplate(namespace, workflowTemplate.UID, "latest")
+	delete(latest.Labels, label.VersionLatest)
+
+	createdTemplate, err := c.ArgoprojV1alpha1().WorkflowTemplates(namespace).Create(updatedTemplate)
 	if err != nil {
-		log.WithFields(log.Fields{
-			"Namespace":        namespace,
-			"WorkflowTemplate": workflowTemplate,
-			"Error":            err.Error(),
-		}).Error("Could not get latest argo workflow template")
-
-		return nil, err
-	}
-	delete(latest.Labels, label.VersionLatest)
-
-	if _, err := c.ArgoprojV1alpha1().WorkflowTemplates(namespace).Create(updatedTemplate); err != nil {
 		return nil, err
 	}
 
@@ -597,9 +1128,11 @@ func (c *Client) CreateWorkflowTemplateVersion(namespace string, workflowTemplat
 		return nil, err
 	}
 
-	// Make sure the associated workflow template has the latest labels
+	// Make sure the associated workflow template has the latest labels and base template
 	_, err = sb.Update("workflow_templates").
 		Set("labels", workflowTemplate.Labels).
+		Set("base_template_uid", workflowTemplate.BaseTemplateUID).
+		Set("updated_at", time.Now().UTC()).
 		Where(sq.Eq{
 			"id": workflowTemplateDB.ID,
 		}).
@@ -615,10 +1148,55 @@ func (c *Client) CreateWorkflowTemplateVersion(namespace string, workflowTemplat
 
 	workflowTemplate.ID = workflowTemplateDB.ID
 	workflowTemplate.Version = workflowTemplateVersion.Version
+	workflowTemplate.ArgoName = createdTemplate.Name
 
 	return workflowTemplate, nil
 }
 
+// missingWorkflowTemplateUserError returns the error for a non-archived workflow template that could not be found.
+// If the workflow template exists but is archived, a FailedPrecondition error is returned, otherwise NotFound.
+func (c *Client) missingWorkflowTemplateUserError(namespace, uid string) error {
+	count := 0
+	query := sb.Select("COUNT(*)").
+		From("workflow_templates").
+		Where(sq.Eq{
+			"namespace":   namespace,
+			"uid":         uid,
+			"is_archived": true,
+		})
+	if err := c.DB.Getx(&count, query); err != nil {
+		return err
+	}
+
+	if count > 0 {
+		return util.NewUserError(codes.FailedPrecondition, "Workflow template is archived. Unarchive it before creating a new version.")
+	}
+
+	return util.NewUserError(codes.NotFound, "Workflow template not found.")
+}
+
+// TouchWorkflowTemplate sets the updated_at of the non-archived workflow template to the current time.
+func (c *Client) TouchWorkflowTemplate(namespace, uid string) error {
+	defer c.InvalidateWorkflowTemplateCache(namespace, uid)
+
+	return touchWorkflowTemplate(c.DB, namespace, uid)
+}
+
+// touchWorkflowTemplate is TouchWorkflowTemplate using the runner, so it can be part of a transaction.
+func touchWorkflowTemplate(runner sq.BaseRunner, namespace, uid string) error {
+	_, err := sb.Update("workflow_templates").
+		Set("updated_at", time.Now().UTC()).
+		Where(sq.Eq{
+			"namespace":   namespace,
+			"uid":         uid,
+			"is_archived": false,
+		}).
+		RunWith(runner).
+		Exec()
+
+	return err
+}
+
 // UpdateWorkflowTemplateVersion will update a given WorkflowTemplateVersion in the database.
 // The intent is to change specific database values for a WorkflowTemplateVersion.
 // - wtv.ID has to be set and greater than 0
@@ -626,6 +1204,12 @@ func (c *Client) UpdateWorkflowTemplateVersion(wtv *WorkflowTemplateVersion) err
 	if wtv.ID <= 0 {
 		return fmt.Errorf("id required for UpdateWorkflowTemplateVersionDB")
 	}
+	if wtv.WorkflowTemplate != nil {
+		defer c.InvalidateWorkflowTemplateCache(wtv.WorkflowTemplate.Namespace, wtv.WorkflowTemplate.UID)
+	} else {
+		defer c.InvalidateWorkflowTemplateCache("", "")
+	}
+
 	return updateWorkflowTemplateVersionDB(c.DB, wtv)
 }
 
@@ -637,281 +1221,2529 @@ func (c *Client) UpdateWorkflowTemplateVersion(wtv *WorkflowTemplateVersion) err
 // * ArgoWorkflowTemplate
 // * Labels
 func (c *Client) GetWorkflowTemplate(namespace, uid string, version int64) (workflowTemplate *WorkflowTemplate, err error) {
-	workflowTemplate, err = c.getWorkflowTemplate(namespace, uid, version)
+	return c.GetWorkflowTemplateWithOptions(namespace, uid, version, nil)
+}
+
+// GetWorkflowTemplateWithOptions is like GetWorkflowTemplate, with what is loaded modified by opts.
+// opts may be nil, in which case everything is loaded.
+func (c *Client) GetWorkflowTemplateWithOptions(namespace, uid string, version int64, opts *GetWorkflowTemplateOptions) (workflowTemplate *WorkflowTemplate, err error) {
+	if opts != nil && opts.MetadataOnly {
+		workflowTemplate, err = c.getWorkflowTemplateMetadata(namespace, uid, version)
+	} else {
+		workflowTemplate, err = c.getWorkflowTemplateCached(namespace, uid, version)
+	}
 	if err != nil {
 		log.WithFields(log.Fields{
-			"Namespace":        namespace,
-			"WorkflowTemplate": workflowTemplate,
-			"Error":            err.Error(),
+			"Namespace": namespace,
+			"UID":       uid,
+			"Version":   version,
+			"Error":     err.Error(),
 		}).Error("Get Workflow Template failed.")
-		return nil, util.NewUserError(codes.Unknown, "Unknown error.")
+		return nil, argoWorkflowTemplateUserError(err, "Unknown error.")
+	}
+	// self healing loads the argo workflow templates, which MetadataOnly does not call
+	if workflowTemplate == nil && c.WorkflowTemplateSelfHeal != SelfHealOff && (opts == nil || !opts.MetadataOnly) {
+		workflowTemplate, err = c.selfHealWorkflowTemplate(namespace, uid, version)
+		if err != nil {
+			return nil, err
+		}
 	}
 	if workflowTemplate == nil {
 		return nil, util.NewUserError(codes.NotFound, "Workflow template not found.")
 	}
 
+	if opts != nil && opts.IncludeParameters {
+		if workflowTemplate.Manifest == "" {
+			workflowTemplate.Parameters, err = c.GetWorkflowTemplateParameters(namespace, uid, workflowTemplate.Version)
+			if err != nil {
+				return nil, err
+			}
+		} else {
+			workflowTemplate.Parameters, err = ParseParametersFromManifest([]byte(workflowTemplate.Manifest))
+			if err != nil {
+				return nil, util.NewUserError(codes.InvalidArgument, err.Error())
+			}
+		}
+	}
+
+	if opts != nil && opts.IncludeInjectionReport && workflowTemplate.Manifest != "" {
+		workflowTemplate.InjectionReport, err = c.GetWorkflowTemplateInjectionReport(namespace, workflowTemplate)
+		if err != nil {
+			return nil, util.NewUserErrorWrap(err, "Workflow template")
+		}
+	}
+
+	if opts != nil && opts.MaskSensitiveParameters {
+		if err := maskWorkflowTemplateSensitiveParameters(workflowTemplate); err != nil {
+			return nil, err
+		}
+	}
+
 	return
 }
 
-// GetLatestWorkflowTemplate returns a workflow template with the latest version data.
-func (c *Client) GetLatestWorkflowTemplate(namespace, uid string) (workflowTemplate *WorkflowTemplate, err error) {
-	return c.GetWorkflowTemplate(namespace, uid, 0)
+// maskWorkflowTemplateSensitiveParameters replaces the values of the sensitive parameters of the loaded workflow template,
+// see GetWorkflowTemplateOptions.MaskSensitiveParameters
+func maskWorkflowTemplateSensitiveParameters(workflowTemplate *WorkflowTemplate) error {
+	if workflowTemplate.Manifest == "" {
+		return nil
+	}
+
+	manifest, masked, err := MaskSensitiveParameters([]byte(workflowTemplate.Manifest))
+	if err != nil {
+		return util.NewUserError(codes.InvalidArgument, err.Error())
+	}
+	workflowTemplate.Manifest = string(manifest)
+
+	maskedNames := make(map[string]bool)
+	for _, name := range masked {
+		maskedNames[name] = true
+	}
+
+	for i := range workflowTemplate.Parameters {
+		parameter := &workflowTemplate.Parameters[i]
+		if parameter.Sensitive {
+			maskedNames[parameter.Name] = true
+		}
+		if maskedNames[parameter.Name] && parameter.Value != nil {
+			parameter.Value = ptr.String(SensitiveParameterPlaceholder)
+		}
+	}
+
+	if workflowTemplate.ArgoWorkflowTemplate != nil {
+		parameters := workflowTemplate.ArgoWorkflowTemplate.Spec.Arguments.Parameters
+		for i := range parameters {
+			if maskedNames[parameters[i].Name] && parameters[i].Value != nil {
+				parameters[i].Value = ptr.String(SensitiveParameterPlaceholder)
+			}
+		}
+
+		// each parameter is also written to an annotation, which FormatManifest reads the parameters back from
+		if err := maskSensitiveParameterAnnotations(workflowTemplate.ArgoWorkflowTemplate.Annotations, maskedNames); err != nil {
+			return util.NewUserError(codes.InvalidArgument, err.Error())
+		}
+	}
+
+	return nil
 }
 
-// CountWorkflowTemplatesByName returns the number of WorkflowTemplates given the arguments.
-// If archived is nil, it is not considered.
-func (c *Client) CountWorkflowTemplatesByName(namespace, name string, archived *bool) (count uint64, err error) {
-	sb := c.countWorkflowTemplateSelectBuilder(namespace).
-		Where(sq.Eq{"wt.name": name})
+// maskSensitiveParameterAnnotations replaces the value in the annotations of the parameters with the names.
+func maskSensitiveParameterAnnotations(annotations map[string]string, names map[string]bool) error {
+	for name := range names {
+		annotation, ok := annotations[name]
+		if !ok {
+			continue
+		}
 
-	if archived != nil {
-		sb = sb.Where(sq.Eq{"wt.is_archived": *archived})
+		parameter, err := mapping.NewFromYamlString(annotation)
+		if err != nil {
+			return err
+		}
+		if _, ok := parameter["value"]; !ok {
+			continue
+		}
+		parameter["value"] = SensitiveParameterPlaceholder
+
+		masked, err := parameter.ToYamlBytes()
+		if err != nil {
+			return err
+		}
+		annotations[name] = string(masked)
 	}
 
-	err = sb.RunWith(c.DB).
-		QueryRow().
-		Scan(&count)
+	return nil
+}
 
-	return
+// GetWorkflowTemplateInjectionReport compares what the user authored in the workflow template with what Onepanel
+// injects: namespace parameter defaults, the options of system parameters and the annotations of the argo workflow template.
+// The argo annotations are only reported if the workflow template has its ArgoWorkflowTemplate set.
+// Annotations that Onepanel did not write, e.g. the ones added by kubectl, are not reported.
+func (c *Client) GetWorkflowTemplateInjectionReport(namespace string, workflowTemplate *WorkflowTemplate) (*WorkflowTemplateInjectionReport, error) {
+	report := &WorkflowTemplateInjectionReport{
+		AuthoredParameters:  make([]string, 0),
+		DefaultedParameters: make([]string, 0),
+		SystemParameters:    make([]string, 0),
+		AuthoredAnnotations: make([]string, 0),
+		InjectedAnnotations: make([]string, 0),
+	}
+
+	parameters, err := ParseParametersFromManifest(workflowTemplate.GetManifestBytes())
+	if err != nil {
+		return nil, util.NewUserError(codes.InvalidArgument, err.Error())
+	}
+	parameterNames := make(map[string]bool)
+	for _, parameter := range parameters {
+		parameterNames[parameter.Name] = true
+		report.AuthoredParameters = append(report.AuthoredParameters, parameter.Name)
+		if parameter.Name == nodePoolParameterName {
+			report.SystemParameters = append(report.SystemParameters, parameter.Name)
+		}
+	}
+
+	withDefaults := *workflowTemplate
+	injected, err := c.ApplyNamespaceParameterDefaults(namespace, &withDefaults)
+	if err != nil {
+		return nil, err
+	}
+	report.DefaultedParameters = append(report.DefaultedParameters, injected...)
+
+	if workflowTemplate.ArgoWorkflowTemplate != nil {
+		for key := range workflowTemplate.ArgoWorkflowTemplate.Annotations {
+			if strings.HasPrefix(key, label.AnnotationPrefix) {
+				report.AuthoredAnnotations = append(report.AuthoredAnnotations, strings.TrimPrefix(key, label.AnnotationPrefix))
+				report.InjectedAnnotations = append(report.InjectedAnnotations, key)
+			} else if parameterNames[key] {
+				// each parameter is written to an annotation, see CreateWorkflowTemplateVersion
+				report.InjectedAnnotations = append(report.InjectedAnnotations, key)
+			}
+		}
+		sort.Strings(report.AuthoredAnnotations)
+		sort.Strings(report.InjectedAnnotations)
+	}
+
+	return report, nil
 }
 
-// CountWorkflowTemplateVersions returns the number of versions a non-archived WorkflowTemplate has.
-func (c *Client) CountWorkflowTemplateVersions(namespace, uid string) (count uint64, err error) {
-	err = sb.Select("COUNT(*)").
-		From("workflow_templates wt").
-		Join("workflow_template_versions wtv ON wtv.workflow_template_id = wt.id").
+// GetWorkflowTemplateByUIDOrName returns a WorkflowTemplate, see GetWorkflowTemplate, where identifier is either the uid or the name.
+// The uid is tried first. If there is no match, the non-archived workflow template with the name is used.
+// A FailedPrecondition error is returned if more than one workflow template has the name.
+func (c *Client) GetWorkflowTemplateByUIDOrName(namespace, identifier string, version int64) (workflowTemplate *WorkflowTemplate, err error) {
+	workflowTemplate, err = c.getWorkflowTemplate(namespace, identifier, version)
+	if err != nil {
+		log.WithFields(log.Fields{
+			"Namespace":  namespace,
+			"Identifier": identifier,
+			"Error":      err.Error(),
+		}).Error("Get Workflow Template failed.")
+		return nil, argoWorkflowTemplateUserError(err, "Unknown error.")
+	}
+	if workflowTemplate != nil {
+		return
+	}
+
+	uids := make([]string, 0)
+	query := sb.Select("uid").
+		From("workflow_templates").
+		Where(sq.Eq{
+			"namespace":   namespace,
+			"name":        identifier,
+			"is_archived": false,
+		})
+	if err = c.DB.Selectx(&uids, query); err != nil {
+		log.WithFields(log.Fields{
+			"Namespace":  namespace,
+			"Identifier": identifier,
+			"Error":      err.Error(),
+		}).Error("Get Workflow Template uids by name failed.")
+		return nil, util.NewUserError(codes.Unknown, "Unknown error.")
+	}
+
+	if len(uids) == 0 {
+		return nil, util.NewUserError(codes.NotFound, "Workflow template not found.")
+	}
+	if len(uids) > 1 {
+		return nil, util.NewUserError(codes.FailedPrecondition, fmt.Sprintf("More than one workflow template is named '%v', use the uid instead.", identifier))
+	}
+
+	return c.GetWorkflowTemplate(namespace, uids[0], version)
+}
+
+// GetLatestWorkflowTemplate returns a workflow template with the latest version data.
+func (c *Client) GetLatestWorkflowTemplate(namespace, uid string) (workflowTemplate *WorkflowTemplate, err error) {
+	return c.GetWorkflowTemplate(namespace, uid, 0)
+}
+
+// GetPreviousWorkflowTemplateVersion returns the workflow template at the greatest version that is less than version.
+// A NotFound error is returned if version is the earliest version of the workflow template.
+func (c *Client) GetPreviousWorkflowTemplateVersion(namespace, uid string, version int64) (*WorkflowTemplate, error) {
+	query := sb.Select("COALESCE(MAX(wtv.version), 0)").
+		From("workflow_template_versions wtv").
+		Join("workflow_templates wt ON wt.id = wtv.workflow_template_id").
 		Where(sq.Eq{
 			"wt.namespace":   namespace,
 			"wt.uid":         uid,
 			"wt.is_archived": false,
 		}).
-		RunWith(c.DB).
-		QueryRow().
-		Scan(&count)
-
-	return
-}
+		Where(sq.Lt{"wtv.version": version})
 
-// ListWorkflowTemplateVersions returns all the WorkflowTemplates for a given namespace and uid.
-func (c *Client) ListWorkflowTemplateVersions(namespace, uid string) (workflowTemplateVersions []*WorkflowTemplate, err error) {
-	workflowTemplateVersions, err = c.listWorkflowTemplateVersions(namespace, uid)
-	if err != nil {
+	previousVersion := int64(0)
+	if err := c.DB.Getx(&previousVersion, query); err != nil {
 		log.WithFields(log.Fields{
 			"Namespace": namespace,
 			"UID":       uid,
+			"Version":   version,
 			"Error":     err.Error(),
-		}).Error("Workflow template versions not found.")
-		return nil, util.NewUserError(codes.NotFound, "Workflow template versions not found.")
+		}).Error("Get previous Workflow Template Version failed.")
+		return nil, util.NewUserError(codes.Unknown, "Unknown error.")
+	}
+	if previousVersion == 0 {
+		return nil, util.NewUserError(codes.NotFound, "Previous workflow template version not found.")
 	}
 
-	return
+	return c.GetWorkflowTemplate(namespace, uid, previousVersion)
 }
 
-// ListWorkflowTemplateVersionsModels returns all the WorkflowTemplateVersions from the database.
-// This function is a work-around for ListWorkflowTemplateVersions. Once that function is refactored,
-// this function should no longer be necessary and removed.
-// See: https://github.com/onepanelio/core/issues/436
-func (c *Client) ListWorkflowTemplateVersionsModels(namespace, uid string) (workflowTemplateVersions []*WorkflowTemplateVersion, err error) {
-	workflowTemplateVersions, err = c.selectWorkflowTemplateVersionsDB(namespace, uid)
-	if err != nil {
+// getWorkflowTemplateVersionByUID loads a version of a non-archived workflow template, returning user errors.
+// If version is 0, the latest version is used.
+func (c *Client) getWorkflowTemplateVersionByUID(namespace, uid string, version int64) (*WorkflowTemplateVersion, error) {
+	sb := c.workflowTemplatesVersionSelectBuilder(namespace).
+		Where(sq.Eq{
+			"wt.uid":         uid,
+			"wt.is_archived": false,
+		})
+
+	if version <= 0 {
+		sb = sb.Where(sq.Eq{"wtv.is_latest": true})
+	} else {
+		sb = sb.Where(sq.Eq{"wtv.version": version})
+	}
+
+	workflowTemplateVersion := &WorkflowTemplateVersion{}
+	if err := c.DB.Getx(workflowTemplateVersion, sb); err != nil {
+		if err == sql.ErrNoRows {
+			return nil, util.NewUserError(codes.NotFound, "Workflow template version not found.")
+		}
+
 		log.WithFields(log.Fields{
 			"Namespace": namespace,
 			"UID":       uid,
+			"Version":   version,
 			"Error":     err.Error(),
-		}).Error("Workflow template versions not found.")
-		return nil, util.NewUserError(codes.NotFound, "Workflow template versions not found.")
+		}).Error("Get Workflow Template Version failed.")
+		return nil, util.NewUserError(codes.Unknown, "Unknown error.")
 	}
-	return
+
+	return workflowTemplateVersion, nil
 }
 
-// ListWorkflowTemplateVersionsAll returns all WorkflowTemplateVersions with no filtering.
-func (c *Client) ListWorkflowTemplateVersionsAll(paginator *pagination.PaginationRequest) (workflowTemplateVersions []*WorkflowTemplateVersion, err error) {
-	workflowTemplateVersions, err = c.selectAllWorkflowTemplateVersionsDB(paginator)
+// GetWorkflowTemplateParameters loads the manifest of a workflow template version and returns the parameters parsed from it.
+// If version is 0, the latest version is used.
+func (c *Client) GetWorkflowTemplateParameters(namespace, uid string, version int64) (parameters []Parameter, err error) {
+	workflowTemplateVersion, err := c.getWorkflowTemplateVersionByUID(namespace, uid, version)
 	if err != nil {
-		log.WithFields(log.Fields{
-			"Error": err.Error(),
-		}).Error("Workflow template versions not found.")
-		return nil, util.NewUserError(codes.NotFound, "Workflow template versions not found.")
+		return nil, err
 	}
+
+	parameters, err = ParseParametersFromManifest([]byte(workflowTemplateVersion.Manifest))
+	if err != nil {
+		return nil, util.NewUserError(codes.InvalidArgument, err.Error())
+	}
+
 	return
 }
 
-// ListAllWorkflowTemplates lists all of the workflow templates, including archived and system specific
-func (c *Client) ListAllWorkflowTemplates(namespace string, request *request.Request) (workflowTemplateVersions []*WorkflowTemplate, err error) {
-	workflowTemplateVersions, err = c.selectAllWorkflowTemplatesDB(namespace, request)
+// GetParameterSchema returns the parameters of the workflow template version in their groups, see GroupParameters.
+// Version 0 is the latest version.
+func (c *Client) GetParameterSchema(namespace, uid string, version int64) ([]ParameterGroup, error) {
+	parameters, err := c.GetWorkflowTemplateParameters(namespace, uid, version)
 	if err != nil {
-		log.WithFields(log.Fields{
-			"Namespace": namespace,
-			"Error":     err.Error(),
-		}).Error("Workflow templates not found.")
-		return nil, util.NewUserError(codes.NotFound, "Workflow templates not found.")
+		return nil, err
 	}
 
-	err = c.appendExtraWorkflowTemplateData(namespace, workflowTemplateVersions)
+	groups, err := GroupParameters(parameters)
+	if err != nil {
+		return nil, util.NewUserError(codes.InvalidArgument, err.Error())
+	}
 
-	return
+	return groups, nil
 }
 
-// ListWorkflowTemplates returns all WorkflowTemplates where the results
-// are filtered by is_archived and is_System is false.
-func (c *Client) ListWorkflowTemplates(namespace string, request *request.Request) (workflowTemplateVersions []*WorkflowTemplate, err error) {
-	workflowTemplateVersions, err = c.selectWorkflowTemplatesDB(namespace, request)
+// manifestStreamChunkSize is the number of characters of a manifest in each row GetWorkflowTemplateVersionManifestStream reads
+const manifestStreamChunkSize = 64 * 1024
+
+// GetWorkflowTemplateVersionManifest returns the manifest of a workflow template version.
+// If version is 0, the latest version is used.
+func (c *Client) GetWorkflowTemplateVersionManifest(namespace, uid string, version int64) ([]byte, error) {
+	workflowTemplateVersion, err := c.getWorkflowTemplateVersionByUID(namespace, uid, version)
 	if err != nil {
-		log.WithFields(log.Fields{
-			"Namespace": namespace,
-			"Error":     err.Error(),
-		}).Error("Workflow templates not found.")
-		return nil, util.NewUserError(codes.NotFound, "Workflow templates not found.")
+		return nil, err
+	}
+
+	return []byte(workflowTemplateVersion.Manifest), nil
+}
+
+// GetAllWorkflowTemplateVersionManifests returns the manifest of every version of the non-archived workflow template, keyed by version.
+// The manifests are loaded from the database in one query, argo is not called.
+func (c *Client) GetAllWorkflowTemplateVersionManifests(namespace, uid string) (map[int64][]byte, error) {
+	rows := make([]struct {
+		Version  int64
+		Manifest string
+	}, 0)
+
+	query := sb.Select("wtv.version", "wtv.manifest").
+		From("workflow_template_versions wtv").
+		Join("workflow_templates wt ON wt.id = wtv.workflow_template_id").
+		Where(sq.Eq{
+			"wt.namespace":   namespace,
+			"wt.uid":         uid,
+			"wt.is_archived": false,
+		})
+	if err := c.DB.Selectx(&rows, query); err != nil {
+		return nil, util.NewUserErrorWrap(err, "Workflow template")
+	}
+
+	// every workflow template has at least one version
+	if len(rows) == 0 {
+		return nil, c.missingWorkflowTemplateUserError(namespace, uid)
+	}
+
+	manifests := make(map[int64][]byte, len(rows))
+	for _, row := range rows {
+		manifests[row.Version] = []byte(row.Manifest)
+	}
+
+	return manifests, nil
+}
+
+// GetWorkflowTemplateVersionManifestStream writes the manifest of a workflow template version to w.
+// If version is 0, the latest version is used.
+// The manifest is loaded with a single query that returns it in chunks, one per row, and each chunk is written as it is read,
+// so large manifests are never held in memory as a whole.
+func (c *Client) GetWorkflowTemplateVersionManifestStream(namespace, uid string, version int64, w io.Writer) error {
+	// substring is 1 indexed. The left join keeps a row for an empty manifest, so a missing version has no rows.
+	query := sb.Select().
+		Column(sq.Expr("substring(wtv.manifest FROM chunks.start FOR ?)", manifestStreamChunkSize)).
+		From("workflow_template_versions wtv").
+		Join("workflow_templates wt ON wt.id = wtv.workflow_template_id").
+		JoinClause("LEFT JOIN LATERAL generate_series(1, length(wtv.manifest), ?) AS chunks(start) ON true", manifestStreamChunkSize).
+		Where(sq.Eq{
+			"wt.namespace":   namespace,
+			"wt.uid":         uid,
+			"wt.is_archived": false,
+		}).
+		OrderBy("chunks.start")
+	if version <= 0 {
+		query = query.Where(sq.Eq{"wtv.is_latest": true})
+	} else {
+		query = query.Where(sq.Eq{"wtv.version": version})
+	}
+
+	rows, err := c.DB.Rowsx(query)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	found := false
+	for rows.Next() {
+		found = true
+
+		chunk := sql.NullString{}
+		if err := rows.Scan(&chunk); err != nil {
+			return err
+		}
+
+		if _, err := io.WriteString(w, chunk.String); err != nil {
+			return err
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	if !found {
+		return util.NewUserError(codes.NotFound, "Workflow template version not found.")
+	}
+
+	return nil
+}
+
+// ValidateRequiredParameters returns an InvalidArgument error if a required parameter of the workflow template version
+// has neither a default value nor a value in supplied. If version is 0, the latest version is used.
+func (c *Client) ValidateRequiredParameters(namespace, uid string, version int64, supplied []Parameter) error {
+	parameters, err := c.GetWorkflowTemplateParameters(namespace, uid, version)
+	if err != nil {
+		return err
+	}
+
+	return requiredParametersUserError(parameters, supplied)
+}
+
+// requiredParametersUserError returns an InvalidArgument error listing the missing required parameters, if any.
+func requiredParametersUserError(parameters []Parameter, supplied []Parameter) error {
+	missing := MissingRequiredParameters(parameters, supplied)
+	if len(missing) == 0 {
+		return nil
+	}
+
+	return util.NewUserError(codes.InvalidArgument, fmt.Sprintf("Missing values for required parameters: %v", strings.Join(missing, ", ")))
+}
+
+// DiffWorkflowTemplateVersions returns a unified diff of the manifests of two versions of a workflow template.
+// A version of 0 means the latest version. An empty string is returned if the manifests are the same.
+func (c *Client) DiffWorkflowTemplateVersions(namespace, uid string, fromVersion, toVersion int64) (string, error) {
+	from, err := c.getWorkflowTemplateVersionByUID(namespace, uid, fromVersion)
+	if err != nil {
+		return "", err
+	}
+
+	to, err := c.getWorkflowTemplateVersionByUID(namespace, uid, toVersion)
+	if err != nil {
+		return "", err
+	}
+
+	diff, err := difflib.GetUnifiedDiffString(difflib.UnifiedDiff{
+		A:        difflib.SplitLines(from.Manifest),
+		B:        difflib.SplitLines(to.Manifest),
+		FromFile: fmt.Sprintf("%v-v%v", uid, from.Version),
+		ToFile:   fmt.Sprintf("%v-v%v", uid, to.Version),
+		Context:  3,
+	})
+	if err != nil {
+		return "", err
+	}
+
+	return diff, nil
+}
+
+// resolvedWorkflowTemplateVersion returns the version as a WorkflowTemplate whose manifest has the fragments and base
+// the version was created with, see WorkflowTemplateVersion.ResolvedManifest.
+// Versions created before resolved manifests were stored are resolved with the current fragments and base.
+func (c *Client) resolvedWorkflowTemplateVersion(namespace, uid string, workflowTemplateVersion *WorkflowTemplateVersion) (*WorkflowTemplate, error) {
+	if workflowTemplateVersion.ResolvedManifest != "" {
+		return &WorkflowTemplate{
+			UID:       uid,
+			Version:   workflowTemplateVersion.Version,
+			Manifest:  workflowTemplateVersion.ResolvedManifest,
+			Fragments: make(map[string]string),
+		}, nil
+	}
+
+	baseTemplateUID, err := c.getWorkflowTemplateBaseTemplateUID(namespace, uid)
+	if err != nil {
+		return nil, err
+	}
+
+	workflowTemplate := &WorkflowTemplate{
+		UID:             uid,
+		Version:         workflowTemplateVersion.Version,
+		Manifest:        workflowTemplateVersion.Manifest,
+		BaseTemplateUID: baseTemplateUID,
+	}
+	if err := c.loadWorkflowTemplateFragments(namespace, workflowTemplate); err != nil {
+		return nil, err
+	}
+	if err := c.loadWorkflowTemplateBase(namespace, workflowTemplate); err != nil {
+		return nil, err
+	}
+
+	return workflowTemplate, nil
+}
+
+// WorkflowTemplateMatchesArgo compares the spec parsed from the stored manifest of a workflow template version with the
+// spec of its argo workflow template, to detect changes made to argo directly. If version is 0, the latest version is used.
+// Both specs are normalized by marshaling them to YAML. If they do not match, a unified diff from the stored spec
+// to the argo spec is returned. Fragments and base templates are resolved as they were when the version was created,
+// so changing them does not make older versions differ from argo.
+func (c *Client) WorkflowTemplateMatchesArgo(namespace, uid string, version int64) (matches bool, diff string, err error) {
+	workflowTemplateVersion, err := c.getWorkflowTemplateVersionByUID(namespace, uid, version)
+	if err != nil {
+		return false, "", err
+	}
+
+	stored, err := c.resolvedWorkflowTemplateVersion(namespace, uid, workflowTemplateVersion)
+	if err != nil {
+		return false, "", err
+	}
+
+	storedArgoWft, err := parseWorkflowTemplateSpec(stored)
+	if err != nil {
+		return false, "", util.NewUserError(codes.InvalidArgument, err.Error())
+	}
+
+	argoWft, err := c.getArgoWorkflowTemplate(namespace, uid, strconv.FormatInt(workflowTemplateVersion.Version, 10))
+	if err != nil {
+		return false, "", argoWorkflowTemplateUserError(err, "Unable to get argo workflow template.")
+	}
+
+	storedSpec, err := yaml.Marshal(storedArgoWft.Spec)
+	if err != nil {
+		return false, "", err
+	}
+
+	argoSpec, err := yaml.Marshal(argoWft.Spec)
+	if err != nil {
+		return false, "", err
+	}
+
+	if string(storedSpec) == string(argoSpec) {
+		return true, "", nil
+	}
+
+	diff, err = difflib.GetUnifiedDiffString(difflib.UnifiedDiff{
+		A:        difflib.SplitLines(string(storedSpec)),
+		B:        difflib.SplitLines(string(argoSpec)),
+		FromFile: fmt.Sprintf("%v-v%v (stored)", uid, workflowTemplateVersion.Version),
+		ToFile:   argoWft.Name,
+		Context:  3,
+	})
+	if err != nil {
+		return false, "", err
+	}
+
+	return false, diff, nil
+}
+
+// DiffWorkflowTemplateVersionFromLatest returns a unified diff from the manifest of the version to the latest manifest.
+func (c *Client) DiffWorkflowTemplateVersionFromLatest(namespace, uid string, version int64) (string, error) {
+	return c.DiffWorkflowTemplateVersions(namespace, uid, version, 0)
+}
+
+// CountWorkflowTemplatesByName returns the number of WorkflowTemplates given the arguments.
+// If archived is nil, it is not considered.
+func (c *Client) CountWorkflowTemplatesByName(namespace, name string, archived *bool) (count uint64, err error) {
+	sb := c.countWorkflowTemplateSelectBuilder(namespace).
+		Where(sq.Eq{"wt.name": name})
+
+	if archived != nil {
+		sb = sb.Where(sq.Eq{"wt.is_archived": *archived})
+	}
+
+	err = sb.RunWith(c.DB).
+		QueryRow().
+		Scan(&count)
+
+	return
+}
+
+// CountWorkflowTemplateVersions returns the number of versions a non-archived WorkflowTemplate has.
+func (c *Client) CountWorkflowTemplateVersions(namespace, uid string) (count uint64, err error) {
+	err = sb.Select("COUNT(*)").
+		From("workflow_templates wt").
+		Join("workflow_template_versions wtv ON wtv.workflow_template_id = wt.id").
+		Where(sq.Eq{
+			"wt.namespace":   namespace,
+			"wt.uid":         uid,
+			"wt.is_archived": false,
+		}).
+		RunWith(c.DB).
+		QueryRow().
+		Scan(&count)
+
+	return
+}
+
+// WorkflowTemplateVersionExists returns true if the non-archived workflow template has the given version.
+// Neither the manifest nor the argo resources are loaded.
+func (c *Client) WorkflowTemplateVersionExists(namespace, uid string, version int64) (exists bool, err error) {
+	err = sb.Select("1").
+		From("workflow_template_versions wtv").
+		Join("workflow_templates wt ON wt.id = wtv.workflow_template_id").
+		Where(sq.Eq{
+			"wt.namespace":   namespace,
+			"wt.uid":         uid,
+			"wt.is_archived": false,
+			"wtv.version":    version,
+		}).
+		Prefix("SELECT EXISTS (").
+		Suffix(")").
+		RunWith(c.DB).
+		QueryRow().
+		Scan(&exists)
+
+	return
+}
+
+// ListWorkflowTemplateVersionIndex returns the uid and latest version of every non-archived workflow template in the namespace.
+// Manifests are not loaded.
+func (c *Client) ListWorkflowTemplateVersionIndex(namespace string) (index []*WorkflowTemplateVersionIndex, err error) {
+	index = make([]*WorkflowTemplateVersionIndex, 0)
+
+	sb := sb.Select("wt.uid", "wtv.version").
+		From("workflow_templates wt").
+		Join("workflow_template_versions wtv ON wtv.workflow_template_id = wt.id").
+		Where(sq.Eq{
+			"wt.namespace":   namespace,
+			"wt.is_archived": false,
+			"wtv.is_latest":  true,
+		}).
+		OrderBy("wt.uid")
+
+	err = c.DB.Selectx(&index, sb)
+
+	return
+}
+
+// ListWorkflowTemplateVersions returns all the WorkflowTemplates for a given namespace and uid.
+func (c *Client) ListWorkflowTemplateVersions(namespace, uid string) (workflowTemplateVersions []*WorkflowTemplate, err error) {
+	workflowTemplateVersions, err = c.listWorkflowTemplateVersions(namespace, uid)
+	if err != nil {
+		log.WithFields(log.Fields{
+			"Namespace": namespace,
+			"UID":       uid,
+			"Error":     err.Error(),
+		}).Error("Workflow template versions not found.")
+		return nil, util.NewUserError(codes.NotFound, "Workflow template versions not found.")
+	}
+
+	return
+}
+
+// ListWorkflowTemplateVersionsModels returns all the WorkflowTemplateVersions from the database.
+// This function is a work-around for ListWorkflowTemplateVersions. Once that function is refactored,
+// this function should no longer be necessary and removed.
+// See: https://github.com/onepanelio/core/issues/436
+func (c *Client) ListWorkflowTemplateVersionsModels(namespace, uid string) (workflowTemplateVersions []*WorkflowTemplateVersion, err error) {
+	workflowTemplateVersions, err = c.selectWorkflowTemplateVersionsDB(namespace, uid)
+	if err != nil {
+		log.WithFields(log.Fields{
+			"Namespace": namespace,
+			"UID":       uid,
+			"Error":     err.Error(),
+		}).Error("Workflow template versions not found.")
+		return nil, util.NewUserError(codes.NotFound, "Workflow template versions not found.")
+	}
+	return
+}
+
+// ListWorkflowTemplateVersionsAll returns all WorkflowTemplateVersions with no filtering.
+func (c *Client) ListWorkflowTemplateVersionsAll(paginator *pagination.PaginationRequest) (workflowTemplateVersions []*WorkflowTemplateVersion, err error) {
+	workflowTemplateVersions, err = c.selectAllWorkflowTemplateVersionsDB(paginator)
+	if err != nil {
+		log.WithFields(log.Fields{
+			"Error": err.Error(),
+		}).Error("Workflow template versions not found.")
+		return nil, util.NewUserError(codes.NotFound, "Workflow template versions not found.")
+	}
+	return
+}
+
+// ListAllWorkflowTemplates lists all of the workflow templates, including archived and system specific
+func (c *Client) ListAllWorkflowTemplates(namespace string, request *request.Request) (workflowTemplateVersions []*WorkflowTemplate, err error) {
+	workflowTemplateVersions, err = c.selectAllWorkflowTemplatesDB(namespace, request)
+	if err != nil {
+		log.WithFields(log.Fields{
+			"Namespace": namespace,
+			"Error":     err.Error(),
+		}).Error("Workflow templates not found.")
+		return nil, util.NewUserError(codes.NotFound, "Workflow templates not found.")
+	}
+
+	err = c.appendExtraWorkflowTemplateData(namespace, workflowTemplateVersions)
+
+	return
+}
+
+// AdoptArgoWorkflowTemplate makes an argo workflow template that was created outside of onepanel managed by onepanel.
+// A workflow template named onepanelName is created from the argo workflow template's spec and tag labels.
+//
+// If the argo workflow template is already named and labeled like a onepanel workflow template version, only the
+// database records are created and the argo workflow template is kept. Otherwise, a new argo workflow template is created
+// and the original one is left as is.
+func (c *Client) AdoptArgoWorkflowTemplate(namespace, argoName, onepanelName string) (*WorkflowTemplate, error) {
+	// the uid is only known once the workflow template is adopted
+	defer c.InvalidateWorkflowTemplateCache(namespace, "")
+
+	argoWft, err := c.ArgoprojV1alpha1().WorkflowTemplates(namespace).Get(argoName, v1.GetOptions{})
+	if err != nil {
+		log.WithFields(log.Fields{
+			"Namespace": namespace,
+			"Name":      argoName,
+			"Error":     err.Error(),
+		}).Error("Argo Workflow Template not found.")
+		return nil, util.NewUserError(codes.NotFound, "Argo workflow template not found.")
+	}
+
+	manifest, err := yaml.Marshal(argoWft.Spec)
+	if err != nil {
+		return nil, err
+	}
+
+	annotations := label.FilterByPrefix(label.AnnotationPrefix, argoWft.Annotations)
+	workflowTemplate := &WorkflowTemplate{
+		Name:        onepanelName,
+		Manifest:    string(manifest),
+		Labels:      tagLabelsFromArgo(argoWft.Labels, c.NamespaceDefaultLabels[namespace]),
+		Annotations: label.RemovePrefix(label.AnnotationPrefix, annotations),
+	}
+	if err := workflowTemplate.GenerateUID(onepanelName); err != nil {
+		return nil, util.NewUserError(codes.InvalidArgument, "Template name must be 30 characters or less")
+	}
+
+	version := argoWorkflowTemplateVersion(argoWft)
+	isLabeled := argoWft.Labels[label.WorkflowTemplateUid] == workflowTemplate.UID &&
+		version > 0 &&
+		argoWft.Name == fmt.Sprintf("%v-v%v", workflowTemplate.UID, version)
+	if !isLabeled {
+		workflowTemplate.UID = ""
+		return c.CreateWorkflowTemplate(namespace, workflowTemplate)
+	}
+
+	if err := c.prevalidateWorkflowTemplate(namespace, workflowTemplate); err != nil {
+		return nil, err
+	}
+
+	tx, err := c.DB.Begin()
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	err = sb.Insert("workflow_templates").
+		SetMap(sq.Eq{
+			"uid":        workflowTemplate.UID,
+			"name":       workflowTemplate.Name,
+			"namespace":  namespace,
+			"is_system":  workflowTemplate.IsSystem,
+			"is_public":  workflowTemplate.IsPublic,
+			"labels":     workflowTemplate.Labels,
+			"created_by": c.Actor,
+			"category":   workflowTemplate.Category,
+		}).
+		Suffix("RETURNING id").
+		RunWith(tx).
+		QueryRow().
+		Scan(&workflowTemplate.ID)
+	if err != nil {
+		return nil, util.NewUserErrorWrap(err, "Workflow template")
+	}
+
+	params, err := ParseParametersFromManifest([]byte(workflowTemplate.Manifest))
+	if err != nil {
+		return nil, util.NewUserError(codes.InvalidArgument, err.Error())
+	}
+
+	workflowTemplateVersion := &WorkflowTemplateVersion{
+		WorkflowTemplate: workflowTemplate,
+		Version:          version,
+		Manifest:         workflowTemplate.Manifest,
+		Labels:           workflowTemplate.Labels,
+		ResolvedManifest: workflowTemplate.Manifest,
+	}
+	if err := createWorkflowTemplateVersionDB(tx, workflowTemplateVersion, params); err != nil {
+		return nil, err
+	}
+
+	if err := c.insertWorkflowTemplateAuditEntry(tx, workflowTemplate.ID, AuditActionCreate, &version); err != nil {
+		return nil, err
+	}
+
+	var updated []v1alpha1.WorkflowTemplate
+	if argoWft.Labels[label.VersionLatest] != "true" {
+		original := argoWft.DeepCopy()
+		argoWft.Labels[label.VersionLatest] = "true"
+		argoWft, err = c.ArgoprojV1alpha1().WorkflowTemplates(namespace).Update(argoWft)
+		if err != nil {
+			return nil, err
+		}
+		updated = append(updated, *original)
+	}
+
+	if err := tx.Commit(); err != nil {
+		if errRestore := c.restoreArgoWorkflowTemplateLabels(namespace, updated); errRestore != nil {
+			err = fmt.Errorf("%w; %s", err, errRestore)
+		}
+		return nil, err
+	}
+
+	workflowTemplate.WorkflowTemplateVersionID = workflowTemplateVersion.ID
+	workflowTemplate.Version = version
+	workflowTemplate.ArgoWorkflowTemplate = argoWft
+
+	return workflowTemplate, nil
+}
+
+// selfHealWorkflowTemplate recovers a workflow template that has argo workflow templates, but no database record,
+// as set by Client.WorkflowTemplateSelfHeal. If version is 0, the latest version is returned.
+// (nil, nil) is returned if the workflow template can not be recovered, like when it has a database record that is archived.
+//
+// The name of the workflow template is not stored in argo, so the recovered workflow template is named after its uid.
+func (c *Client) selfHealWorkflowTemplate(namespace, uid string, version int64) (*WorkflowTemplate, error) {
+	count := 0
+	query := sb.Select("COUNT(*)").
+		From("workflow_templates").
+		Where(sq.Eq{
+			"namespace": namespace,
+			"uid":       uid,
+		})
+	if err := c.DB.Getx(&count, query); err != nil {
+		return nil, util.NewUserErrorWrap(err, "Workflow template")
+	}
+	if count > 0 {
+		return nil, nil
+	}
+
+	argoWorkflowTemplates, err := c.listArgoWorkflowTemplates(namespace, uid)
+	if err != nil {
+		return nil, argoWorkflowTemplateUserError(err, "Unable to get workflow template.")
+	}
+	if len(*argoWorkflowTemplates) == 0 || argoWorkflowTemplateVersion(&(*argoWorkflowTemplates)[0]) <= 0 {
+		return nil, nil
+	}
+
+	log.WithFields(log.Fields{
+		"Namespace": namespace,
+		"UID":       uid,
+		"Mode":      c.WorkflowTemplateSelfHeal,
+	}).Warn("Workflow template is missing from the database, recovering it from argo.")
+
+	if c.WorkflowTemplateSelfHeal == SelfHealReinsert {
+		if err := c.reinsertWorkflowTemplateDB(namespace, uid, *argoWorkflowTemplates); err != nil {
+			if util.IsUniqueViolation(err) {
+				// a concurrent load reinserted it first
+				return c.getWorkflowTemplate(namespace, uid, version)
+			}
+			log.WithFields(log.Fields{
+				"Namespace": namespace,
+				"UID":       uid,
+				"Error":     err.Error(),
+			}).Error("Could not reinsert workflow template.")
+			return nil, util.NewUserErrorWrap(err, "Workflow template")
+		}
+
+		return c.getWorkflowTemplate(namespace, uid, version)
+	}
+
+	// the versions are sorted newest first
+	var argoWft *v1alpha1.WorkflowTemplate
+	for i := range *argoWorkflowTemplates {
+		candidate := &(*argoWorkflowTemplates)[i]
+		if version <= 0 || argoWorkflowTemplateVersion(candidate) == version {
+			argoWft = candidate
+			break
+		}
+	}
+	if argoWft == nil {
+		return nil, nil
+	}
+
+	workflowTemplate, err := workflowTemplateFromArgo(uid, argoWft, c.NamespaceDefaultLabels[namespace])
+	if err != nil {
+		return nil, err
+	}
+	workflowTemplate.LatestVersion = argoWorkflowTemplateVersion(&(*argoWorkflowTemplates)[0])
+	workflowTemplate.IsLatest = workflowTemplate.Version == workflowTemplate.LatestVersion
+	workflowTemplate.RequestedVersion = version
+	workflowTemplate.ResolvedLatest = version <= 0
+
+	return workflowTemplate, nil
+}
+
+// workflowTemplateFromArgo builds a workflow template version from its argo workflow template, see selfHealWorkflowTemplate.
+// Argo drops the onepanel parameter fields, like displayName and options, so the parameters are rebuilt
+// from the annotations they were stored in when the version was created.
+// defaultLabels are the NamespaceDefaultLabels of the namespace, they are not labels of the workflow template.
+func workflowTemplateFromArgo(uid string, argoWft *v1alpha1.WorkflowTemplate, defaultLabels map[string]string) (*WorkflowTemplate, error) {
+	specManifest, err := yaml.Marshal(argoWft.Spec)
+	if err != nil {
+		return nil, err
+	}
+
+	spec := make(map[string]interface{})
+	if err := yaml.Unmarshal(specManifest, &spec); err != nil {
+		return nil, err
+	}
+	if arguments, ok := spec["arguments"].(map[string]interface{}); ok {
+		if parameters, ok := arguments["parameters"].([]interface{}); ok {
+			for i, parameter := range parameters {
+				parameterMap, ok := parameter.(map[string]interface{})
+				if !ok {
+					continue
+				}
+				name, _ := parameterMap["name"].(string)
+				if annotated := parameterFromArgoAnnotations(argoWft, name); annotated != nil {
+					parameters[i] = annotated
+				}
+			}
+		}
+	}
+
+	manifest, err := yaml.Marshal(spec)
+	if err != nil {
+		return nil, err
+	}
+
+	workflowTemplate := &WorkflowTemplate{
+		UID:                  uid,
+		Name:                 uid,
+		Manifest:             string(manifest),
+		Version:              argoWorkflowTemplateVersion(argoWft),
+		CreatedAt:            argoWft.CreationTimestamp.UTC(),
+		Labels:               tagLabelsFromArgo(argoWft.Labels, defaultLabels),
+		ArgoWorkflowTemplate: argoWft,
+	}
+	workflowTemplate.SetAnnotationsFromArgoWorkflowTemplate()
+
+	return workflowTemplate, nil
+}
+
+// parameterFromArgoAnnotations returns the parameter with the name as it was declared in the manifest,
+// from the annotation written when the version was created, or nil if there is none.
+func parameterFromArgoAnnotations(argoWft *v1alpha1.WorkflowTemplate, name string) map[string]interface{} {
+	value, ok := argoWft.Annotations[name]
+	if name == "" || !ok {
+		return nil
+	}
+
+	parameter := make(map[string]interface{})
+	if err := yaml.Unmarshal([]byte(value), &parameter); err != nil {
+		return nil
+	}
+	// other annotations may have the same key as a parameter
+	if parameter["name"] != name {
+		return nil
+	}
+	delete(parameter, "order")
+
+	return parameter
+}
+
+// reinsertWorkflowTemplateDB recreates the database records of a workflow template from its argo workflow templates,
+// sorted newest first. Argo workflow templates without a valid version label are skipped.
+func (c *Client) reinsertWorkflowTemplateDB(namespace, uid string, argoWorkflowTemplates []v1alpha1.WorkflowTemplate) error {
+	latest, err := workflowTemplateFromArgo(uid, &argoWorkflowTemplates[0], c.NamespaceDefaultLabels[namespace])
+	if err != nil {
+		return err
+	}
+
+	tx, err := c.DB.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	err = sb.Insert("workflow_templates").
+		SetMap(sq.Eq{
+			"uid":       latest.UID,
+			"name":      latest.Name,
+			"namespace": namespace,
+			"labels":    latest.Labels,
+		}).
+		Suffix("RETURNING id").
+		RunWith(tx).
+		QueryRow().
+		Scan(&latest.ID)
+	if err != nil {
+		return err
+	}
+
+	// versions are inserted oldest first, each one is inserted as the latest
+	for i := len(argoWorkflowTemplates) - 1; i >= 0; i-- {
+		if argoWorkflowTemplateVersion(&argoWorkflowTemplates[i]) <= 0 {
+			continue
+		}
+
+		workflowTemplate, err := workflowTemplateFromArgo(uid, &argoWorkflowTemplates[i], c.NamespaceDefaultLabels[namespace])
+		if err != nil {
+			return err
+		}
+
+		parameters, err := ParseParametersFromManifest([]byte(workflowTemplate.Manifest))
+		if err != nil {
+			return err
+		}
+
+		workflowTemplateVersion := &WorkflowTemplateVersion{
+			WorkflowTemplate: latest,
+			Version:          workflowTemplate.Version,
+			Manifest:         workflowTemplate.Manifest,
+			Labels:           workflowTemplate.Labels,
+			ResolvedManifest: workflowTemplate.Manifest,
+		}
+		if err := createWorkflowTemplateVersionDB(tx, workflowTemplateVersion, parameters); err != nil {
+			return err
+		}
+	}
+
+	_, err = sb.Update("workflow_template_versions").
+		Set("is_latest", false).
+		Where(sq.Eq{
+			"workflow_template_id": latest.ID,
+		}).
+		Where(sq.NotEq{
+			"version": latest.Version,
+		}).
+		RunWith(tx).
+		Exec()
+	if err != nil {
+		return err
+	}
+
+	if err := c.insertWorkflowTemplateAuditEntry(tx, latest.ID, AuditActionCreate, &latest.Version); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// GetWorkflowTemplateStatistics returns the number of workflow templates, versions and executions in the namespace.
+// Executions are counted by the workflow template they were run from.
+// System workflow templates, which belong to workspace templates, are not counted, like they are not listed.
+func (c *Client) GetWorkflowTemplateStatistics(namespace string) (statistics *WorkflowTemplateStatistics, err error) {
+	query := sb.Select(
+		"COUNT(*) FILTER (WHERE NOT wt.is_archived) total_templates",
+		"COALESCE(SUM(v.count) FILTER (WHERE NOT wt.is_archived), 0) total_versions",
+		"COALESCE(SUM(e.count) FILTER (WHERE NOT wt.is_archived), 0) total_executions",
+		"COUNT(*) FILTER (WHERE wt.is_archived) archived_templates",
+		"COALESCE(SUM(v.count) FILTER (WHERE wt.is_archived), 0) archived_versions",
+		"COALESCE(SUM(e.count) FILTER (WHERE wt.is_archived), 0) archived_executions",
+	).
+		From("workflow_templates wt").
+		LeftJoin("(SELECT workflow_template_id, COUNT(*) count FROM workflow_template_versions GROUP BY workflow_template_id) v ON v.workflow_template_id = wt.id").
+		LeftJoin("(SELECT wtv.workflow_template_id, COUNT(*) count FROM workflow_executions we JOIN workflow_template_versions wtv ON wtv.id = we.workflow_template_version_id GROUP BY wtv.workflow_template_id) e ON e.workflow_template_id = wt.id").
+		Where(sq.Eq{
+			"wt.namespace": namespace,
+			"wt.is_system": false,
+		})
+
+	statistics = &WorkflowTemplateStatistics{}
+	if err = c.DB.Getx(statistics, query); err != nil {
+		log.WithFields(log.Fields{
+			"Namespace": namespace,
+			"Error":     err.Error(),
+		}).Error("Get Workflow Template Statistics failed.")
+		return nil, util.NewUserError(codes.Unknown, "Unable to get workflow template statistics.")
+	}
+
+	return
+}
+
+// RenameWorkflowTemplate changes the name of the non-archived workflow template.
+// The uid is kept, so executions, cron workflows and links to the workflow template are not affected.
+// The workflow template label on the argo workflow templates is updated to the uid generated from the new name.
+// If another non-archived workflow template has the name, or would have the same generated uid, an AlreadyExists error is returned.
+func (c *Client) RenameWorkflowTemplate(namespace, uid, newName string) error {
+	defer c.InvalidateWorkflowTemplateCache(namespace, uid)
+
+	slug := &WorkflowTemplate{}
+	if err := slug.GenerateUID(newName); err != nil {
+		return util.NewUserError(codes.InvalidArgument, "Template name must be 30 characters or less")
+	}
+
+	tx, err := c.DB.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	// Renames in the namespace are done one after the other until the transaction ends, so two workflow templates
+	// can not be renamed to the same name at the same time.
+	_, err = sb.Select().
+		Column("pg_advisory_xact_lock(hashtext(?))", "workflow_templates/rename/"+namespace).
+		RunWith(tx).
+		Exec()
+	if err != nil {
+		return err
+	}
+
+	count := 0
+	err = sb.Select("COUNT(*)").
+		From("workflow_templates").
+		Where(sq.Eq{
+			"namespace":   namespace,
+			"is_archived": false,
+		}).
+		Where(sq.NotEq{"uid": uid}).
+		Where(sq.Or{
+			sq.Eq{"name": newName},
+			sq.Eq{"uid": slug.UID},
+		}).
+		RunWith(tx).
+		QueryRow().
+		Scan(&count)
+	if err != nil {
+		return err
+	}
+	if count > 0 {
+		return util.NewUserError(codes.AlreadyExists, fmt.Sprintf("Workflow template '%v' already exists.", newName))
+	}
+
+	result, err := sb.Update("workflow_templates").
+		Set("name", newName).
+		Set("updated_at", time.Now().UTC()).
+		Where(sq.Eq{
+			"namespace":   namespace,
+			"uid":         uid,
+			"is_archived": false,
+		}).
+		RunWith(tx).
+		Exec()
+	if err != nil {
+		return util.NewUserErrorWrap(err, "Workflow template")
+	}
+	if rowsAffected, err := result.RowsAffected(); err != nil {
+		return err
+	} else if rowsAffected == 0 {
+		return util.NewUserError(codes.NotFound, "Workflow template not found.")
+	}
+
+	if err := c.insertWorkflowTemplateAuditEntryByUID(tx, namespace, uid, AuditActionRename); err != nil {
+		return err
+	}
+
+	argoWorkflowTemplates, err := c.listArgoWorkflowTemplates(namespace, uid)
+	if err != nil {
+		return err
+	}
+
+	var updated []v1alpha1.WorkflowTemplate
+	for i := range *argoWorkflowTemplates {
+		argoWft := &(*argoWorkflowTemplates)[i]
+		if argoWft.Labels[label.WorkflowTemplate] == slug.UID {
+			continue
+		}
+
+		original := argoWft.DeepCopy()
+		argoWft.Labels[label.WorkflowTemplate] = slug.UID
+		if _, err := c.ArgoprojV1alpha1().WorkflowTemplates(namespace).Update(argoWft); err != nil {
+			if errRestore := c.restoreArgoWorkflowTemplateLabels(namespace, updated); errRestore != nil {
+				err = fmt.Errorf("%w; %s", err, errRestore)
+			}
+			return err
+		}
+		updated = append(updated, *original)
+	}
+
+	if err := tx.Commit(); err != nil {
+		if errRestore := c.restoreArgoWorkflowTemplateLabels(namespace, updated); errRestore != nil {
+			err = fmt.Errorf("%w; %s", err, errRestore)
+		}
+		return err
+	}
+
+	return nil
+}
+
+// restoreArgoWorkflowTemplateLabels puts back the labels the argo workflow templates had before they were updated.
+// Argo is not part of the database transaction, so this undoes the argo updates when the transaction fails.
+func (c *Client) restoreArgoWorkflowTemplateLabels(namespace string, originals []v1alpha1.WorkflowTemplate) error {
+	for _, original := range originals {
+		argoWft, err := c.ArgoprojV1alpha1().WorkflowTemplates(namespace).Get(original.Name, v1.GetOptions{})
+		if err != nil {
+			return err
+		}
+
+		argoWft.Labels = original.Labels
+		if _, err := c.ArgoprojV1alpha1().WorkflowTemplates(namespace).Update(argoWft); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// SetWorkflowTemplateDeprecated flags the non-archived workflow template as deprecated, or clears the flag.
+// Deprecated templates can still be executed and are listed after the other templates.
+func (c *Client) SetWorkflowTemplateDeprecated(namespace, uid string, deprecated bool) error {
+	defer c.InvalidateWorkflowTemplateCache(namespace, uid)
+
+	result, err := sb.Update("workflow_templates").
+		Set("is_deprecated", deprecated).
+		Set("updated_at", time.Now().UTC()).
+		Where(sq.Eq{
+			"namespace":   namespace,
+			"uid":         uid,
+			"is_archived": false,
+		}).
+		RunWith(c.DB).
+		Exec()
+	if err != nil {
+		return util.NewUserErrorWrap(err, "Workflow template")
+	}
+	if rowsAffected, err := result.RowsAffected(); err != nil {
+		return err
+	} else if rowsAffected == 0 {
+		return c.missingWorkflowTemplateUserError(namespace, uid)
+	}
+
+	return nil
+}
+
+// SetLatestWorkflowTemplateVersion marks an existing version of the non-archived workflow template as the latest one.
+// No version is created, the manifest of the version is used as is. The workflow template's labels are set to the version's labels.
+func (c *Client) SetLatestWorkflowTemplateVersion(namespace, uid string, version int64) error {
+	defer c.InvalidateWorkflowTemplateCache(namespace, uid)
+
+	workflowTemplateDB := &WorkflowTemplate{}
+	wftSb := c.workflowTemplatesSelectBuilder(namespace).
+		Where(sq.Eq{
+			"wt.uid":         uid,
+			"wt.is_archived": false,
+		})
+	if err := c.DB.Getx(workflowTemplateDB, wftSb); err != nil {
+		if err == sql.ErrNoRows {
+			return c.missingWorkflowTemplateUserError(namespace, uid)
+		}
+		return err
+	}
+
+	if version <= 0 {
+		return util.NewUserError(codes.InvalidArgument, "Version must be greater than 0.")
+	}
+
+	workflowTemplateVersion, err := c.getWorkflowTemplateVersionByUID(namespace, uid, version)
+	if err != nil {
+		return err
+	}
+
+	tx, err := c.DB.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	_, err = sb.Update("workflow_template_versions").
+		Set("is_latest", sq.Expr("id = ?", workflowTemplateVersion.ID)).
+		Where(sq.Eq{
+			"workflow_template_id": workflowTemplateDB.ID,
+		}).
+		RunWith(tx).
+		Exec()
+	if err != nil {
+		return err
+	}
+
+	_, err = sb.Update("workflow_templates").
+		Set("labels", workflowTemplateVersion.Labels).
+		Set("updated_at", time.Now().UTC()).
+		Where(sq.Eq{
+			"id": workflowTemplateDB.ID,
+		}).
+		RunWith(tx).
+		Exec()
+	if err != nil {
+		return err
+	}
+
+	argoWorkflowTemplates, err := c.listArgoWorkflowTemplates(namespace, uid)
+	if err != nil {
+		return err
+	}
+
+	foundArgoTemplate := false
+	for i := range *argoWorkflowTemplates {
+		if argoWorkflowTemplateVersion(&(*argoWorkflowTemplates)[i]) == version {
+			foundArgoTemplate = true
+			break
+		}
+	}
+	if !foundArgoTemplate {
+		return util.NewUserError(codes.NotFound, "Workflow template version not found.")
+	}
+
+	var updated []v1alpha1.WorkflowTemplate
+	for i := range *argoWorkflowTemplates {
+		argoWft := &(*argoWorkflowTemplates)[i]
+		isTarget := argoWorkflowTemplateVersion(argoWft) == version
+		_, isLatest := argoWft.Labels[label.VersionLatest]
+		if isTarget == isLatest {
+			continue
+		}
+
+		original := argoWft.DeepCopy()
+		if isTarget {
+			argoWft.Labels[label.VersionLatest] = "true"
+		} else {
+			delete(argoWft.Labels, label.VersionLatest)
+		}
+
+		if _, err := c.ArgoprojV1alpha1().WorkflowTemplates(namespace).Update(argoWft); err != nil {
+			if errRestore := c.restoreArgoWorkflowTemplateLabels(namespace, updated); errRestore != nil {
+				err = fmt.Errorf("%w; %s", err, errRestore)
+			}
+			return err
+		}
+		updated = append(updated, *original)
+	}
+
+	if err := tx.Commit(); err != nil {
+		if errRestore := c.restoreArgoWorkflowTemplateLabels(namespace, updated); errRestore != nil {
+			err = fmt.Errorf("%w; %s", err, errRestore)
+		}
+		return err
+	}
+
+	return nil
+}
+
+// GetWorkflowTemplateByManifestHash returns the version of the workflow template whose manifest has the hash, see ManifestHash.
+// Versions of archived workflow templates with the uid are included. If more than one version matches, the newest is returned.
+func (c *Client) GetWorkflowTemplateByManifestHash(namespace, uid, hash string) (*WorkflowTemplate, error) {
+	query := c.workflowTemplatesVersionSelectBuilder(namespace).
+		Columns(getWorkflowTemplateColumns("wt", "workflow_template")...).
+		Where(sq.Eq{
+			"wt.uid":            uid,
+			"wtv.manifest_hash": hash,
+		}).
+		OrderBy("wtv.version DESC").
+		Limit(1)
+
+	version := &WorkflowTemplateVersion{}
+	if err := c.DB.Getx(version, query); err != nil {
+		if err == sql.ErrNoRows {
+			return nil, util.NewUserError(codes.NotFound, "Workflow template version not found.")
+		}
+
+		log.WithFields(log.Fields{
+			"Namespace": namespace,
+			"UID":       uid,
+			"Error":     err.Error(),
+		}).Error("Get Workflow Template Version failed.")
+		return nil, util.NewUserError(codes.Unknown, "Unknown error.")
+	}
+
+	return version.toWorkflowTemplate(), nil
+}
+
+// GetWorkflowTemplateVersionAsOf returns the version of the non-archived workflow template that was the newest at the time,
+// that is, the newest version created at or before it.
+func (c *Client) GetWorkflowTemplateVersionAsOf(namespace, uid string, t time.Time) (*WorkflowTemplate, error) {
+	query := c.workflowTemplatesVersionSelectBuilder(namespace).
+		Columns(getWorkflowTemplateColumns("wt", "workflow_template")...).
+		Where(sq.Eq{
+			"wt.uid":         uid,
+			"wt.is_archived": false,
+		}).
+		Where(sq.LtOrEq{
+			"wtv.created_at": t.UTC(),
+		}).
+		OrderBy("wtv.created_at DESC", "wtv.version DESC").
+		Limit(1)
+
+	version := &WorkflowTemplateVersion{}
+	if err := c.DB.Getx(version, query); err != nil {
+		if err == sql.ErrNoRows {
+			return nil, util.NewUserError(codes.NotFound, "Workflow template version not found.")
+		}
+
+		log.WithFields(log.Fields{
+			"Namespace": namespace,
+			"UID":       uid,
+			"Time":      t,
+			"Error":     err.Error(),
+		}).Error("Get Workflow Template Version failed.")
+		return nil, util.NewUserError(codes.Unknown, "Unknown error.")
+	}
+
+	return version.toWorkflowTemplate(), nil
+}
+
+// ListPublicWorkflowTemplates returns the non-archived workflow templates that are public, regardless of namespace.
+func (c *Client) ListPublicWorkflowTemplates() (workflowTemplates []*WorkflowTemplate, err error) {
+	workflowTemplates = make([]*WorkflowTemplate, 0)
+
+	sb := sb.Select(getWorkflowTemplateColumns("wt")...).
+		Column("COUNT(wtv.*) versions, MAX(wtv.id) workflow_template_version_id").
+		From("workflow_templates wt").
+		Join("workflow_template_versions wtv ON wtv.workflow_template_id = wt.id").
+		Where(sq.Eq{
+			"wt.is_public":   true,
+			"wt.is_archived": false,
+		}).
+		GroupBy("wt.id").
+		OrderBy("wt.created_at DESC")
+
+	if err = c.DB.Selectx(&workflowTemplates, sb); err != nil {
+		log.WithFields(log.Fields{
+			"Error": err.Error(),
+		}).Error("Unable to list public workflow templates.")
+		return nil, util.NewUserError(codes.Unknown, "Unable to list public workflow templates.")
+	}
+
+	return
+}
+
+// ClonePublicWorkflowTemplate creates a new workflow template named name in namespace using
+// the latest manifest and labels of the public workflow template identified by publicNamespace and uid.
+func (c *Client) ClonePublicWorkflowTemplate(publicNamespace, uid, namespace, name string) (*WorkflowTemplate, error) {
+	workflowTemplate, err := c.getLatestWorkflowTemplate(publicNamespace, uid)
+	if err != nil {
+		log.WithFields(log.Fields{
+			"Namespace": publicNamespace,
+			"UID":       uid,
+			"Error":     err.Error(),
+		}).Error("Get Workflow Template failed.")
+		return nil, argoWorkflowTemplateUserError(err, "Unable to clone workflow template.")
+	}
+	if workflowTemplate == nil || !workflowTemplate.IsPublic {
+		return nil, util.NewUserError(codes.NotFound, "Workflow template not found.")
+	}
+
+	workflowTemplateVersion, err := c.getWorkflowTemplateVersionByUID(publicNamespace, uid, workflowTemplate.Version)
+	if err != nil {
+		return nil, err
+	}
+	manifest, err := c.resolvedWorkflowTemplateVersionManifest(publicNamespace, uid, workflowTemplateVersion)
+	if err != nil {
+		return nil, err
+	}
+
+	workflowTemplateClone := &WorkflowTemplate{
+		Name:     name,
+		Manifest: manifest,
+		Labels:   workflowTemplate.Labels,
+		IsLatest: true,
+	}
+
+	return c.CreateWorkflowTemplate(namespace, workflowTemplateClone)
+}
+
+// CloneWorkflowTemplateVersion creates a new workflow template named newName in dstNamespace whose initial version
+// has the manifest and labels of the given version of the workflow template identified by srcNamespace and uid.
+// This allows forking from a known good version when later versions have regressed.
+func (c *Client) CloneWorkflowTemplateVersion(srcNamespace, uid string, version int64, dstNamespace, newName string) (*WorkflowTemplate, error) {
+	if version <= 0 {
+		return nil, util.NewUserError(codes.InvalidArgument, "version must be greater than 0.")
+	}
+
+	workflowTemplateVersion, err := c.getWorkflowTemplateVersionByUID(srcNamespace, uid, version)
+	if err != nil {
+		return nil, err
+	}
+
+	manifest, err := c.resolvedWorkflowTemplateVersionManifest(srcNamespace, uid, workflowTemplateVersion)
+	if err != nil {
+		return nil, err
+	}
+
+	workflowTemplateClone := &WorkflowTemplate{
+		Name:     newName,
+		Manifest: manifest,
+		Labels:   workflowTemplateVersion.Labels,
+		IsLatest: true,
+	}
+
+	return c.CreateWorkflowTemplate(dstNamespace, workflowTemplateClone)
+}
+
+// resolvedWorkflowTemplateVersionManifest returns the manifest of the version with its fragments and base resolved,
+// see resolvedWorkflowTemplateVersion. Clones use it, as the fragments and base are not copied with them.
+func (c *Client) resolvedWorkflowTemplateVersionManifest(namespace, uid string, workflowTemplateVersion *WorkflowTemplateVersion) (string, error) {
+	resolved, err := c.resolvedWorkflowTemplateVersion(namespace, uid, workflowTemplateVersion)
+	if err != nil {
+		return "", err
+	}
+
+	manifest, err := resolved.resolvedManifest()
+	if err != nil {
+		return "", util.NewUserError(codes.InvalidArgument, err.Error())
+	}
+
+	return manifest, nil
+}
+
+// ListWorkflowTemplates returns all WorkflowTemplates where the results
+// are filtered by is_archived and is_System is false.
+func (c *Client) ListWorkflowTemplates(namespace string, request *request.Request) (workflowTemplateVersions []*WorkflowTemplate, err error) {
+	workflowTemplateVersions, err = c.selectWorkflowTemplatesDB(namespace, request)
+	if err != nil {
+		log.WithFields(log.Fields{
+			"Namespace": namespace,
+			"Error":     err.Error(),
+		}).Error("Workflow templates not found.")
+		return nil, util.NewUserError(codes.NotFound, "Workflow templates not found.")
+	}
+
+	err = c.appendExtraWorkflowTemplateData(namespace, workflowTemplateVersions)
+
+	return
+}
+
+// ListWorkflowTemplateSummaries is like ListWorkflowTemplates, but returns summaries of the workflow templates.
+// Only the database is queried, execution and cron workflow statistics are not loaded.
+func (c *Client) ListWorkflowTemplateSummaries(namespace string, request *request.Request) (summaries []*WorkflowTemplateSummary, err error) {
+	summaries = make([]*WorkflowTemplateSummary, 0)
+
+	query := sb.Select("wt.id", "wt.uid", "wt.name", "wt.created_at", "wt.labels").
+		Column("MAX(wtv.version) FILTER (WHERE wtv.is_latest) version, COUNT(wtv.*) versions").
+		From("workflow_templates wt").
+		Join("workflow_template_versions wtv ON wtv.workflow_template_id = wt.id").
+		Where(sq.Eq{
+			"wt.namespace":   namespace,
+			"wt.is_archived": false,
+			"wt.is_system":   false,
+		}).
+		GroupBy("wt.id").
+		OrderBy("wt.is_deprecated")
+	query = applyWorkflowTemplateListRequest(query, request)
+
+	if err = c.DB.Selectx(&summaries, query); err != nil {
+		log.WithFields(log.Fields{
+			"Namespace": namespace,
+			"Error":     err.Error(),
+		}).Error("Unable to list workflow template summaries.")
+		return nil, util.NewUserError(codes.Unknown, "Unable to list workflow template summaries.")
+	}
+
+	return
+}
+
+// ListWorkflowTemplatesForUser returns the non-archived and non-system workflow templates in the namespace
+// that were created by the Actor of the client, or are public.
+// Ownership is based on the created_by of the workflow template, which is the Actor of the client that created it.
+// A client without an Actor only gets the public workflow templates.
+func (c *Client) ListWorkflowTemplatesForUser(namespace string) (workflowTemplates []*WorkflowTemplate, err error) {
+	workflowTemplates = make([]*WorkflowTemplate, 0)
+
+	visibility := sq.Or{
+		sq.Eq{"wt.is_public": true},
+	}
+	if c.Actor != "" {
+		visibility = append(visibility, sq.Eq{"wt.created_by": c.Actor})
+	}
+
+	query := c.selectWorkflowTemplatesQuery(namespace, &request.Request{}).
+		Where(sq.Eq{
+			"wt.is_archived": false,
+			"wt.is_system":   false,
+		}).
+		Where(visibility)
+
+	if err = c.DB.Selectx(&workflowTemplates, query); err != nil {
+		log.WithFields(log.Fields{
+			"Namespace": namespace,
+			"Actor":     c.Actor,
+			"Error":     err.Error(),
+		}).Error("Unable to list workflow templates.")
+		return nil, util.NewUserError(codes.Unknown, "Unable to list workflow templates.")
+	}
+
+	err = c.appendExtraWorkflowTemplateData(namespace, workflowTemplates)
+
+	return
+}
+
+// ListWorkflowTemplatesByCategory returns the non-archived and non-system workflow templates in the namespace
+// with the category. An empty category returns the uncategorized workflow templates.
+func (c *Client) ListWorkflowTemplatesByCategory(namespace, category string) (workflowTemplates []*WorkflowTemplate, err error) {
+	workflowTemplates = make([]*WorkflowTemplate, 0)
+
+	query := c.selectWorkflowTemplatesQuery(namespace, &request.Request{}).
+		Where(sq.Eq{
+			"wt.is_archived": false,
+			"wt.is_system":   false,
+			"wt.category":    category,
+		})
+
+	if err = c.DB.Selectx(&workflowTemplates, query); err != nil {
+		log.WithFields(log.Fields{
+			"Namespace": namespace,
+			"Category":  category,
+			"Error":     err.Error(),
+		}).Error("Unable to list workflow templates by category.")
+		return nil, util.NewUserError(codes.Unknown, "Unable to list workflow templates by category.")
+	}
+
+	err = c.appendExtraWorkflowTemplateData(namespace, workflowTemplates)
+
+	return
+}
+
+// FindWorkflowTemplatesUsingImage returns the non-archived workflow templates in the namespace whose latest manifest
+// has a container, script, init container or sidecar image that matches the pattern, e.g. when an image has a known vulnerability.
+//
+// The pattern uses the syntax of path.Match, except that "*" and "?" also match "/", so "pytorch/pytorch:*" matches every tag
+// of pytorch/pytorch and "*/pytorch:*" matches it in any registry. The manifest is searched with the fragments and base template
+// the version was created with. Images set by parameters are not searched.
+func (c *Client) FindWorkflowTemplatesUsingImage(namespace, imagePattern string) (workflowTemplates []*WorkflowTemplate, err error) {
+	if imagePattern == "" {
+		return nil, util.NewUserError(codes.InvalidArgument, "Image pattern is required.")
+	}
+	imageRegexp, err := compileImagePattern(imagePattern)
+	if err != nil {
+		return nil, util.NewUserError(codes.InvalidArgument, fmt.Sprintf("Invalid image pattern '%v'.", imagePattern))
+	}
+
+	candidates := make([]*struct {
+		WorkflowTemplate
+		ResolvedManifest string `db:"resolved_manifest"`
+	}, 0)
+	likeFilter := imagePatternLikeFilter(imagePattern)
+	query := c.latestWorkflowTemplateVersionsSelectBuilder(namespace).
+		Columns("COALESCE(wtv.resolved_manifest, '') resolved_manifest").
+		Where(sq.Or{
+			sq.Like{"wtv.manifest": likeFilter},
+			sq.Like{"wtv.resolved_manifest": likeFilter},
+		}).
+		OrderBy("wt.name")
+
+	if err = c.DB.Selectx(&candidates, query); err != nil {
+		log.WithFields(log.Fields{
+			"Namespace":    namespace,
+			"ImagePattern": imagePattern,
+			"Error":        err.Error(),
+		}).Error("Unable to search workflow template manifests.")
+		return nil, util.NewUserErrorWrap(err, "Workflow templates")
+	}
+
+	workflowTemplates = make([]*WorkflowTemplate, 0)
+	for _, candidate := range candidates {
+		// versions created before resolved manifests were stored only have their own manifest
+		manifest := candidate.ResolvedManifest
+		if manifest == "" {
+			manifest = candidate.Manifest
+		}
+
+		matches, err := manifestUsesImage([]byte(manifest), imageRegexp)
+		if err != nil {
+			log.WithFields(log.Fields{
+				"Namespace": namespace,
+				"UID":       candidate.UID,
+				"Error":     err.Error(),
+			}).Warn("Unable to parse workflow template manifest, skipping it.")
+			continue
+		}
+		if matches {
+			workflowTemplates = append(workflowTemplates, &candidate.WorkflowTemplate)
+		}
+	}
+
+	return
+}
+
+// compileImagePattern converts the image pattern of FindWorkflowTemplatesUsingImage to a regular expression.
+// The syntax is the one of path.Match, but "*" and "?" also match "/", as images are not paths.
+func compileImagePattern(imagePattern string) (*regexp.Regexp, error) {
+	if _, err := path.Match(imagePattern, ""); err != nil {
+		return nil, err
+	}
+
+	expr := strings.Builder{}
+	expr.WriteString("^")
+	runes := []rune(imagePattern)
+	for i := 0; i < len(runes); i++ {
+		switch runes[i] {
+		case '*':
+			expr.WriteString(".*")
+		case '?':
+			expr.WriteString(".")
+		case '[':
+			expr.WriteString("[")
+			if i+1 < len(runes) && runes[i+1] == '^' {
+				expr.WriteString("^")
+				i++
+			}
+			for i++; i < len(runes) && runes[i] != ']'; i++ {
+				if runes[i] == '\\' && i+1 < len(runes) {
+					i++
+				}
+				if runes[i] == '-' {
+					expr.WriteString("-")
+					continue
+				}
+				expr.WriteString(regexp.QuoteMeta(string(runes[i])))
+			}
+			expr.WriteString("]")
+		case '\\':
+			if i+1 < len(runes) {
+				i++
+			}
+			expr.WriteString(regexp.QuoteMeta(string(runes[i])))
+		default:
+			expr.WriteString(regexp.QuoteMeta(string(runes[i])))
+		}
+	}
+	expr.WriteString("$")
+
+	return regexp.Compile(expr.String())
+}
+
+// imagePatternLikeFilter returns a LIKE filter with the longest literal part of the image pattern,
+// so the database only returns manifests that can have a matching image.
+func imagePatternLikeFilter(imagePattern string) string {
+	literal := ""
+	part := make([]rune, 0)
+	endPart := func() {
+		if len(string(part)) > len(literal) {
+			literal = string(part)
+		}
+		part = part[:0]
+	}
+
+	runes := []rune(imagePattern)
+	for i := 0; i < len(runes); i++ {
+		switch runes[i] {
+		case '*', '?':
+			endPart()
+		case '[':
+			endPart()
+			for i < len(runes) && runes[i] != ']' {
+				i++
+			}
+		case '\\':
+			if i+1 < len(runes) {
+				i++
+				part = append(part, runes[i])
+			}
+		default:
+			part = append(part, runes[i])
+		}
+	}
+	endPart()
+
+	literal = strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`).Replace(literal)
+
+	return "%" + literal + "%"
+}
+
+// manifestUsesImage returns true if any "image" of a template in the manifest matches the image regular expression.
+func manifestUsesImage(manifest []byte, imageRegexp *regexp.Regexp) (bool, error) {
+	spec := make(map[string]interface{})
+	if err := yaml.Unmarshal(manifest, &spec); err != nil {
+		return false, err
+	}
+
+	templates, _ := spec["templates"].([]interface{})
+	for _, template := range templates {
+		templateMap, ok := template.(map[string]interface{})
+		if !ok {
+			continue
+		}
+
+		containers := make([]interface{}, 0)
+		containers = append(containers, templateMap["container"], templateMap["script"])
+		for _, key := range []string{"initContainers", "sidecars"} {
+			if list, ok := templateMap[key].([]interface{}); ok {
+				containers = append(containers, list...)
+			}
+		}
+
+		for _, container := range containers {
+			containerMap, ok := container.(map[string]interface{})
+			if !ok {
+				continue
+			}
+			image, ok := containerMap["image"].(string)
+			if !ok {
+				continue
+			}
+			if imageRegexp.MatchString(image) {
+				return true, nil
+			}
+		}
+	}
+
+	return false, nil
+}
+
+// ListWorkflowTemplateCategories returns the categories of the non-archived and non-system workflow templates in the namespace, sorted.
+// If there are uncategorized workflow templates, the empty category is included.
+func (c *Client) ListWorkflowTemplateCategories(namespace string) (categories []string, err error) {
+	categories = make([]string, 0)
+
+	query := sb.Select("DISTINCT category").
+		From("workflow_templates").
+		Where(sq.Eq{
+			"namespace":   namespace,
+			"is_archived": false,
+			"is_system":   false,
+		}).
+		OrderBy("category")
+
+	if err = c.DB.Selectx(&categories, query); err != nil {
+		log.WithFields(log.Fields{
+			"Namespace": namespace,
+			"Error":     err.Error(),
+		}).Error("Unable to list workflow template categories.")
+		return nil, util.NewUserError(codes.Unknown, "Unable to list workflow template categories.")
+	}
+
+	return
+}
+
+// workflowTemplateWithStatistics is a workflow template row with its execution statistics
+type workflowTemplateWithStatistics struct {
+	WorkflowTemplate
+	Statistics WorkflowExecutionStatisticReport `db:"statistics"`
+}
+
+// ListWorkflowTemplatesByFailureRate returns the non-archived and non-system workflow templates in the namespace
+// ordered by the ratio of failed to total executions, highest first. Templates without executions are last.
+// The WorkflowExecutionStatisticReport of each workflow template is set.
+func (c *Client) ListWorkflowTemplatesByFailureRate(namespace string) ([]*WorkflowTemplate, error) {
+	// the sub queries use ? placeholders, they are numbered when the query is built
+	workflowTemplateIDsSQL, workflowTemplateIDsArgs, err := sq.Select("id").
+		From("workflow_templates").
+		Where(sq.Eq{
+			"namespace":   namespace,
+			"is_archived": false,
+			"is_system":   false,
+		}).
+		ToSql()
+	if err != nil {
+		return nil, err
+	}
+
+	// only the executions of the listed workflow templates are counted
+	statsQuery, statsArgs, err := sq.Select(workflowExecutionStatisticsSelect).
+		From("workflow_executions we").
+		Join("workflow_template_versions wtv ON wtv.id = we.workflow_template_version_id").
+		Where("wtv.workflow_template_id IN ("+workflowTemplateIDsSQL+")", workflowTemplateIDsArgs...).
+		GroupBy("wtv.workflow_template_id").
+		ToSql()
+	if err != nil {
+		return nil, err
+	}
+
+	query := c.workflowTemplatesSelectBuilder(namespace).
+		Columns(
+			`wt.id "statistics.workflow_template_id"`,
+			`s.last_executed "statistics.last_executed"`,
+			`COALESCE(s.running, 0) "statistics.running"`,
+			`COALESCE(s.completed, 0) "statistics.completed"`,
+			`COALESCE(s.failed, 0) "statistics.failed"`,
+			`COALESCE(s.terminated, 0) "statistics.terminated"`,
+			`COALESCE(s.total, 0) "statistics.total"`,
+		).
+		LeftJoin("("+statsQuery+") s ON s.workflow_template_id = wt.id", statsArgs...).
+		Where(sq.Eq{
+			"wt.is_archived": false,
+			"wt.is_system":   false,
+		}).
+		OrderBy("s.failed::float / NULLIF(s.total, 0) DESC NULLS LAST", "wt.created_at DESC")
+
+	rows := make([]*workflowTemplateWithStatistics, 0)
+	if err := c.DB.Selectx(&rows, query); err != nil {
+		log.WithFields(log.Fields{
+			"Namespace": namespace,
+			"Error":     err.Error(),
+		}).Error("Unable to list workflow templates by failure rate.")
+		return nil, util.NewUserError(codes.Unknown, "Unable to list workflow templates.")
+	}
+
+	workflowTemplates := make([]*WorkflowTemplate, len(rows))
+	for i, row := range rows {
+		workflowTemplate := row.WorkflowTemplate
+		statistics := row.Statistics
+		workflowTemplate.WorkflowExecutionStatisticReport = &statistics
+		workflowTemplates[i] = &workflowTemplate
+	}
+
+	return workflowTemplates, nil
+}
+
+// StreamWorkflowTemplates calls fn for each non-archived and non-system workflow template in the namespace, newest first.
+// The rows are read one at a time, so the templates are not all held in memory like ListWorkflowTemplates does.
+// Execution statistics are not loaded. If fn returns an error, iteration stops and the error is returned.
+func (c *Client) StreamWorkflowTemplates(namespace string, fn func(*WorkflowTemplate) error) error {
+	query := c.selectWorkflowTemplatesQuery(namespace, &request.Request{}).
+		Where(sq.Eq{
+			"wt.is_archived": false,
+			"wt.is_system":   false,
+		})
+
+	rows, err := c.DB.Rowsx(query)
+	if err != nil {
+		log.WithFields(log.Fields{
+			"Namespace": namespace,
+			"Error":     err.Error(),
+		}).Error("Unable to list workflow templates.")
+		return util.NewUserError(codes.Unknown, "Unable to list workflow templates.")
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		workflowTemplate := &WorkflowTemplate{}
+		if err := rows.StructScan(workflowTemplate); err != nil {
+			log.WithFields(log.Fields{
+				"Namespace": namespace,
+				"Error":     err.Error(),
+			}).Error("Unable to scan workflow template.")
+			return util.NewUserError(codes.Unknown, "Unable to list workflow templates.")
+		}
+
+		if err := fn(workflowTemplate); err != nil {
+			return err
+		}
+	}
+
+	if err := rows.Err(); err != nil {
+		log.WithFields(log.Fields{
+			"Namespace": namespace,
+			"Error":     err.Error(),
+		}).Error("Unable to list workflow templates.")
+		return util.NewUserError(codes.Unknown, "Unable to list workflow templates.")
+	}
+
+	return nil
+}
+
+// appendExtraWorkflowTemplateData adds extra information to workflow templates
+// * execution statistics (including cron)
+func (c *Client) appendExtraWorkflowTemplateData(namespace string, workflowTemplateVersions []*WorkflowTemplate) (err error) {
+	err = c.GetWorkflowExecutionStatisticsForTemplates(workflowTemplateVersions...)
+	if err != nil {
+		log.WithFields(log.Fields{
+			"Namespace": namespace,
+			"Error":     err.Error(),
+		}).Error("Unable to get Workflow Execution Statistic for Templates.")
+		return util.NewUserError(codes.NotFound, "Unable to get Workflow Execution Statistic for Templates.")
+	}
+
+	err = c.GetCronWorkflowStatisticsForTemplates(workflowTemplateVersions...)
+	if err != nil {
+		log.WithFields(log.Fields{
+			"Namespace": namespace,
+			"Error":     err.Error(),
+		}).Error("Unable to get Cron Workflow Statistic for Templates.")
+		return util.NewUserError(codes.NotFound, "Unable to get Cron Workflow Statistic for Templates.")
+	}
+
+	return
+}
+
+func (c *Client) getLatestWorkflowTemplate(namespace, uid string) (*WorkflowTemplate, error) {
+	return c.getWorkflowTemplate(namespace, uid, 0) //version=0 means latest
+}
+
+func (c *Client) ArchiveWorkflowTemplate(namespace, uid string) (archived bool, err error) {
+	defer c.InvalidateWorkflowTemplateCache(namespace, uid)
+
+	workflowTemplate, err := c.getLatestWorkflowTemplate(namespace, uid)
+	if err != nil {
+		log.WithFields(log.Fields{
+			"Namespace": namespace,
+			"UID":       uid,
+			"Error":     err.Error(),
+		}).Error("Get Workflow Template failed.")
+		return false, argoWorkflowTemplateUserError(err, "Unable to archive workflow template.")
+	}
+	if workflowTemplate == nil {
+		return false, util.NewUserError(codes.NotFound, "Workflow template not found.")
+	}
+
+	wftVersions, err := c.listWorkflowTemplateVersions(namespace, uid)
+	if err != nil {
+		log.WithFields(log.Fields{
+			"Namespace": namespace,
+			"UID":       uid,
+			"Error":     err.Error(),
+		}).Error("Get Workflow Template Versions failed.")
+		return false, util.NewUserError(codes.Unknown, "Unable to archive workflow template.")
+	}
+
+	//cron workflows
+	cronWorkflows := []*CronWorkflow{}
+	cwfSB := c.cronWorkflowSelectBuilder(namespace, uid).
+		OrderBy("cw.created_at DESC")
+
+	if err := c.DB.Selectx(&cronWorkflows, cwfSB); err != nil {
+		log.WithFields(log.Fields{
+			"Namespace": namespace,
+			"UID":       uid,
+			"Error":     err.Error(),
+		}).Error("Get Cron Workflows failed.")
+		return false, util.NewUserError(codes.Unknown, "Unable to archive workflow template.")
+	}
+	for _, cwf := range cronWorkflows {
+		err = c.ArchiveCronWorkflow(namespace, cwf.Name)
+		if err != nil {
+			log.WithFields(log.Fields{
+				"Namespace": namespace,
+				"UID":       uid,
+				"Error":     err.Error(),
+			}).Error("Archive Cron Workflow failed.")
+			return false, util.NewUserError(codes.Unknown, "Unable to archive workflow template.")
+		}
+	}
+
+	//workflow executions
+	paginator := pagination.NewRequest(0, 100)
+
+	for _, wftVer := range wftVersions {
+		wfTempVer := strconv.FormatInt(wftVer.Version, 10)
+		workflowTemplateName := uid + "-v" + wfTempVer
+
+		for {
+			req := &request.Request{Pagination: &paginator}
+			wfs, err := c.ListWorkflowExecutions(namespace, uid, wfTempVer, true, req)
+			if err != nil {
+				log.WithFields(log.Fields{
+					"Namespace": namespace,
+					"UID":       uid,
+					"Error":     err.Error(),
+				}).Error("Get Workflow Executions failed.")
+				return false, util.NewUserError(codes.Unknown, "Unable to archive workflow template.")
+			}
+			if len(wfs) == 0 {
+				break
+			}
+			for _, wf := range wfs {
+				err = c.ArchiveWorkflowExecution(namespace, wf.UID)
+				if err != nil {
+					log.WithFields(log.Fields{
+						"Namespace": namespace,
+						"UID":       uid,
+						"Error":     err.Error(),
+					}).Error("Archive Workflow Execution Failed.")
+					return false, util.NewUserError(codes.Unknown, "Unable to archive workflow template.")
+				}
+			}
+		}
+
+		err = c.ArgoprojV1alpha1().WorkflowTemplates(namespace).Delete(workflowTemplateName, nil)
+		if err != nil {
+			if strings.Contains(err.Error(), "not found") {
+				return true, nil
+			}
+			return false, err
+		}
+	}
+
+	if err := c.archiveWorkflowTemplateDB(namespace, uid, workflowTemplate.ID); err != nil {
+		log.WithFields(log.Fields{
+			"Namespace": namespace,
+			"UID":       uid,
+			"Error":     err.Error(),
+		}).Error("Archive Workflow Template DB failed.")
+		return false, util.NewUserError(codes.Unknown, "Unable to archive workflow template.")
+	}
+
+	return true, nil
+}
+
+// archiveWorkflowTemplateDB marks the workflow templates with the uid as archived and records it in the audit log.
+func (c *Client) archiveWorkflowTemplateDB(namespace, uid string, workflowTemplateID uint64) error {
+	tx, err := c.DB.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if err := c.insertWorkflowTemplateAuditEntry(tx, workflowTemplateID, AuditActionArchive, nil); err != nil {
+		return err
+	}
+
+	_, err = sb.Update("workflow_templates").
+		Set("is_archived", true).
+		Set("archived_at", sq.Expr("COALESCE(archived_at, ?)", time.Now().UTC())).
+		Where(sq.Eq{
+			"uid":       uid,
+			"namespace": namespace,
+		}).RunWith(tx).
+		Exec()
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// UnarchiveWorkflowTemplate restores the most recently archived workflow template with the uid and recreates the argo
+// workflow templates of its versions. Its workflow executions and cron workflows stay archived.
+// codes.AlreadyExists is returned if a non-archived workflow template has the uid.
+func (c *Client) UnarchiveWorkflowTemplate(namespace, uid string) (*WorkflowTemplate, error) {
+	defer c.InvalidateWorkflowTemplateCache(namespace, uid)
+
+	workflowTemplateID := uint64(0)
+	err := sb.Select("id").
+		From("workflow_templates").
+		Where(sq.Eq{
+			"namespace":   namespace,
+			"uid":         uid,
+			"is_archived": true,
+		}).
+		OrderBy("archived_at DESC NULLS LAST", "id DESC").
+		Limit(1).
+		RunWith(c.DB).
+		QueryRow().
+		Scan(&workflowTemplateID)
+	if err == sql.ErrNoRows {
+		return nil, util.NewUserError(codes.NotFound, "Archived workflow template not found.")
+	}
+	if err != nil {
+		return nil, util.NewUserErrorWrap(err, "Workflow template")
+	}
+
+	versions := make([]*WorkflowTemplateVersion, 0)
+	query := sb.Select("version", "is_latest", "manifest", "resolved_manifest", "labels").
+		From("workflow_template_versions").
+		Where(sq.Eq{
+			"workflow_template_id": workflowTemplateID,
+		}).
+		OrderBy("version")
+	if err := c.DB.Selectx(&versions, query); err != nil {
+		return nil, util.NewUserErrorWrap(err, "Workflow template")
+	}
+
+	tx, err := c.DB.Begin()
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	_, err = sb.Update("workflow_templates").
+		Set("is_archived", false).
+		Set("archived_at", nil).
+		Set("updated_at", time.Now().UTC()).
+		Where(sq.Eq{
+			"id": workflowTemplateID,
+		}).
+		RunWith(tx).
+		Exec()
+	if err != nil {
+		if util.IsUniqueViolation(err) {
+			return nil, util.NewUserError(codes.AlreadyExists, "A workflow template with the same uid already exists.")
+		}
+		return nil, util.NewUserErrorWrap(err, "Workflow template")
+	}
+
+	if err := c.insertWorkflowTemplateAuditEntry(tx, workflowTemplateID, AuditActionUnarchive, nil); err != nil {
+		return nil, err
+	}
+
+	created := make([]string, 0, len(versions))
+	removeCreated := func() {
+		for _, name := range created {
+			if err := c.ArgoprojV1alpha1().WorkflowTemplates(namespace).Delete(name, &v1.DeleteOptions{}); err != nil {
+				log.WithFields(log.Fields{
+					"Namespace": namespace,
+					"Name":      name,
+					"Error":     err.Error(),
+				}).Error("Could not remove unarchived argo workflow template.")
+			}
+		}
+	}
+
+	for _, version := range versions {
+		// the resolved manifest is used, as the fragments and base of the version may have changed since it was archived
+		manifest := version.ResolvedManifest
+		if manifest == "" {
+			manifest = version.Manifest
+		}
+
+		argoWft, err := createArgoWorkflowTemplate(&WorkflowTemplate{
+			UID:      uid,
+			Manifest: manifest,
+			Labels:   version.Labels,
+		}, version.Version, c.NamespaceDefaultLabels[namespace])
+		if err != nil {
+			removeCreated()
+			return nil, err
+		}
+		argoWft.Labels[label.WorkflowTemplateVersionUid] = strconv.FormatInt(version.Version, 10)
+		if !version.IsLatest {
+			delete(argoWft.Labels, label.VersionLatest)
+		}
+
+		if _, err := c.ArgoprojV1alpha1().WorkflowTemplates(namespace).Create(argoWft); err != nil {
+			log.WithFields(log.Fields{
+				"Namespace": namespace,
+				"Name":      argoWft.Name,
+				"Error":     err.Error(),
+			}).Error("Could not create unarchived argo workflow template.")
+			removeCreated()
+			return nil, util.NewUserError(codes.Unknown, "Unable to unarchive workflow template.")
+		}
+		created = append(created, argoWft.Name)
+	}
+
+	if err := tx.Commit(); err != nil {
+		removeCreated()
+		return nil, err
+	}
+
+	return c.getLatestWorkflowTemplate(namespace, uid)
+}
+
+// PurgeArchivedWorkflowTemplates hard deletes the workflow templates in the namespace that were archived more than olderThan ago.
+// Their versions, executions, cron workflows and any argo workflow templates left over are deleted too.
+// The audit log entries are kept, they can still be read by namespace and uid with GetWorkflowTemplateAuditLog.
+// System workflow templates, which belong to workspace templates, are not purged. The number of purged workflow templates is returned.
+func (c *Client) PurgeArchivedWorkflowTemplates(namespace string, olderThan time.Duration) (purged int, err error) {
+	defer c.InvalidateWorkflowTemplateCache(namespace, "")
+
+	workflowTemplates := make([]*WorkflowTemplate, 0)
+	query := c.workflowTemplatesSelectBuilder(namespace).
+		Where(sq.Eq{
+			"wt.is_archived": true,
+			"wt.is_system":   false,
+		}).
+		Where(sq.Lt{
+			"wt.archived_at": time.Now().UTC().Add(-olderThan),
+		})
+	if err = c.DB.Selectx(&workflowTemplates, query); err != nil {
+		return 0, util.NewUserErrorWrap(err, "Workflow template")
+	}
+
+	for _, workflowTemplate := range workflowTemplates {
+		if err = c.purgeWorkflowTemplate(namespace, workflowTemplate); err != nil {
+			log.WithFields(log.Fields{
+				"Namespace":        namespace,
+				"WorkflowTemplate": workflowTemplate.LogEntry(),
+				"Error":            err.Error(),
+			}).Error("Purge Workflow Template failed.")
+			return purged, util.NewUserError(codes.Unknown, "Unable to purge archived workflow templates.")
+		}
+		purged++
+	}
+
+	return purged, nil
+}
+
+// purgeWorkflowTemplate hard deletes an archived workflow template, see PurgeArchivedWorkflowTemplates.
+// A new workflow template may have been created with the same uid, so the argo workflow templates
+// are deleted by the name of each version instead of by the uid label.
+func (c *Client) purgeWorkflowTemplate(namespace string, workflowTemplate *WorkflowTemplate) error {
+	versions := make([]int64, 0)
+	versionsQuery := sb.Select("version").
+		From("workflow_template_versions").
+		Where(sq.Eq{
+			"workflow_template_id": workflowTemplate.ID,
+		})
+	if err := c.DB.Selectx(&versions, versionsQuery); err != nil {
+		return err
 	}
 
-	err = c.appendExtraWorkflowTemplateData(namespace, workflowTemplateVersions)
-
-	return
-}
+	tx, err := c.DB.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
 
-// appendExtraWorkflowTemplateData adds extra information to workflow templates
-// * execution statistics (including cron)
-func (c *Client) appendExtraWorkflowTemplateData(namespace string, workflowTemplateVersions []*WorkflowTemplate) (err error) {
-	err = c.GetWorkflowExecutionStatisticsForTemplates(workflowTemplateVersions...)
+	// the sub query uses ? placeholders, they are numbered when the delete query is built
+	versionIDs := sq.Select("id").
+		From("workflow_template_versions").
+		Where(sq.Eq{
+			"workflow_template_id": workflowTemplate.ID,
+		})
+	versionIDsSQL, versionIDsArgs, err := versionIDs.ToSql()
 	if err != nil {
-		log.WithFields(log.Fields{
-			"Namespace": namespace,
-			"Error":     err.Error(),
-		}).Error("Unable to get Workflow Execution Statistic for Templates.")
-		return util.NewUserError(codes.NotFound, "Unable to get Workflow Execution Statistic for Templates.")
+		return err
 	}
 
-	err = c.GetCronWorkflowStatisticsForTemplates(workflowTemplateVersions...)
+	// executions reference cron workflows, so they are deleted first
+	for _, table := range []string{"workflow_executions", "cron_workflows"} {
+		_, err = sb.Delete(table).
+			Where("workflow_template_version_id IN ("+versionIDsSQL+")", versionIDsArgs...).
+			RunWith(tx).
+			Exec()
+		if err != nil {
+			return err
+		}
+	}
+
+	_, err = sb.Delete("workflow_templates").
+		Where(sq.Eq{
+			"id": workflowTemplate.ID,
+		}).
+		RunWith(tx).
+		Exec()
 	if err != nil {
-		log.WithFields(log.Fields{
-			"Namespace": namespace,
-			"Error":     err.Error(),
-		}).Error("Unable to get Cron Workflow Statistic for Templates.")
-		return util.NewUserError(codes.NotFound, "Unable to get Cron Workflow Statistic for Templates.")
+		return err
 	}
 
-	return
-}
+	if err := tx.Commit(); err != nil {
+		return err
+	}
 
-func (c *Client) getLatestWorkflowTemplate(namespace, uid string) (*WorkflowTemplate, error) {
-	return c.getWorkflowTemplate(namespace, uid, 0) //version=0 means latest
+	// argo is only changed once the database records are gone, so a failed transaction does not leave
+	// versions without their argo workflow templates
+	for _, version := range versions {
+		name := fmt.Sprintf("%v-v%v", workflowTemplate.UID, version)
+		err := c.ArgoprojV1alpha1().WorkflowTemplates(namespace).Delete(name, &v1.DeleteOptions{})
+		if err != nil && !apierrors.IsNotFound(err) {
+			return err
+		}
+	}
+
+	return nil
 }
 
-func (c *Client) ArchiveWorkflowTemplate(namespace, uid string) (archived bool, err error) {
-	workflowTemplate, err := c.getLatestWorkflowTemplate(namespace, uid)
-	if err != nil {
-		log.WithFields(log.Fields{
-			"Namespace": namespace,
-			"UID":       uid,
-			"Error":     err.Error(),
-		}).Error("Get Workflow Template failed.")
-		return false, util.NewUserError(codes.Unknown, "Unable to archive workflow template.")
+// MoveWorkflowTemplatesToNamespace moves the non-archived workflow templates of namespace src to namespace dst,
+// for example when namespaces are renamed or merged. The number of moved workflow templates is returned.
+//
+// The latest version of each workflow template is validated against dst first, including that the secrets and config maps
+// it references exist there. Then the argo workflow templates of every version are created in dst and the database is updated
+// in a transaction, so nothing is moved if either fails. The argo workflow templates in src are deleted once the move is committed.
+// The argo workflow templates in dst get the NamespaceDefaultLabels of dst instead of the ones of src.
+//
+// Executions and cron workflows of the workflow templates stay in src. The cron workflows keep running the workflow
+// they were created with, but they can not be updated anymore, as their workflow template is no longer found in src.
+// Move or recreate them in dst.
+func (c *Client) MoveWorkflowTemplatesToNamespace(src, dst string) (moved int, err error) {
+	if src == dst {
+		return 0, util.NewUserError(codes.InvalidArgument, "source and destination namespaces must be different.")
+	}
+	defer c.InvalidateWorkflowTemplateCache(src, "")
+	defer c.InvalidateWorkflowTemplateCache(dst, "")
+
+	workflowTemplates := make([]*WorkflowTemplate, 0)
+	query := c.workflowTemplatesSelectBuilder(src).
+		Where(sq.Eq{
+			"wt.is_archived": false,
+		})
+	if err = c.DB.Selectx(&workflowTemplates, query); err != nil {
+		return 0, util.NewUserErrorWrap(err, "Workflow template")
 	}
-	if workflowTemplate == nil {
-		return false, util.NewUserError(codes.NotFound, "Workflow template not found.")
+	if len(workflowTemplates) == 0 {
+		return 0, nil
 	}
 
-	wftVersions, err := c.listWorkflowTemplateVersions(namespace, uid)
-	if err != nil {
-		log.WithFields(log.Fields{
-			"Namespace": namespace,
-			"UID":       uid,
-			"Error":     err.Error(),
-		}).Error("Get Workflow Template Versions failed.")
-		return false, util.NewUserError(codes.Unknown, "Unable to archive workflow template.")
+	uids := make([]string, len(workflowTemplates))
+	ids := make([]uint64, len(workflowTemplates))
+	for i, workflowTemplate := range workflowTemplates {
+		uids[i] = workflowTemplate.UID
+		ids[i] = workflowTemplate.ID
 	}
 
-	//cron workflows
-	cronWorkflows := []*CronWorkflow{}
-	cwfSB := c.cronWorkflowSelectBuilder(namespace, uid).
-		OrderBy("cw.created_at DESC")
-
-	if err := c.DB.Selectx(&cronWorkflows, cwfSB); err != nil {
-		log.WithFields(log.Fields{
-			"Namespace": namespace,
-			"UID":       uid,
-			"Error":     err.Error(),
-		}).Error("Get Cron Workflows failed.")
-		return false, util.NewUserError(codes.Unknown, "Unable to archive workflow template.")
+	conflicts := make([]string, 0)
+	conflictsQuery := sb.Select("uid").
+		From("workflow_templates").
+		Where(sq.Eq{
+			"namespace":   dst,
+			"uid":         uids,
+			"is_archived": false,
+		}).
+		OrderBy("uid")
+	if err = c.DB.Selectx(&conflicts, conflictsQuery); err != nil {
+		return 0, util.NewUserErrorWrap(err, "Workflow template")
 	}
-	for _, cwf := range cronWorkflows {
-		err = c.ArchiveCronWorkflow(namespace, cwf.Name)
+	if len(conflicts) > 0 {
+		return 0, util.NewUserError(codes.AlreadyExists, fmt.Sprintf("workflow templates already exist in namespace '%v': %v", dst, strings.Join(conflicts, ", ")))
+	}
+
+	argoWorkflowTemplates := make([]v1alpha1.WorkflowTemplate, 0)
+	for _, workflowTemplate := range workflowTemplates {
+		if err = c.validateMovedWorkflowTemplate(src, dst, workflowTemplate); err != nil {
+			return 0, err
+		}
+
+		versions, err := c.listArgoWorkflowTemplates(src, workflowTemplate.UID)
 		if err != nil {
-			log.WithFields(log.Fields{
-				"Namespace": namespace,
-				"UID":       uid,
-				"Error":     err.Error(),
-			}).Error("Archive Cron Workflow failed.")
-			return false, util.NewUserError(codes.Unknown, "Unable to archive workflow template.")
+			return 0, argoWorkflowTemplateUserError(err, "Unable to move workflow templates.")
 		}
+		argoWorkflowTemplates = append(argoWorkflowTemplates, *versions...)
 	}
 
-	//workflow executions
-	paginator := pagination.NewRequest(0, 100)
+	versionLabels, err := c.getWorkflowTemplateVersionLabelsByArgoName(ids)
+	if err != nil {
+		return 0, util.NewUserErrorWrap(err, "Workflow template")
+	}
 
-	for _, wftVer := range wftVersions {
-		wfTempVer := strconv.FormatInt(wftVer.Version, 10)
-		workflowTemplateName := uid + "-v" + wfTempVer
+	tx, err := c.DB.Begin()
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
 
-		for {
-			req := &request.Request{Pagination: &paginator}
-			wfs, err := c.ListWorkflowExecutions(namespace, uid, wfTempVer, true, req)
-			if err != nil {
+	_, err = sb.Update("workflow_templates").
+		Set("namespace", dst).
+		Set("updated_at", time.Now().UTC()).
+		Where(sq.Eq{
+			"id": ids,
+		}).
+		RunWith(tx).
+		Exec()
+	if err != nil {
+		return 0, util.NewUserErrorWrap(err, "Workflow template")
+	}
+
+	created := make([]string, 0, len(argoWorkflowTemplates))
+	removeCreated := func() {
+		for _, name := range created {
+			if err := c.ArgoprojV1alpha1().WorkflowTemplates(dst).Delete(name, &v1.DeleteOptions{}); err != nil {
 				log.WithFields(log.Fields{
-					"Namespace": namespace,
-					"UID":       uid,
+					"Namespace": dst,
+					"Name":      name,
 					"Error":     err.Error(),
-				}).Error("Get Workflow Executions failed.")
-				return false, util.NewUserError(codes.Unknown, "Unable to archive workflow template.")
-			}
-			if len(wfs) == 0 {
-				break
-			}
-			for _, wf := range wfs {
-				err = c.ArchiveWorkflowExecution(namespace, wf.UID)
-				if err != nil {
-					log.WithFields(log.Fields{
-						"Namespace": namespace,
-						"UID":       uid,
-						"Error":     err.Error(),
-					}).Error("Archive Workflow Execution Failed.")
-					return false, util.NewUserError(codes.Unknown, "Unable to archive workflow template.")
-				}
+				}).Error("Could not remove moved argo workflow template.")
 			}
 		}
+	}
 
-		err = c.ArgoprojV1alpha1().WorkflowTemplates(namespace).Delete(workflowTemplateName, nil)
-		if err != nil {
-			if strings.Contains(err.Error(), "not found") {
-				return true, nil
-			}
-			return false, err
+	for i := range argoWorkflowTemplates {
+		argoWft := argoWorkflowTemplates[i].DeepCopy()
+		argoWft.Namespace = dst
+		argoWft.ResourceVersion = ""
+		argoWft.UID = ""
+		argoWft.SelfLink = ""
+		argoWft.CreationTimestamp = v1.Time{}
+		label.DeleteWithPrefix(argoWft.Labels, label.TagPrefix)
+		mergeWorkflowTemplateTagLabels(argoWft.Labels, versionLabels[argoWft.Name], c.NamespaceDefaultLabels[dst])
+
+		if _, err := c.ArgoprojV1alpha1().WorkflowTemplates(dst).Create(argoWft); err != nil {
+			log.WithFields(log.Fields{
+				"Namespace": dst,
+				"Name":      argoWft.Name,
+				"Error":     err.Error(),
+			}).Error("Could not create moved argo workflow template.")
+			removeCreated()
+			return 0, util.NewUserError(codes.Unknown, "Unable to move workflow templates.")
 		}
+		created = append(created, argoWft.Name)
 	}
 
-	_, err = sb.Update("workflow_templates").
-		Set("is_archived", true).
+	if err := tx.Commit(); err != nil {
+		removeCreated()
+		return 0, err
+	}
+
+	for _, argoWft := range argoWorkflowTemplates {
+		err := c.ArgoprojV1alpha1().WorkflowTemplates(src).Delete(argoWft.Name, &v1.DeleteOptions{})
+		if err != nil && !apierrors.IsNotFound(err) {
+			log.WithFields(log.Fields{
+				"Namespace": src,
+				"Name":      argoWft.Name,
+				"Error":     err.Error(),
+			}).Error("Could not delete argo workflow template after moving it.")
+		}
+	}
+
+	return len(workflowTemplates), nil
+}
+
+// getWorkflowTemplateVersionLabelsByArgoName returns the labels of every version of the workflow templates with the ids,
+// keyed by the name of the argo workflow template of the version.
+func (c *Client) getWorkflowTemplateVersionLabelsByArgoName(ids []uint64) (map[string]types.JSONLabels, error) {
+	rows := make([]*struct {
+		UID     string
+		Version int64
+		Labels  types.JSONLabels
+	}, 0)
+	query := sb.Select("wt.uid", "wtv.version", "wtv.labels").
+		From("workflow_template_versions wtv").
+		Join("workflow_templates wt ON wt.id = wtv.workflow_template_id").
 		Where(sq.Eq{
-			"uid":       uid,
-			"namespace": namespace,
-		}).RunWith(c.DB).
-		Exec()
+			"wt.id": ids,
+		})
+	if err := c.DB.Selectx(&rows, query); err != nil {
+		return nil, err
+	}
+
+	versionLabels := make(map[string]types.JSONLabels)
+	for _, row := range rows {
+		versionLabels[fmt.Sprintf("%v-v%v", row.UID, row.Version)] = row.Labels
+	}
+
+	return versionLabels, nil
+}
 
+// validateMovedWorkflowTemplate validates the latest version of a workflow template in src as if it was in dst,
+// see MoveWorkflowTemplatesToNamespace. Fragments and base templates are loaded from src, where they are until the move.
+func (c *Client) validateMovedWorkflowTemplate(src, dst string, workflowTemplate *WorkflowTemplate) error {
+	workflowTemplateVersion, err := c.getWorkflowTemplateVersionByUID(src, workflowTemplate.UID, 0)
 	if err != nil {
-		log.WithFields(log.Fields{
-			"Namespace": namespace,
-			"UID":       uid,
-			"Error":     err.Error(),
-		}).Error("Archive Workflow Template DB failed.")
-		return false, util.NewUserError(codes.Unknown, "Unable to archive workflow template.")
+		return err
 	}
+	workflowTemplate.Manifest = workflowTemplateVersion.Manifest
 
-	return true, nil
+	if err := c.loadWorkflowTemplateFragments(src, workflowTemplate); err != nil {
+		return err
+	}
+	if err := c.loadWorkflowTemplateBase(src, workflowTemplate); err != nil {
+		return err
+	}
+
+	err = c.validateWorkflowTemplate(dst, workflowTemplate)
+	if err == nil {
+		var argoWft *v1alpha1.WorkflowTemplate
+		if argoWft, err = parseWorkflowTemplateSpec(workflowTemplate); err == nil {
+			err = c.checkReferencedSecretsAndConfigMaps(dst, &argoWft.Spec.WorkflowSpec)
+		}
+	}
+	if err != nil {
+		return workflowTemplateValidationUserError(err, fmt.Sprintf("workflow template '%v' is not valid in namespace '%v': %v", workflowTemplate.UID, dst, err))
+	}
+
+	return nil
 }
 
 // createArgoWorkflowTemplate creates an argo workflow template from the workflowTemplate struct
 // the argo template stores the version information.
-func createArgoWorkflowTemplate(workflowTemplate *WorkflowTemplate, version int64) (*v1alpha1.WorkflowTemplate, error) {
+// defaultLabels are added as tags unless the workflowTemplate has a label with the same key.
+// An InvalidArgument error is returned if a label of the workflowTemplate would overwrite a system label, like the version.
+func createArgoWorkflowTemplate(workflowTemplate *WorkflowTemplate, version int64, defaultLabels map[string]string) (*v1alpha1.WorkflowTemplate, error) {
 	var argoWft *v1alpha1.WorkflowTemplate
 	var jsonOpts []argojson.JSONOpt
 	jsonOpts = append(jsonOpts, argojson.DisallowUnknownFields)
@@ -926,8 +3758,10 @@ func createArgoWorkflowTemplate(workflowTemplate *WorkflowTemplate, version int6
 		return nil, err
 	}
 
-	if err := workflowTemplate.GenerateUID(workflowTemplate.Name); err != nil {
-		return nil, err
+	if workflowTemplate.UID == "" {
+		if err := workflowTemplate.GenerateUID(workflowTemplate.Name); err != nil {
+			return nil, err
+		}
 	}
 
 	argoWft.Name = fmt.Sprintf("%v-v%v", workflowTemplate.UID, version)
@@ -939,12 +3773,86 @@ func createArgoWorkflowTemplate(workflowTemplate *WorkflowTemplate, version int6
 		label.VersionLatest:       "true",
 	}
 
-	label.MergeLabelsPrefix(labels, workflowTemplate.Labels, label.TagPrefix)
+	if collisions := label.PrefixCollisions(labels, workflowTemplate.Labels, label.TagPrefix); len(collisions) > 0 {
+		return nil, util.NewUserError(codes.InvalidArgument, fmt.Sprintf("labels %v would overwrite reserved system labels", strings.Join(collisions, ", ")))
+	}
+
+	mergeWorkflowTemplateTagLabels(labels, workflowTemplate.Labels, defaultLabels)
 	argoWft.Labels = labels
 
+	if len(workflowTemplate.Annotations) > 0 {
+		if argoWft.Annotations == nil {
+			argoWft.Annotations = make(map[string]string)
+		}
+		label.MergeLabelsPrefix(argoWft.Annotations, workflowTemplate.Annotations, label.AnnotationPrefix)
+	}
+
 	return argoWft, nil
 }
 
+// mergeWorkflowTemplateTagLabels adds the tags and the defaultLabels of the namespace to the argo labels, as tag labels.
+// The tags take precedence over the defaultLabels with the same key.
+func mergeWorkflowTemplateTagLabels(labels, tags, defaultLabels map[string]string) {
+	label.MergeLabelsPrefix(labels, defaultLabels, label.TagPrefix)
+	label.MergeLabelsPrefix(labels, tags, label.TagPrefix)
+}
+
+// tagLabelsFromArgo returns the tag labels of an argo workflow template, without the prefix and without the defaultLabels
+// of the namespace that mergeWorkflowTemplateTagLabels added. A tag with the same value as a default label is taken to be the default label.
+func tagLabelsFromArgo(argoLabels, defaultLabels map[string]string) map[string]string {
+	tags := label.RemovePrefix(label.TagPrefix, label.FilterByPrefix(label.TagPrefix, argoLabels))
+	for key, value := range defaultLabels {
+		if existing, ok := tags[key]; ok && existing == value {
+			delete(tags, key)
+		}
+	}
+
+	return tags
+}
+
+// waitForWorkflowTemplateInitialDelay and waitForWorkflowTemplateMaxDelay bound the delay between
+// the polls of WaitForWorkflowTemplate, which doubles after every poll.
+const (
+	waitForWorkflowTemplateInitialDelay = 50 * time.Millisecond
+	waitForWorkflowTemplateMaxDelay     = 2 * time.Second
+)
+
+// WaitForWorkflowTemplate polls, with exponential backoff, until the argo workflow template of the version is listable.
+// Right after a workflow template is created, argo may not list it yet, so callers that create then read can wait for it.
+// If version is 0, the latest version is waited for. A DeadlineExceeded error is returned if it is not visible within the timeout.
+func (c *Client) WaitForWorkflowTemplate(namespace, uid string, version int64, timeout time.Duration) error {
+	versionAsString := "latest"
+	if version > 0 {
+		versionAsString = strconv.FormatInt(version, 10)
+	}
+
+	deadline := time.Now().Add(timeout)
+	delay := waitForWorkflowTemplateInitialDelay
+	for {
+		_, err := c.getArgoWorkflowTemplate(namespace, uid, versionAsString)
+		if err == nil {
+			return nil
+		}
+		if !errors.Is(err, ErrArgoTemplateNotFound) {
+			return argoWorkflowTemplateUserError(err, "Unable to get workflow template.")
+		}
+
+		remaining := time.Until(deadline)
+		if remaining <= 0 {
+			return util.NewUserError(codes.DeadlineExceeded, fmt.Sprintf("Workflow template '%v' version %v was not available after %v.", uid, versionAsString, timeout))
+		}
+		if delay > remaining {
+			delay = remaining
+		}
+		time.Sleep(delay)
+
+		delay *= 2
+		if delay > waitForWorkflowTemplateMaxDelay {
+			delay = waitForWorkflowTemplateMaxDelay
+		}
+	}
+}
+
 // getArgoWorkflowTemplate will load the argo workflow template.
 // version "latest" will get the latest version, otherwise a number (as a string) will be used.
 func (c *Client) getArgoWorkflowTemplate(namespace, workflowTemplateUID, version string) (*v1alpha1.WorkflowTemplate, error) {
@@ -955,7 +3863,7 @@ func (c *Client) getArgoWorkflowTemplate(namespace, workflowTemplateUID, version
 		labelSelect += fmt.Sprintf(",%v=%v", label.Version, version)
 	}
 
-	workflowTemplates, err := c.ArgoprojV1alpha1().WorkflowTemplates(namespace).List(v1.ListOptions{
+	workflowTemplates, err := c.listArgoWorkflowTemplatesLimited(namespace, v1.ListOptions{
 		LabelSelector: labelSelect,
 	})
 	if err != nil {
@@ -964,19 +3872,21 @@ func (c *Client) getArgoWorkflowTemplate(namespace, workflowTemplateUID, version
 
 	templates := workflowTemplates.Items
 	if templates.Len() == 0 {
-		return nil, errors.New("not found")
+		return nil, ErrArgoTemplateNotFound
 	}
 
 	if templates.Len() > 1 {
-		return nil, errors.New("not unique result")
+		return nil, ErrArgoTemplateNotUnique
 	}
 
 	return &templates[0], nil
 }
 
+// listArgoWorkflowTemplates loads all of the argo workflow templates for the workflow template uid.
+// The result is sorted by the version label, newest first. Templates with a missing or invalid version label are last.
 func (c *Client) listArgoWorkflowTemplates(namespace, workflowTemplateUid string) (*[]v1alpha1.WorkflowTemplate, error) {
 	labelSelect := fmt.Sprintf("%v=%v", label.WorkflowTemplateUid, workflowTemplateUid)
-	workflowTemplates, err := c.ArgoprojV1alpha1().WorkflowTemplates(namespace).List(v1.ListOptions{
+	workflowTemplates, err := c.listArgoWorkflowTemplatesLimited(namespace, v1.ListOptions{
 		LabelSelector: labelSelect,
 	})
 	if err != nil {
@@ -984,10 +3894,24 @@ func (c *Client) listArgoWorkflowTemplates(namespace, workflowTemplateUid string
 	}
 
 	templates := []v1alpha1.WorkflowTemplate(workflowTemplates.Items)
+	sort.SliceStable(templates, func(i, j int) bool {
+		return argoWorkflowTemplateVersion(&templates[i]) > argoWorkflowTemplateVersion(&templates[j])
+	})
 
 	return &templates, nil
 }
 
+// argoWorkflowTemplateVersion returns the version stored in the argo workflow template's version label.
+// If the label is missing or not a number, -1 is returned.
+func argoWorkflowTemplateVersion(wft *v1alpha1.WorkflowTemplate) int64 {
+	version, err := strconv.ParseInt(wft.Labels[label.Version], 10, 64)
+	if err != nil {
+		return -1
+	}
+
+	return version
+}
+
 // listDBWorkflowTemplateVersions gets all of the workflow template versions for a specified workflow template uid
 // archived ones are ignored. Returned in created_at desc order.
 func (c *Client) selectWorkflowTemplateVersionsDB(namespace, workflowTemplateUID string) (versions []*WorkflowTemplateVersion, err error) {
@@ -1017,6 +3941,39 @@ func (c *Client) selectAllWorkflowTemplateVersionsDB(paginator *pagination.Pagin
 	return
 }
 
+// GetWorkflowTemplateLabelHistory returns the labels of every version of a non-archived workflow template, newest first.
+// Only the Version, CreatedAt, IsLatest and Labels fields of the returned versions are set.
+// If the workflow template does not exist, codes.NotFound is returned.
+func (c *Client) GetWorkflowTemplateLabelHistory(namespace, uid string) (versions []*WorkflowTemplateVersion, err error) {
+	versions = make([]*WorkflowTemplateVersion, 0)
+
+	sb := sb.Select("wtv.version", "wtv.created_at", "wtv.is_latest", "wtv.labels").
+		From("workflow_template_versions wtv").
+		Join("workflow_templates wt ON wt.id = wtv.workflow_template_id").
+		Where(sq.Eq{
+			"wt.namespace":   namespace,
+			"wt.uid":         uid,
+			"wt.is_archived": false,
+		}).
+		OrderBy("wtv.version DESC")
+
+	if err = c.DB.Selectx(&versions, sb); err != nil {
+		log.WithFields(log.Fields{
+			"Namespace": namespace,
+			"UID":       uid,
+			"Error":     err.Error(),
+		}).Error("Unable to get workflow template label history.")
+		return nil, util.NewUserError(codes.Unknown, "Unable to get workflow template label history.")
+	}
+
+	// every workflow template has at least one version
+	if len(versions) == 0 {
+		return nil, util.NewUserError(codes.NotFound, "Workflow template not found.")
+	}
+
+	return
+}
+
 // prefix is the label prefix.
 // e.g. prefix/my-label-key: my-label-value
 // if version is 0, latest is used.
@@ -1033,7 +3990,7 @@ func (c *Client) GetWorkflowTemplateLabels(namespace, name, prefix string, versi
 			"Name":      name,
 			"Error":     err.Error(),
 		}).Error("Workflow Template not found.")
-		return nil, util.NewUserError(codes.NotFound, "Workflow Template not found.")
+		return nil, argoWorkflowTemplateUserError(err, "Unable to get workflow template labels.")
 	}
 
 	labels = label.FilterByPrefix(prefix, wf.Labels)
@@ -1041,3 +3998,21 @@ func (c *Client) GetWorkflowTemplateLabels(namespace, name, prefix string, versi
 
 	return
 }
+
+// GetWorkflowTemplateLabelsOrEmpty is like GetWorkflowTemplateLabels, but returns an empty map instead of
+// a codes.NotFound error if the argo workflow template is missing. Other errors are returned.
+// Use it when enriching lists, so one missing workflow template does not fail the whole list.
+func (c *Client) GetWorkflowTemplateLabelsOrEmpty(namespace, name, prefix string, version int64) (map[string]string, error) {
+	labels, err := c.GetWorkflowTemplateLabels(namespace, name, prefix, version)
+	if err != nil {
+		if userErr, ok := err.(*util.UserError); ok && userErr.Code == codes.NotFound {
+			return make(map[string]string), nil
+		}
+		return nil, err
+	}
+	if labels == nil {
+		labels = make(map[string]string)
+	}
+
+	return labels, nil
+}
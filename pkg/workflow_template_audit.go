@@ -0,0 +1,104 @@
+package v1
+
+import (
+	"time"
+
+	sq "github.com/Masterminds/squirrel"
+	"github.com/onepanelio/core/pkg/util"
+	log "github.com/sirupsen/logrus"
+	"google.golang.org/grpc/codes"
+)
+
+// Audit actions recorded for workflow templates
+const (
+	AuditActionCreate      = "create"
+	AuditActionVersion     = "version"
+	AuditActionArchive     = "archive"
+	AuditActionUnarchive   = "unarchive"
+	AuditActionLabelChange = "label-change"
+	AuditActionRename      = "rename"
+)
+
+// AuditEntry is a record of a change made to a workflow template
+type AuditEntry struct {
+	ID                 uint64
+	WorkflowTemplateID *uint64 `db:"workflow_template_id"` // Nil once the workflow template is purged
+	Action             string
+	Actor              string
+	Version            *int64    // The version the action is about, if any
+	CreatedAt          time.Time `db:"created_at"`
+}
+
+// insertWorkflowTemplateAuditEntry records the action for the workflow template with the given id.
+// runner should be the transaction the change is made in, so the audit log matches the actual state.
+// The namespace and uid of the workflow template are copied into the entry, so it is kept if the workflow template is purged.
+func (c *Client) insertWorkflowTemplateAuditEntry(runner sq.BaseRunner, workflowTemplateID uint64, action string, version *int64) error {
+	_, err := sb.Insert("workflow_template_audit_logs").
+		SetMap(sq.Eq{
+			"workflow_template_id": workflowTemplateID,
+			"namespace":            sq.Expr("(SELECT namespace FROM workflow_templates WHERE id = ?)", workflowTemplateID),
+			"uid":                  sq.Expr("(SELECT uid FROM workflow_templates WHERE id = ?)", workflowTemplateID),
+			"action":               action,
+			"actor":                c.Actor,
+			"version":              version,
+		}).
+		RunWith(runner).
+		Exec()
+
+	return err
+}
+
+// insertWorkflowTemplateAuditEntryByUID is like insertWorkflowTemplateAuditEntry, for the non-archived workflow template with the uid.
+func (c *Client) insertWorkflowTemplateAuditEntryByUID(runner sq.BaseRunner, namespace, uid, action string) error {
+	workflowTemplateID := uint64(0)
+	err := sb.Select("id").
+		From("workflow_templates").
+		Where(sq.Eq{
+			"namespace":   namespace,
+			"uid":         uid,
+			"is_archived": false,
+		}).
+		RunWith(runner).
+		QueryRow().
+		Scan(&workflowTemplateID)
+	if err != nil {
+		return err
+	}
+
+	return c.insertWorkflowTemplateAuditEntry(runner, workflowTemplateID, action, nil)
+}
+
+// recordWorkflowTemplateLabelChange updates the workflow template's updated_at and records the label change in the audit log.
+// runner should be the transaction the labels are changed in.
+func (c *Client) recordWorkflowTemplateLabelChange(runner sq.BaseRunner, namespace, uid string) error {
+	if err := touchWorkflowTemplate(runner, namespace, uid); err != nil {
+		return err
+	}
+
+	return c.insertWorkflowTemplateAuditEntryByUID(runner, namespace, uid, AuditActionLabelChange)
+}
+
+// GetWorkflowTemplateAuditLog returns the audit log of the workflow template with the uid, oldest first.
+// Entries of archived and purged workflow templates that had the same uid are included.
+func (c *Client) GetWorkflowTemplateAuditLog(namespace, uid string) (entries []*AuditEntry, err error) {
+	entries = make([]*AuditEntry, 0)
+
+	query := sb.Select("al.id", "al.workflow_template_id", "al.action", "al.actor", "al.version", "al.created_at").
+		From("workflow_template_audit_logs al").
+		Where(sq.Eq{
+			"al.namespace": namespace,
+			"al.uid":       uid,
+		}).
+		OrderBy("al.created_at", "al.id")
+
+	if err = c.DB.Selectx(&entries, query); err != nil {
+		log.WithFields(log.Fields{
+			"Namespace": namespace,
+			"UID":       uid,
+			"Error":     err.Error(),
+		}).Error("Get Workflow Template Audit Log failed.")
+		return nil, util.NewUserError(codes.Unknown, "Unable to get workflow template audit log.")
+	}
+
+	return
+}
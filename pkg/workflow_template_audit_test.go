@@ -0,0 +1,73 @@
+package v1
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// TestClient_GetWorkflowTemplateAuditLog makes sure changes to a workflow template are recorded in order
+func TestClient_GetWorkflowTemplateAuditLog(t *testing.T) {
+	c := DefaultTestClient()
+	c.Actor = "admin"
+	clearDatabase(t)
+
+	namespace := "onepanel"
+	created, _ := c.CreateWorkflowTemplate(namespace, &WorkflowTemplate{
+		Name:     "test",
+		Manifest: defaultWorkflowTemplate,
+	})
+	createdVersion := created.Version
+
+	version, _ := c.CreateWorkflowTemplateVersion(namespace, &WorkflowTemplate{
+		UID:      created.UID,
+		Name:     created.Name,
+		Manifest: defaultWorkflowTemplate,
+	})
+
+	_, err := c.SetWorkflowTemplateLabels(namespace, created.UID, "tags.onepanel.io/", map[string]string{"a": "b"}, false)
+	assert.Nil(t, err)
+
+	c.ArchiveWorkflowTemplate(namespace, created.UID)
+
+	entries, err := c.GetWorkflowTemplateAuditLog(namespace, created.UID)
+	assert.Nil(t, err)
+	assert.Len(t, entries, 4)
+
+	actions := make([]string, 0)
+	for _, entry := range entries {
+		actions = append(actions, entry.Action)
+		assert.Equal(t, "admin", entry.Actor)
+	}
+	assert.Equal(t, []string{AuditActionCreate, AuditActionVersion, AuditActionLabelChange, AuditActionArchive}, actions)
+	assert.Equal(t, createdVersion, *entries[0].Version)
+	assert.Equal(t, version.Version, *entries[1].Version)
+	assert.Nil(t, entries[2].Version)
+}
+
+// TestClient_GetWorkflowTemplateAuditLog_Labels makes sure added and deleted labels and unarchiving are recorded
+func TestClient_GetWorkflowTemplateAuditLog_Labels(t *testing.T) {
+	c := DefaultTestClient()
+	clearDatabase(t)
+
+	namespace := "onepanel"
+	created, _ := c.CreateWorkflowTemplate(namespace, &WorkflowTemplate{
+		Name:     "test",
+		Manifest: defaultWorkflowTemplate,
+	})
+
+	assert.Nil(t, c.AddLabels(namespace, TypeWorkflowTemplate, created.UID, map[string]string{"a": "b"}))
+	assert.Nil(t, c.DeleteLabels(namespace, TypeWorkflowTemplate, created.UID, map[string]string{"a": ""}))
+	c.ArchiveWorkflowTemplate(namespace, created.UID)
+	_, err := c.UnarchiveWorkflowTemplate(namespace, created.UID)
+	assert.Nil(t, err)
+
+	entries, err := c.GetWorkflowTemplateAuditLog(namespace, created.UID)
+	assert.Nil(t, err)
+
+	actions := make([]string, 0)
+	for _, entry := range entries {
+		actions = append(actions, entry.Action)
+	}
+	assert.Equal(t, []string{AuditActionCreate, AuditActionLabelChange, AuditActionLabelChange, AuditActionArchive, AuditActionUnarchive}, actions)
+}
@@ -0,0 +1,187 @@
+package v1
+
+import (
+	"database/sql"
+	"fmt"
+	"strings"
+
+	sq "github.com/Masterminds/squirrel"
+	"github.com/onepanelio/core/pkg/util"
+	"google.golang.org/grpc/codes"
+	"gopkg.in/yaml.v2"
+)
+
+// mergeWorkflowTemplateSpecs returns the base spec with the fields of override applied over it.
+//
+// Templates are merged by name: a template in override replaces the base template with the same name,
+// and other templates are added after the base templates. The parameters and artifacts of the arguments are merged the same way,
+// so a child template overrides a parameter's default by declaring the parameter again.
+// Any other field in override replaces the base field.
+func mergeWorkflowTemplateSpecs(base, override map[interface{}]interface{}) map[interface{}]interface{} {
+	result := make(map[interface{}]interface{}, len(base)+len(override))
+	for key, value := range base {
+		result[key] = value
+	}
+
+	for key, value := range override {
+		switch key {
+		case "templates":
+			result[key] = mergeNamedLists(result[key], value)
+		case "arguments":
+			baseArguments, baseOk := result[key].(map[interface{}]interface{})
+			overrideArguments, overrideOk := value.(map[interface{}]interface{})
+			if !baseOk || !overrideOk {
+				result[key] = value
+				continue
+			}
+
+			arguments := make(map[interface{}]interface{}, len(baseArguments)+len(overrideArguments))
+			for argumentKey, argumentValue := range baseArguments {
+				arguments[argumentKey] = argumentValue
+			}
+			for argumentKey, argumentValue := range overrideArguments {
+				arguments[argumentKey] = mergeNamedLists(arguments[argumentKey], argumentValue)
+			}
+			result[key] = arguments
+		default:
+			result[key] = value
+		}
+	}
+
+	return result
+}
+
+// mergeNamedLists merges two lists of items with a "name", see mergeWorkflowTemplateSpecs.
+// If either value is not a list, override is returned.
+func mergeNamedLists(base, override interface{}) interface{} {
+	baseList, baseOk := base.([]interface{})
+	overrideList, overrideOk := override.([]interface{})
+	if !baseOk || !overrideOk {
+		return override
+	}
+
+	result := make([]interface{}, len(baseList))
+	copy(result, baseList)
+
+	positions := make(map[string]int)
+	for i, item := range result {
+		if name, ok := itemName(item); ok {
+			positions[name] = i
+		}
+	}
+
+	for _, item := range overrideList {
+		if name, ok := itemName(item); ok {
+			if position, exists := positions[name]; exists {
+				result[position] = item
+				continue
+			}
+		}
+		result = append(result, item)
+	}
+
+	return result
+}
+
+// itemName returns the "name" of a list item, if it has one
+func itemName(item interface{}) (string, bool) {
+	itemMap, ok := item.(map[interface{}]interface{})
+	if !ok {
+		return "", false
+	}
+
+	name, ok := itemMap["name"]
+	if !ok {
+		return "", false
+	}
+
+	return fmt.Sprintf("%v", name), true
+}
+
+// getWorkflowTemplateBaseTemplateUID returns the uid of the base template of the non-archived workflow template with the uid
+func (c *Client) getWorkflowTemplateBaseTemplateUID(namespace, uid string) (baseTemplateUID string, err error) {
+	query := sb.Select("base_template_uid").
+		From("workflow_templates").
+		Where(sq.Eq{
+			"namespace":   namespace,
+			"uid":         uid,
+			"is_archived": false,
+		})
+	if err = c.DB.Getx(&baseTemplateUID, query); err == sql.ErrNoRows {
+		return "", util.NewUserError(codes.NotFound, fmt.Sprintf("Base workflow template '%v' not found.", uid))
+	}
+
+	return
+}
+
+// loadWorkflowTemplateBase sets the BaseManifest of the workflow template from the latest version of its base template,
+// so WrapSpec can merge them. The base template's own fragments and base are resolved first.
+// An InvalidArgument error is returned if the bases form a cycle.
+//
+// Changes to a base template are not propagated to existing versions of the workflow templates that extend it.
+// Each version keeps the base it was created with in its ResolvedManifest, and the next version uses the new base.
+func (c *Client) loadWorkflowTemplateBase(namespace string, workflowTemplate *WorkflowTemplate) error {
+	if workflowTemplate.BaseTemplateUID == "" || workflowTemplate.BaseManifest != "" {
+		return nil
+	}
+
+	chain := make([]string, 0)
+	if workflowTemplate.UID != "" {
+		chain = append(chain, workflowTemplate.UID)
+	}
+
+	baseManifest, err := c.resolveWorkflowTemplateBase(namespace, workflowTemplate.BaseTemplateUID, chain)
+	if err != nil {
+		return err
+	}
+	workflowTemplate.BaseManifest = baseManifest
+
+	return nil
+}
+
+// resolveWorkflowTemplateBase returns the manifest of the latest version of the base template, with its fragments and bases resolved.
+// chain has the uids of the workflow templates that extend the base, to detect cycles.
+func (c *Client) resolveWorkflowTemplateBase(namespace, uid string, chain []string) (string, error) {
+	for _, extendingUID := range chain {
+		if extendingUID == uid {
+			return "", util.NewUserError(codes.InvalidArgument, fmt.Sprintf("workflow template inheritance cycle: %v -> %v", strings.Join(chain, " -> "), uid))
+		}
+	}
+
+	baseTemplateUID, err := c.getWorkflowTemplateBaseTemplateUID(namespace, uid)
+	if err != nil {
+		return "", err
+	}
+
+	workflowTemplateVersion, err := c.getWorkflowTemplateVersionByUID(namespace, uid, 0)
+	if err != nil {
+		return "", err
+	}
+
+	base := &WorkflowTemplate{
+		UID:             uid,
+		Manifest:        workflowTemplateVersion.Manifest,
+		BaseTemplateUID: baseTemplateUID,
+	}
+	if err := c.loadWorkflowTemplateFragments(namespace, base); err != nil {
+		return "", err
+	}
+	if baseTemplateUID != "" {
+		base.BaseManifest, err = c.resolveWorkflowTemplateBase(namespace, baseTemplateUID, append(chain, uid))
+		if err != nil {
+			return "", err
+		}
+	}
+
+	spec, err := base.resolveSpec()
+	if err != nil {
+		return "", util.NewUserError(codes.InvalidArgument, fmt.Sprintf("base template '%v' is invalid: %v", uid, err))
+	}
+
+	manifest, err := yaml.Marshal(spec)
+	if err != nil {
+		return "", err
+	}
+
+	return string(manifest), nil
+}
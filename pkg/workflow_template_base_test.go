@@ -0,0 +1,143 @@
+package v1
+
+import (
+	"github.com/onepanelio/core/pkg/util"
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/grpc/codes"
+	"strings"
+	"testing"
+)
+
+const testBaseWorkflowTemplate = `entrypoint: main
+arguments:
+  parameters:
+  - name: epochs
+    value: "10"
+  - name: image
+    value: pytorch/pytorch:1.5
+templates:
+- name: main
+  container:
+    image: "{{workflow.parameters.image}}"
+    command: [train, "{{workflow.parameters.epochs}}"]
+- name: notify
+  container:
+    image: alpine
+`
+
+// TestWorkflowTemplate_WrapSpecWithBase makes sure the manifest overrides the base by template and parameter name
+func TestWorkflowTemplate_WrapSpecWithBase(t *testing.T) {
+	wt := &WorkflowTemplate{
+		BaseManifest: testBaseWorkflowTemplate,
+		Manifest: `arguments:
+  parameters:
+  - name: epochs
+    value: "1"
+  - name: debug
+    value: "true"
+templates:
+- name: notify
+  container:
+    image: busybox
+`,
+	}
+
+	argoWft, err := parseWorkflowTemplateSpec(wt)
+	assert.Nil(t, err)
+	assert.Equal(t, "main", argoWft.Spec.Entrypoint)
+
+	parameters := argoWft.Spec.Arguments.Parameters
+	assert.Len(t, parameters, 3)
+	assert.Equal(t, "epochs", parameters[0].Name)
+	assert.Equal(t, "1", *parameters[0].Value)
+	assert.Equal(t, "image", parameters[1].Name)
+	assert.Equal(t, "debug", parameters[2].Name)
+
+	templates := argoWft.Spec.Templates
+	assert.Len(t, templates, 2)
+	assert.Equal(t, "main", templates[0].Name)
+	assert.Equal(t, "notify", templates[1].Name)
+	assert.Equal(t, "busybox", templates[1].Container.Image)
+
+	keyString, err := wt.GetParametersKeyStringOrdered()
+	assert.Nil(t, err)
+	assert.Len(t, keyString, 3)
+}
+
+// TestClient_WorkflowTemplateBase makes sure a workflow template can extend another and that cycles are rejected
+func TestClient_WorkflowTemplateBase(t *testing.T) {
+	c := DefaultTestClient()
+	clearDatabase(t)
+
+	namespace := "onepanel"
+	base, err := c.CreateWorkflowTemplate(namespace, &WorkflowTemplate{
+		Name:     "base",
+		Manifest: testBaseWorkflowTemplate,
+	})
+	assert.Nil(t, err)
+
+	child, err := c.CreateWorkflowTemplate(namespace, &WorkflowTemplate{
+		Name:            "child",
+		BaseTemplateUID: base.UID,
+		Manifest: `arguments:
+  parameters:
+  - name: epochs
+    value: "1"
+`,
+	})
+	assert.Nil(t, err)
+	assert.Len(t, child.ArgoWorkflowTemplate.Spec.Templates, 2)
+
+	loaded, err := c.GetWorkflowTemplate(namespace, child.UID, 0)
+	assert.Nil(t, err)
+	assert.Equal(t, base.UID, loaded.BaseTemplateUID)
+
+	// a new version that does not set the base, like one created through the API, keeps it
+	childVersion, err := c.CreateWorkflowTemplateVersion(namespace, &WorkflowTemplate{
+		UID:  child.UID,
+		Name: child.Name,
+		Manifest: `arguments:
+  parameters:
+  - name: epochs
+    value: "2"
+`,
+	})
+	assert.Nil(t, err)
+	childVersionNumber := childVersion.Version
+	loaded, err = c.GetWorkflowTemplate(namespace, child.UID, 0)
+	assert.Nil(t, err)
+	assert.Equal(t, base.UID, loaded.BaseTemplateUID)
+
+	// changing the base does not change the existing versions of the child
+	_, err = c.CreateWorkflowTemplateVersion(namespace, &WorkflowTemplate{
+		UID:      base.UID,
+		Name:     base.Name,
+		Manifest: strings.Replace(testBaseWorkflowTemplate, "image: alpine", "image: busybox", 1),
+	})
+	assert.Nil(t, err)
+	matches, diff, err := c.WorkflowTemplateMatchesArgo(namespace, child.UID, childVersionNumber)
+	assert.Nil(t, err)
+	assert.True(t, matches, diff)
+
+	// the base now extends its own child
+	_, err = c.CreateWorkflowTemplateVersion(namespace, &WorkflowTemplate{
+		UID:             base.UID,
+		Name:            base.Name,
+		Manifest:        testBaseWorkflowTemplate,
+		BaseTemplateUID: child.UID,
+	})
+	userErr, ok := err.(*util.UserError)
+	assert.True(t, ok)
+	assert.Equal(t, codes.InvalidArgument, userErr.Code)
+	assert.Contains(t, userErr.Error(), "cycle")
+	// the base is only removed when asked for
+	_, err = c.CreateWorkflowTemplateVersionWithOptions(namespace, &WorkflowTemplate{
+		UID:      child.UID,
+		Name:     child.Name,
+		Manifest: testBaseWorkflowTemplate,
+	}, &WorkflowTemplateVersionOptions{ClearBaseTemplate: true})
+	assert.Nil(t, err)
+	loaded, err = c.GetWorkflowTemplate(namespace, child.UID, 0)
+	assert.Nil(t, err)
+	assert.Empty(t, loaded.BaseTemplateUID)
+}
@@ -0,0 +1,211 @@
+package v1
+
+import (
+	"sync"
+	"time"
+)
+
+// workflowTemplateCacheKey identifies a cached workflow template. Version 0 is the latest version.
+type workflowTemplateCacheKey struct {
+	namespace string
+	uid       string
+	version   int64
+}
+
+// workflowTemplateCacheEntry is a cached workflow template and when it stops being used
+type workflowTemplateCacheEntry struct {
+	workflowTemplate *WorkflowTemplate
+	expiresAt        time.Time
+}
+
+// WorkflowTemplateCacheStats are the counters of a WorkflowTemplateCache, so operators can tune its TTL.
+// A low hit rate means the TTL is too short for how often workflow templates are loaded.
+type WorkflowTemplateCacheStats struct {
+	Hits          uint64 // Loads answered from the cache.
+	Misses        uint64 // Loads that went to the database and argo, including expired entries.
+	Invalidations uint64 // Entries dropped by InvalidateWorkflowTemplateCache or a change made through the client.
+	Entries       int    // Entries in the cache, some may have expired.
+}
+
+// WorkflowTemplateCache caches the workflow templates loaded by GetWorkflowTemplate for a TTL,
+// so repeated loads of the same version do not call the database and argo each time.
+//
+// Changes made through a Client using the cache drop the affected entries. Changes made to the argo workflow templates
+// out-of-band are only seen once the entries expire, unless InvalidateWorkflowTemplateCache is called.
+// A cache can be shared by many clients.
+type WorkflowTemplateCache struct {
+	ttl     time.Duration
+	mutex   sync.Mutex
+	entries map[workflowTemplateCacheKey]*workflowTemplateCacheEntry
+	// generation is increased by each invalidation, so a load that started before it is not cached, see set.
+	generation    uint64
+	lastSweep     time.Time
+	hits          uint64
+	misses        uint64
+	invalidations uint64
+}
+
+// NewWorkflowTemplateCache returns an empty cache whose entries are used for the ttl
+func NewWorkflowTemplateCache(ttl time.Duration) *WorkflowTemplateCache {
+	return &WorkflowTemplateCache{
+		ttl:     ttl,
+		entries: make(map[workflowTemplateCacheKey]*workflowTemplateCacheEntry),
+	}
+}
+
+// get returns a copy of the cached workflow template, if there is one that has not expired
+func (wc *WorkflowTemplateCache) get(namespace, uid string, version int64) (*WorkflowTemplate, bool) {
+	wc.mutex.Lock()
+	defer wc.mutex.Unlock()
+
+	key := workflowTemplateCacheKey{namespace: namespace, uid: uid, version: version}
+	entry, ok := wc.entries[key]
+	if !ok || time.Now().After(entry.expiresAt) {
+		delete(wc.entries, key)
+		wc.misses++
+		return nil, false
+	}
+
+	wc.hits++
+
+	return copyWorkflowTemplate(entry.workflowTemplate), true
+}
+
+// currentGeneration returns the generation to pass to set for a workflow template that is about to be loaded
+func (wc *WorkflowTemplateCache) currentGeneration() uint64 {
+	wc.mutex.Lock()
+	defer wc.mutex.Unlock()
+
+	return wc.generation
+}
+
+// set caches a copy of the workflow template, so the caller can keep modifying it.
+// generation is the currentGeneration from before the workflow template was loaded. If there has been an invalidation
+// since, the loaded workflow template may already be stale and it is not cached.
+// Expired entries are swept at most once per ttl, so entries that are not loaded again do not pile up.
+func (wc *WorkflowTemplateCache) set(namespace, uid string, version int64, workflowTemplate *WorkflowTemplate, generation uint64) {
+	wc.mutex.Lock()
+	defer wc.mutex.Unlock()
+
+	if generation != wc.generation {
+		return
+	}
+
+	now := time.Now()
+	if now.Sub(wc.lastSweep) >= wc.ttl {
+		for key, entry := range wc.entries {
+			if now.After(entry.expiresAt) {
+				delete(wc.entries, key)
+			}
+		}
+		wc.lastSweep = now
+	}
+
+	key := workflowTemplateCacheKey{namespace: namespace, uid: uid, version: version}
+	wc.entries[key] = &workflowTemplateCacheEntry{
+		workflowTemplate: copyWorkflowTemplate(workflowTemplate),
+		expiresAt:        now.Add(wc.ttl),
+	}
+}
+
+// invalidate drops the cached versions of the workflow template with the uid.
+// If uid is empty, the cached workflow templates of the whole namespace are dropped.
+// If namespace is empty too, every cached workflow template is dropped.
+func (wc *WorkflowTemplateCache) invalidate(namespace, uid string) {
+	wc.mutex.Lock()
+	defer wc.mutex.Unlock()
+
+	wc.generation++
+	for key := range wc.entries {
+		if namespace == "" || (key.namespace == namespace && (uid == "" || key.uid == uid)) {
+			delete(wc.entries, key)
+			wc.invalidations++
+		}
+	}
+}
+
+// Stats returns the current counters of the cache
+func (wc *WorkflowTemplateCache) Stats() WorkflowTemplateCacheStats {
+	wc.mutex.Lock()
+	defer wc.mutex.Unlock()
+
+	return WorkflowTemplateCacheStats{
+		Hits:          wc.hits,
+		Misses:        wc.misses,
+		Invalidations: wc.invalidations,
+		Entries:       len(wc.entries),
+	}
+}
+
+// copyWorkflowTemplate returns a copy of the workflow template that does not share the maps, slices
+// or argo workflow template that callers modify
+func copyWorkflowTemplate(workflowTemplate *WorkflowTemplate) *WorkflowTemplate {
+	result := *workflowTemplate
+	if workflowTemplate.ArgoWorkflowTemplate != nil {
+		result.ArgoWorkflowTemplate = workflowTemplate.ArgoWorkflowTemplate.DeepCopy()
+	}
+	if workflowTemplate.Labels != nil {
+		result.Labels = make(map[string]string, len(workflowTemplate.Labels))
+		for key, value := range workflowTemplate.Labels {
+			result.Labels[key] = value
+		}
+	}
+	if workflowTemplate.Annotations != nil {
+		result.Annotations = make(map[string]string, len(workflowTemplate.Annotations))
+		for key, value := range workflowTemplate.Annotations {
+			result.Annotations[key] = value
+		}
+	}
+	if workflowTemplate.Fragments != nil {
+		result.Fragments = make(map[string]string, len(workflowTemplate.Fragments))
+		for key, value := range workflowTemplate.Fragments {
+			result.Fragments[key] = value
+		}
+	}
+	if workflowTemplate.Parameters != nil {
+		result.Parameters = make([]Parameter, len(workflowTemplate.Parameters))
+		copy(result.Parameters, workflowTemplate.Parameters)
+	}
+	if workflowTemplate.WorkflowExecutionStatisticReport != nil {
+		report := *workflowTemplate.WorkflowExecutionStatisticReport
+		result.WorkflowExecutionStatisticReport = &report
+	}
+	if workflowTemplate.CronWorkflowsStatisticsReport != nil {
+		report := *workflowTemplate.CronWorkflowsStatisticsReport
+		result.CronWorkflowsStatisticsReport = &report
+	}
+
+	return &result
+}
+
+// InvalidateWorkflowTemplateCache drops the cached versions of the workflow template with the uid, or of every
+// workflow template in the namespace if uid is empty, or of every namespace if both are empty.
+// Call it after changing argo workflow templates without the client,
+// so the next GetWorkflowTemplate loads them again. It does nothing if the client has no WorkflowTemplateCache.
+func (c *Client) InvalidateWorkflowTemplateCache(namespace, uid string) {
+	if c.WorkflowTemplateCache == nil {
+		return
+	}
+
+	c.WorkflowTemplateCache.invalidate(namespace, uid)
+}
+
+// getWorkflowTemplateCached is getWorkflowTemplate, answered from the client's WorkflowTemplateCache if it has one
+func (c *Client) getWorkflowTemplateCached(namespace, uid string, version int64) (*WorkflowTemplate, error) {
+	if c.WorkflowTemplateCache == nil {
+		return c.getWorkflowTemplate(namespace, uid, version)
+	}
+
+	if workflowTemplate, ok := c.WorkflowTemplateCache.get(namespace, uid, version); ok {
+		return workflowTemplate, nil
+	}
+
+	generation := c.WorkflowTemplateCache.currentGeneration()
+	workflowTemplate, err := c.getWorkflowTemplate(namespace, uid, version)
+	if err != nil || workflowTemplate == nil {
+		return workflowTemplate, err
+	}
+	c.WorkflowTemplateCache.set(namespace, uid, version, workflowTemplate, generation)
+
+	return workflowTemplate, nil
+}
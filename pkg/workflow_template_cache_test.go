@@ -0,0 +1,145 @@
+package v1
+
+import (
+	"testing"
+	"time"
+
+	"github.com/argoproj/argo/pkg/apis/workflow/v1alpha1"
+	"github.com/stretchr/testify/assert"
+)
+
+// TestWorkflowTemplateCache makes sure entries are copied, expire, are invalidated and counted
+func TestWorkflowTemplateCache(t *testing.T) {
+	cache := NewWorkflowTemplateCache(time.Minute)
+
+	_, ok := cache.get("onepanel", "train", 0)
+	assert.False(t, ok)
+
+	workflowTemplate := &WorkflowTemplate{
+		UID:                  "train",
+		Labels:               map[string]string{"team": "vision"},
+		ArgoWorkflowTemplate: &v1alpha1.WorkflowTemplate{},
+	}
+	cache.set("onepanel", "train", 0, workflowTemplate, 0)
+	cache.set("onepanel", "train", 1, workflowTemplate, 0)
+	cache.set("onepanel", "tune", 0, workflowTemplate, 0)
+	cache.set("other", "train", 0, workflowTemplate, 0)
+
+	// callers can not change the cached workflow template
+	workflowTemplate.Labels["team"] = "nlp"
+	cached, ok := cache.get("onepanel", "train", 0)
+	assert.True(t, ok)
+	assert.Equal(t, "vision", cached.Labels["team"])
+	cached.ArgoWorkflowTemplate.Name = "changed"
+	cached, _ = cache.get("onepanel", "train", 0)
+	assert.Equal(t, "", cached.ArgoWorkflowTemplate.Name)
+
+	cache.invalidate("onepanel", "train")
+	_, ok = cache.get("onepanel", "train", 1)
+	assert.False(t, ok)
+	_, ok = cache.get("onepanel", "tune", 0)
+	assert.True(t, ok)
+
+	cache.invalidate("onepanel", "")
+	_, ok = cache.get("onepanel", "tune", 0)
+	assert.False(t, ok)
+	_, ok = cache.get("other", "train", 0)
+	assert.True(t, ok)
+
+	assert.Equal(t, WorkflowTemplateCacheStats{
+		Hits:          4,
+		Misses:        3,
+		Invalidations: 3,
+		Entries:       1,
+	}, cache.Stats())
+
+	expired := NewWorkflowTemplateCache(0)
+	expired.set("onepanel", "train", 0, workflowTemplate, 0)
+	time.Sleep(time.Millisecond)
+	_, ok = expired.get("onepanel", "train", 0)
+	assert.False(t, ok)
+	assert.Equal(t, 0, expired.Stats().Entries)
+
+	// expired entries that are not loaded again are swept when others are cached
+	expired.set("onepanel", "train", 0, workflowTemplate, 0)
+	time.Sleep(time.Millisecond)
+	expired.set("onepanel", "tune", 0, workflowTemplate, 0)
+	assert.Equal(t, 1, expired.Stats().Entries)
+}
+
+// TestWorkflowTemplateCache_StaleSet makes sure a workflow template loaded before an invalidation is not cached
+func TestWorkflowTemplateCache_StaleSet(t *testing.T) {
+	cache := NewWorkflowTemplateCache(time.Minute)
+	workflowTemplate := &WorkflowTemplate{UID: "train"}
+
+	generation := cache.currentGeneration()
+	// the workflow template changes while it is being loaded
+	cache.invalidate("onepanel", "train")
+	cache.set("onepanel", "train", 0, workflowTemplate, generation)
+	_, ok := cache.get("onepanel", "train", 0)
+	assert.False(t, ok)
+
+	cache.set("onepanel", "train", 0, workflowTemplate, cache.currentGeneration())
+	_, ok = cache.get("onepanel", "train", 0)
+	assert.True(t, ok)
+
+	cache.invalidate("", "")
+	assert.Equal(t, 0, cache.Stats().Entries)
+}
+
+// TestClient_GetWorkflowTemplate_Cache makes sure cached workflow templates are used until they are invalidated
+func TestClient_GetWorkflowTemplate_Cache(t *testing.T) {
+	c := DefaultTestClient()
+	clearDatabase(t)
+	c.WorkflowTemplateCache = NewWorkflowTemplateCache(time.Minute)
+	defer func() { c.WorkflowTemplateCache = nil }()
+
+	namespace := "onepanel"
+	created, err := c.CreateWorkflowTemplate(namespace, &WorkflowTemplate{
+		Name:     "test",
+		Manifest: defaultWorkflowTemplate,
+	})
+	assert.Nil(t, err)
+
+	first, err := c.GetWorkflowTemplate(namespace, created.UID, 0)
+	assert.Nil(t, err)
+	_, err = c.GetWorkflowTemplate(namespace, created.UID, 0)
+	assert.Nil(t, err)
+	stats := c.WorkflowTemplateCache.Stats()
+	assert.Equal(t, uint64(1), stats.Hits)
+	assert.Equal(t, uint64(1), stats.Misses)
+
+	// a new version drops the cached latest version
+	_, err = c.CreateWorkflowTemplateVersion(namespace, &WorkflowTemplate{
+		UID:      created.UID,
+		Name:     created.Name,
+		Manifest: defaultWorkflowTemplate,
+	})
+	assert.Nil(t, err)
+	latest, err := c.GetWorkflowTemplate(namespace, created.UID, 0)
+	assert.Nil(t, err)
+	assert.NotEqual(t, first.Version, latest.Version)
+
+	// label changes drop the cached workflow template too
+	_, err = c.SetWorkflowTemplateLabels(namespace, created.UID, "tags.onepanel.io/", map[string]string{"a": "b"}, false)
+	assert.Nil(t, err)
+	assert.Equal(t, 0, c.WorkflowTemplateCache.Stats().Entries)
+
+	_, err = c.GetWorkflowTemplate(namespace, created.UID, 0)
+	assert.Nil(t, err)
+	c.InvalidateWorkflowTemplateCache(namespace, created.UID)
+	assert.Equal(t, 0, c.WorkflowTemplateCache.Stats().Entries)
+
+	// creating a workflow template drops a cached one with the same uid, like one archived without the client
+	_, err = c.GetWorkflowTemplate(namespace, created.UID, 0)
+	assert.Nil(t, err)
+	_, err = c.DB.Exec(`UPDATE workflow_templates SET is_archived = true WHERE uid = $1`, created.UID)
+	assert.Nil(t, err)
+	recreated, err := c.CreateWorkflowTemplate(namespace, &WorkflowTemplate{
+		Name:     "test",
+		Manifest: defaultWorkflowTemplate,
+	})
+	assert.Nil(t, err)
+	assert.Equal(t, created.UID, recreated.UID)
+	assert.Equal(t, 0, c.WorkflowTemplateCache.Stats().Entries)
+}
@@ -0,0 +1,157 @@
+package v1
+
+import (
+	"fmt"
+	"strconv"
+
+	"github.com/argoproj/argo/pkg/apis/workflow/v1alpha1"
+	"github.com/onepanelio/core/pkg/util"
+	"google.golang.org/grpc/codes"
+)
+
+// templateComplexity is the estimated complexity of running a single template
+type templateComplexity struct {
+	steps          int
+	maxParallelism int
+	pods           int
+}
+
+// complexityEstimator walks the templates of a workflow spec, starting at the entrypoint.
+// The complexity of each template is computed once, so templates referenced from many places do not make
+// the walk exponential.
+type complexityEstimator struct {
+	templates map[string]*v1alpha1.Template
+	visiting  map[string]bool
+	estimated map[string]templateComplexity
+}
+
+// EstimateWorkflowTemplateComplexity returns rough counts of what running the workflow template would take:
+// the number of steps and dag tasks executed, the most pods running at once and the total number of pods.
+//
+// Loops are expanded with withItems and literal withSequence counts. A withParam loop, or a sequence that is only known
+// at runtime, is counted as a single item. Templates referenced from other workflow templates and recursive
+// references are counted as a single pod. Parallelism limits set on the workflow or on a template are applied.
+func EstimateWorkflowTemplateComplexity(wt *WorkflowTemplate) (steps int, maxParallelism int, estimatedPods int, err error) {
+	argoWft, err := parseWorkflowTemplateSpec(wt)
+	if err != nil {
+		return 0, 0, 0, util.NewUserError(codes.InvalidArgument, fmt.Sprintf("unable to parse manifest: %v", err))
+	}
+
+	estimator := &complexityEstimator{
+		templates: make(map[string]*v1alpha1.Template),
+		visiting:  make(map[string]bool),
+		estimated: make(map[string]templateComplexity),
+	}
+	for i := range argoWft.Spec.Templates {
+		template := &argoWft.Spec.Templates[i]
+		estimator.templates[template.Name] = template
+	}
+
+	if _, ok := estimator.templates[argoWft.Spec.Entrypoint]; !ok {
+		return 0, 0, 0, util.NewUserError(codes.InvalidArgument, fmt.Sprintf("entrypoint '%v' is not a template", argoWft.Spec.Entrypoint))
+	}
+
+	result := estimator.estimate(argoWft.Spec.Entrypoint)
+	result.maxParallelism = limitParallelism(result.maxParallelism, argoWft.Spec.Parallelism)
+
+	return result.steps, result.maxParallelism, result.pods, nil
+}
+
+// estimate returns the complexity of running the named template once
+func (e *complexityEstimator) estimate(name string) templateComplexity {
+	template, ok := e.templates[name]
+	if !ok || e.visiting[name] {
+		return templateComplexity{maxParallelism: 1, pods: 1}
+	}
+
+	if result, ok := e.estimated[name]; ok {
+		return result
+	}
+
+	e.visiting[name] = true
+	defer delete(e.visiting, name)
+
+	result := templateComplexity{}
+	switch {
+	case template.Steps != nil:
+		for _, parallelSteps := range template.Steps {
+			groupParallelism := 0
+			for _, step := range parallelSteps.Steps {
+				count := loopCount(step.WithItems, step.WithSequence)
+				child := e.estimateReference(step.Template, step.TemplateRef)
+
+				result.steps += count * (child.steps + 1)
+				result.pods += count * child.pods
+				groupParallelism += count * child.maxParallelism
+			}
+			if groupParallelism > result.maxParallelism {
+				result.maxParallelism = groupParallelism
+			}
+		}
+	case template.DAG != nil:
+		// Without resolving dependencies, assume every task can run at the same time
+		for _, task := range template.DAG.Tasks {
+			count := loopCount(task.WithItems, task.WithSequence)
+			child := e.estimateReference(task.Template, task.TemplateRef)
+
+			result.steps += count * (child.steps + 1)
+			result.pods += count * child.pods
+			result.maxParallelism += count * child.maxParallelism
+		}
+	case template.Suspend != nil:
+		// suspend templates do not run a pod
+	default:
+		result.maxParallelism = 1
+		result.pods = 1
+	}
+
+	result.maxParallelism = limitParallelism(result.maxParallelism, template.Parallelism)
+	e.estimated[name] = result
+
+	return result
+}
+
+// estimateReference returns the complexity of a step or task's template
+func (e *complexityEstimator) estimateReference(name string, templateRef *v1alpha1.TemplateRef) templateComplexity {
+	if templateRef != nil {
+		return templateComplexity{maxParallelism: 1, pods: 1}
+	}
+
+	return e.estimate(name)
+}
+
+// loopCount returns how many times a step or task runs, see EstimateWorkflowTemplateComplexity
+func loopCount(items []v1alpha1.Item, sequence *v1alpha1.Sequence) int {
+	if len(items) > 0 {
+		return len(items)
+	}
+
+	if sequence != nil {
+		if count, err := strconv.Atoi(sequence.Count); err == nil && count > 0 {
+			return count
+		}
+
+		start, startErr := strconv.Atoi(sequence.Start)
+		if sequence.Start == "" {
+			start, startErr = 0, nil
+		}
+		end, endErr := strconv.Atoi(sequence.End)
+		if startErr == nil && endErr == nil {
+			if end < start {
+				return start - end + 1
+			}
+			return end - start + 1
+		}
+	}
+
+	return 1
+}
+
+// limitParallelism caps parallelism to the limit, if there is one
+func limitParallelism(parallelism int, limit *int64) int {
+	if limit != nil && *limit > 0 && int64(parallelism) > *limit {
+		return int(*limit)
+	}
+
+	return parallelism
+}
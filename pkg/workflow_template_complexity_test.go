@@ -0,0 +1,90 @@
+package v1
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestEstimateWorkflowTemplateComplexity(t *testing.T) {
+	manifest := `entrypoint: main
+templates:
+- name: main
+  steps:
+  - - name: prepare
+      template: work
+  - - name: train
+      template: work
+      withItems: [1, 2, 3]
+    - name: evaluate
+      template: pipeline
+  - - name: approve
+      template: approve
+- name: pipeline
+  dag:
+    tasks:
+    - name: shard
+      template: work
+      withSequence:
+        count: "10"
+    - name: merge
+      template: work
+      dependencies: [shard]
+- name: approve
+  suspend: {}
+- name: work
+  container:
+    image: alpine
+`
+	steps, maxParallelism, estimatedPods, err := EstimateWorkflowTemplateComplexity(&WorkflowTemplate{Manifest: manifest})
+	assert.Nil(t, err)
+	// prepare, train x3, evaluate with its 11 tasks, approve
+	assert.Equal(t, 17, steps)
+	// train x3 next to the dag, which is assumed to run all of its 11 pods at once
+	assert.Equal(t, 14, maxParallelism)
+	assert.Equal(t, 15, estimatedPods)
+
+	limited := "parallelism: 5\n" + manifest
+	_, maxParallelism, _, err = EstimateWorkflowTemplateComplexity(&WorkflowTemplate{Manifest: limited})
+	assert.Nil(t, err)
+	assert.Equal(t, 5, maxParallelism)
+
+	_, _, _, err = EstimateWorkflowTemplateComplexity(&WorkflowTemplate{Manifest: "entrypoint: missing\ntemplates: []"})
+	assert.NotNil(t, err)
+}
+
+// TestEstimateWorkflowTemplateComplexity_Shared makes sure templates referenced many times are estimated once,
+// and recursive templates still stop
+func TestEstimateWorkflowTemplateComplexity_Shared(t *testing.T) {
+	// each level runs the next one twice, so there are 2^40 pods
+	levels := 40
+	manifest := strings.Builder{}
+	manifest.WriteString("entrypoint: level-0\ntemplates:\n")
+	for i := 0; i < levels; i++ {
+		manifest.WriteString(fmt.Sprintf(`- name: level-%v
+  steps:
+  - - name: first
+      template: level-%v
+  - - name: second
+      template: level-%v
+`, i, i+1, i+1))
+	}
+	manifest.WriteString(fmt.Sprintf("- name: level-%v\n  container:\n    image: alpine\n", levels))
+
+	_, _, estimatedPods, err := EstimateWorkflowTemplateComplexity(&WorkflowTemplate{Manifest: manifest.String()})
+	assert.Nil(t, err)
+	assert.Equal(t, 1<<levels, estimatedPods)
+
+	recursive := `entrypoint: main
+templates:
+- name: main
+  steps:
+  - - name: again
+      template: main
+`
+	_, _, estimatedPods, err = EstimateWorkflowTemplateComplexity(&WorkflowTemplate{Manifest: recursive})
+	assert.Nil(t, err)
+	assert.Equal(t, 1, estimatedPods)
+}
@@ -0,0 +1,234 @@
+package v1
+
+import (
+	"bytes"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	sq "github.com/Masterminds/squirrel"
+	"github.com/argoproj/argo/pkg/apis/workflow/v1alpha1"
+	"github.com/ghodss/yaml"
+	"github.com/onepanelio/core/pkg/util"
+	log "github.com/sirupsen/logrus"
+	"google.golang.org/grpc/codes"
+)
+
+// WorkflowTemplateFragment is a named sub-manifest of a workflow template.
+// The manifest is a single argo template, which the workflow template's manifest includes with a "- fragment: <name>" templates entry.
+type WorkflowTemplateFragment struct {
+	ID         uint64
+	CreatedAt  time.Time  `db:"created_at"`
+	ModifiedAt *time.Time `db:"modified_at"`
+	Name       string
+	Manifest   string
+}
+
+// validate returns an error if the fragment has no name or the manifest is not an argo template.
+// Unknown fields are errors, so a misspelled field is not silently dropped.
+func (f *WorkflowTemplateFragment) validate() error {
+	if f.Name == "" {
+		return fmt.Errorf("fragment name is required")
+	}
+
+	manifest, err := yaml.YAMLToJSON([]byte(f.Manifest))
+	if err != nil {
+		return fmt.Errorf("fragment '%v' is not a valid template: %v", f.Name, err)
+	}
+
+	decoder := json.NewDecoder(bytes.NewReader(manifest))
+	decoder.DisallowUnknownFields()
+
+	template := &v1alpha1.Template{}
+	if err := decoder.Decode(template); err != nil {
+		return fmt.Errorf("fragment '%v' is not a valid template: %v", f.Name, strings.TrimPrefix(err.Error(), "json: "))
+	}
+
+	return nil
+}
+
+// workflowTemplateFragmentsSelectBuilder selects the fragments of the non-archived workflow template with the uid
+func workflowTemplateFragmentsSelectBuilder(namespace, uid string) sq.SelectBuilder {
+	return sb.Select("f.id", "f.created_at", "f.modified_at", "f.name", "f.manifest").
+		From("workflow_template_fragments f").
+		Join("workflow_templates wt ON wt.id = f.workflow_template_id").
+		Where(sq.Eq{
+			"wt.namespace":   namespace,
+			"wt.uid":         uid,
+			"wt.is_archived": false,
+		})
+}
+
+// getWorkflowTemplateID returns the id of the non-archived workflow template with the uid
+func (c *Client) getWorkflowTemplateID(namespace, uid string) (id uint64, err error) {
+	query := sb.Select("id").
+		From("workflow_templates").
+		Where(sq.Eq{
+			"namespace":   namespace,
+			"uid":         uid,
+			"is_archived": false,
+		})
+	if err = c.DB.Getx(&id, query); err == sql.ErrNoRows {
+		return 0, util.NewUserError(codes.NotFound, "Workflow template not found.")
+	}
+
+	return
+}
+
+// CreateWorkflowTemplateFragment adds the fragment to the non-archived workflow template with the uid.
+// The fragment name must be unique for the workflow template.
+func (c *Client) CreateWorkflowTemplateFragment(namespace, uid string, fragment *WorkflowTemplateFragment) (*WorkflowTemplateFragment, error) {
+	defer c.InvalidateWorkflowTemplateCache(namespace, uid)
+
+	if err := fragment.validate(); err != nil {
+		return nil, util.NewUserError(codes.InvalidArgument, err.Error())
+	}
+
+	workflowTemplateID, err := c.getWorkflowTemplateID(namespace, uid)
+	if err != nil {
+		return nil, err
+	}
+
+	err = sb.Insert("workflow_template_fragments").
+		SetMap(sq.Eq{
+			"workflow_template_id": workflowTemplateID,
+			"name":                 fragment.Name,
+			"manifest":             fragment.Manifest,
+		}).
+		Suffix("RETURNING id, created_at").
+		RunWith(c.DB).
+		QueryRow().
+		Scan(&fragment.ID, &fragment.CreatedAt)
+	if err != nil {
+		log.WithFields(log.Fields{
+			"Namespace": namespace,
+			"UID":       uid,
+			"Fragment":  fragment.Name,
+			"Error":     err.Error(),
+		}).Error("Could not create workflow template fragment.")
+		return nil, util.NewUserErrorWrap(err, "Fragment")
+	}
+
+	return fragment, nil
+}
+
+// GetWorkflowTemplateFragment returns the fragment with the name of the non-archived workflow template with the uid
+func (c *Client) GetWorkflowTemplateFragment(namespace, uid, name string) (*WorkflowTemplateFragment, error) {
+	query := workflowTemplateFragmentsSelectBuilder(namespace, uid).
+		Where(sq.Eq{
+			"f.name": name,
+		})
+
+	fragment := &WorkflowTemplateFragment{}
+	if err := c.DB.Getx(fragment, query); err != nil {
+		if err == sql.ErrNoRows {
+			return nil, util.NewUserError(codes.NotFound, "Fragment not found.")
+		}
+		return nil, err
+	}
+
+	return fragment, nil
+}
+
+// ListWorkflowTemplateFragments returns the fragments of the non-archived workflow template with the uid, by name
+func (c *Client) ListWorkflowTemplateFragments(namespace, uid string) (fragments []*WorkflowTemplateFragment, err error) {
+	fragments = make([]*WorkflowTemplateFragment, 0)
+
+	query := workflowTemplateFragmentsSelectBuilder(namespace, uid).
+		OrderBy("f.name")
+	err = c.DB.Selectx(&fragments, query)
+
+	return
+}
+
+// UpdateWorkflowTemplateFragment replaces the manifest of the fragment with the name.
+// Existing versions are not changed, the new manifest is used by versions created afterwards.
+func (c *Client) UpdateWorkflowTemplateFragment(namespace, uid, name, manifest string) error {
+	defer c.InvalidateWorkflowTemplateCache(namespace, uid)
+
+	fragment := &WorkflowTemplateFragment{
+		Name:     name,
+		Manifest: manifest,
+	}
+	if err := fragment.validate(); err != nil {
+		return util.NewUserError(codes.InvalidArgument, err.Error())
+	}
+
+	workflowTemplateID, err := c.getWorkflowTemplateID(namespace, uid)
+	if err != nil {
+		return err
+	}
+
+	result, err := sb.Update("workflow_template_fragments").
+		Set("manifest", manifest).
+		Set("modified_at", time.Now().UTC()).
+		Where(sq.Eq{
+			"workflow_template_id": workflowTemplateID,
+			"name":                 name,
+		}).
+		RunWith(c.DB).
+		Exec()
+	if err != nil {
+		return err
+	}
+
+	if rowsAffected, err := result.RowsAffected(); err != nil {
+		return err
+	} else if rowsAffected == 0 {
+		return util.NewUserError(codes.NotFound, "Fragment not found.")
+	}
+
+	return nil
+}
+
+// DeleteWorkflowTemplateFragment deletes the fragment with the name.
+// Existing versions are not changed, as fragments are resolved when a version is created.
+func (c *Client) DeleteWorkflowTemplateFragment(namespace, uid, name string) error {
+	defer c.InvalidateWorkflowTemplateCache(namespace, uid)
+
+	workflowTemplateID, err := c.getWorkflowTemplateID(namespace, uid)
+	if err != nil {
+		return err
+	}
+
+	result, err := sb.Delete("workflow_template_fragments").
+		Where(sq.Eq{
+			"workflow_template_id": workflowTemplateID,
+			"name":                 name,
+		}).
+		RunWith(c.DB).
+		Exec()
+	if err != nil {
+		return err
+	}
+
+	if rowsAffected, err := result.RowsAffected(); err != nil {
+		return err
+	} else if rowsAffected == 0 {
+		return util.NewUserError(codes.NotFound, "Fragment not found.")
+	}
+
+	return nil
+}
+
+// loadWorkflowTemplateFragments sets the Fragments of the workflow template from the database, so WrapSpec can resolve them.
+// Fragments that are already set, or templates without a uid, are left as is.
+func (c *Client) loadWorkflowTemplateFragments(namespace string, workflowTemplate *WorkflowTemplate) error {
+	if workflowTemplate.Fragments != nil || workflowTemplate.UID == "" {
+		return nil
+	}
+
+	fragments, err := c.ListWorkflowTemplateFragments(namespace, workflowTemplate.UID)
+	if err != nil {
+		return err
+	}
+
+	workflowTemplate.Fragments = make(map[string]string)
+	for _, fragment := range fragments {
+		workflowTemplate.Fragments[fragment.Name] = fragment.Manifest
+	}
+
+	return nil
+}
@@ -0,0 +1,100 @@
+package v1
+
+import (
+	"github.com/onepanelio/core/pkg/util"
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/grpc/codes"
+	"testing"
+)
+
+const testFragment = `name: main
+container:
+  image: alpine
+  command: [echo, hello]
+`
+
+func TestClient_WorkflowTemplateFragments(t *testing.T) {
+	c := DefaultTestClient()
+	clearDatabase(t)
+
+	namespace := "onepanel"
+	created, _ := c.CreateWorkflowTemplate(namespace, &WorkflowTemplate{
+		Name:     "test",
+		Manifest: defaultWorkflowTemplate,
+	})
+
+	_, err := c.CreateWorkflowTemplateFragment(namespace, created.UID, &WorkflowTemplateFragment{
+		Name:     "main",
+		Manifest: testFragment,
+	})
+	assert.Nil(t, err)
+
+	_, err = c.CreateWorkflowTemplateFragment(namespace, created.UID, &WorkflowTemplateFragment{
+		Name:     "main",
+		Manifest: testFragment,
+	})
+	userErr, ok := err.(*util.UserError)
+	assert.True(t, ok)
+	assert.Equal(t, codes.AlreadyExists, userErr.Code)
+
+	fragment, err := c.GetWorkflowTemplateFragment(namespace, created.UID, "main")
+	assert.Nil(t, err)
+	assert.Equal(t, testFragment, fragment.Manifest)
+
+	withFragment, err := c.CreateWorkflowTemplateVersion(namespace, &WorkflowTemplate{
+		UID:  created.UID,
+		Name: created.Name,
+		Manifest: `entrypoint: main
+templates:
+- fragment: main
+`,
+	})
+	assert.Nil(t, err)
+	withFragmentVersion := withFragment.Version
+
+	updatedFragment := testFragment + "  args: [world]\n"
+	assert.Nil(t, c.UpdateWorkflowTemplateFragment(namespace, created.UID, "main", updatedFragment))
+
+	// the version keeps the fragment it was created with
+	workflowTemplateVersion, err := c.getWorkflowTemplateVersionByUID(namespace, created.UID, withFragmentVersion)
+	assert.Nil(t, err)
+	assert.Contains(t, workflowTemplateVersion.ResolvedManifest, "hello")
+	assert.NotContains(t, workflowTemplateVersion.ResolvedManifest, "world")
+
+	// so changing the fragment does not look like the argo workflow template was edited
+	matches, diff, err := c.WorkflowTemplateMatchesArgo(namespace, created.UID, withFragmentVersion)
+	assert.Nil(t, err)
+	assert.True(t, matches, diff)
+
+	// clones do not have the fragments, so they get the resolved manifest
+	cloned, err := c.CloneWorkflowTemplateVersion(namespace, created.UID, withFragmentVersion, namespace, "test-clone")
+	assert.Nil(t, err)
+	assert.Contains(t, cloned.Manifest, "hello")
+	assert.NotContains(t, cloned.Manifest, "fragment:")
+
+	fragments, err := c.ListWorkflowTemplateFragments(namespace, created.UID)
+	assert.Nil(t, err)
+	assert.Len(t, fragments, 1)
+	assert.Equal(t, updatedFragment, fragments[0].Manifest)
+
+	assert.Nil(t, c.DeleteWorkflowTemplateFragment(namespace, created.UID, "main"))
+	err = c.DeleteWorkflowTemplateFragment(namespace, created.UID, "main")
+	userErr, ok = err.(*util.UserError)
+	assert.True(t, ok)
+	assert.Equal(t, codes.NotFound, userErr.Code)
+}
+
+// TestWorkflowTemplateFragment_validate makes sure fragments are decoded strictly
+func TestWorkflowTemplateFragment_validate(t *testing.T) {
+	assert.Nil(t, (&WorkflowTemplateFragment{Name: "main", Manifest: testFragment}).validate())
+
+	err := (&WorkflowTemplateFragment{Name: "main", Manifest: testFragment + "  imagePullPolicy: Always\n"}).validate()
+	assert.Nil(t, err)
+
+	err = (&WorkflowTemplateFragment{Name: "main", Manifest: testFragment + "retry: 3\n"}).validate()
+	assert.NotNil(t, err)
+	assert.Contains(t, err.Error(), "unknown field \"retry\"")
+
+	err = (&WorkflowTemplateFragment{Manifest: testFragment}).validate()
+	assert.NotNil(t, err)
+}
@@ -0,0 +1,200 @@
+package v1
+
+import (
+	"database/sql"
+	"fmt"
+
+	sq "github.com/Masterminds/squirrel"
+	"github.com/onepanelio/core/pkg/util"
+	"github.com/onepanelio/core/pkg/util/mapping"
+	"google.golang.org/grpc/codes"
+)
+
+// Strategies for importing a workflow template whose name is already used by a non-archived workflow template.
+const (
+	// ImportStrategyFail returns an AlreadyExists error. This is the default.
+	ImportStrategyFail = "fail"
+	// ImportStrategyNewVersion adds the manifest as a new version of the existing workflow template.
+	ImportStrategyNewVersion = "new-version"
+	// ImportStrategyRename creates a new workflow template with a numeric suffix added to the name.
+	ImportStrategyRename = "rename"
+)
+
+// Outcomes of an import, in addition to the strategies, reported in ImportResult.
+const (
+	// ImportResultCreated means there was no conflict and the workflow template was created as is.
+	ImportResultCreated = "created"
+	// ImportResultUnchanged means an existing workflow template already has the manifest, so nothing was created.
+	// This is what makes re-running an import idempotent.
+	ImportResultUnchanged = "unchanged"
+)
+
+// ImportResult is the outcome of importing a single workflow template
+type ImportResult struct {
+	WorkflowTemplate *WorkflowTemplate
+	Applied          string // ImportResultCreated, ImportResultUnchanged, ImportStrategyNewVersion or ImportStrategyRename
+}
+
+// getWorkflowTemplateUIDByName returns the uid of the newest non-archived workflow template with the name,
+// or an empty string if there is none.
+func (c *Client) getWorkflowTemplateUIDByName(namespace, name string) (uid string, err error) {
+	query := sb.Select("uid").
+		From("workflow_templates").
+		Where(sq.Eq{
+			"namespace":   namespace,
+			"name":        name,
+			"is_archived": false,
+		}).
+		OrderBy("created_at DESC").
+		Limit(1)
+
+	if err = c.DB.Getx(&uid, query); err == sql.ErrNoRows {
+		return "", nil
+	}
+
+	return
+}
+
+// manifestsEqual reports if the manifests have the same content, ignoring the key order and formatting of the yaml.
+func manifestsEqual(a, b string) bool {
+	mappingA, errA := mapping.NewFromYamlString(a)
+	mappingB, errB := mapping.NewFromYamlString(b)
+	if errA != nil || errB != nil {
+		return a == b
+	}
+
+	dataA, errA := mappingA.ToYamlBytes()
+	dataB, errB := mappingB.ToYamlBytes()
+	if errA != nil || errB != nil {
+		return a == b
+	}
+
+	return string(dataA) == string(dataB)
+}
+
+// getLatestWorkflowTemplateWithManifest returns the latest version of the workflow template if it has the manifest,
+// or nil if it does not.
+func (c *Client) getLatestWorkflowTemplateWithManifest(namespace, uid, manifest string) (*WorkflowTemplate, error) {
+	latest, err := c.GetLatestWorkflowTemplate(namespace, uid)
+	if err != nil {
+		return nil, err
+	}
+	if !manifestsEqual(latest.Manifest, manifest) {
+		return nil, nil
+	}
+
+	return latest, nil
+}
+
+// availableImportName returns the name with the lowest numeric suffix, like "name-2", that no non-archived
+// workflow template in the namespace uses. The name is shortened, if needed, to fit the 30 character limit.
+// If a workflow template with one of the suffixed names already has the manifest, it is returned instead,
+// as it was created by an earlier import of the same workflow template.
+func (c *Client) availableImportName(namespace, name, manifest string) (string, *WorkflowTemplate, error) {
+	for i := 2; ; i++ {
+		suffix := fmt.Sprintf("-%v", i)
+		base := name
+		if maxLength := 30 - len(suffix); len(base) > maxLength {
+			base = base[:maxLength]
+		}
+
+		candidate := base + suffix
+		uid, err := c.getWorkflowTemplateUIDByName(namespace, candidate)
+		if err != nil {
+			return "", nil, util.NewUserErrorWrap(err, "Workflow template")
+		}
+		if uid == "" {
+			return candidate, nil, nil
+		}
+
+		imported, err := c.getLatestWorkflowTemplateWithManifest(namespace, uid, manifest)
+		if err != nil {
+			return "", nil, err
+		}
+		if imported != nil {
+			return "", imported, nil
+		}
+	}
+}
+
+// ImportWorkflowTemplate creates the workflow template, using strategy to resolve a conflict with the name of an
+// existing non-archived workflow template. An empty strategy is ImportStrategyFail.
+//
+// Importing is idempotent: with the new-version and rename strategies, a workflow template that already has the
+// manifest, ignoring the server managed fields and yaml formatting, is reported as ImportResultUnchanged.
+func (c *Client) ImportWorkflowTemplate(namespace string, workflowTemplate *WorkflowTemplate, strategy string) (*ImportResult, error) {
+	if strategy == "" {
+		strategy = ImportStrategyFail
+	}
+	if strategy != ImportStrategyFail && strategy != ImportStrategyNewVersion && strategy != ImportStrategyRename {
+		return nil, util.NewUserError(codes.InvalidArgument, fmt.Sprintf("Unknown import strategy '%v'.", strategy))
+	}
+
+	if err := workflowTemplate.StripServerManagedFields(); err != nil {
+		return nil, util.NewUserError(codes.InvalidArgument, err.Error())
+	}
+
+	existingUID, err := c.getWorkflowTemplateUIDByName(namespace, workflowTemplate.Name)
+	if err != nil {
+		return nil, util.NewUserErrorWrap(err, "Workflow template")
+	}
+
+	if existingUID == "" {
+		created, err := c.CreateWorkflowTemplate(namespace, workflowTemplate)
+		if err != nil {
+			return nil, err
+		}
+		return &ImportResult{WorkflowTemplate: created, Applied: ImportResultCreated}, nil
+	}
+
+	if strategy == ImportStrategyFail {
+		return nil, util.NewUserError(codes.AlreadyExists, fmt.Sprintf("Workflow template '%v' already exists.", workflowTemplate.Name))
+	}
+
+	unchanged, err := c.getLatestWorkflowTemplateWithManifest(namespace, existingUID, workflowTemplate.Manifest)
+	if err != nil {
+		return nil, err
+	}
+	if unchanged != nil {
+		return &ImportResult{WorkflowTemplate: unchanged, Applied: ImportResultUnchanged}, nil
+	}
+
+	if strategy == ImportStrategyNewVersion {
+		workflowTemplate.UID = existingUID
+		updated, err := c.CreateWorkflowTemplateVersion(namespace, workflowTemplate)
+		if err != nil {
+			return nil, err
+		}
+		return &ImportResult{WorkflowTemplate: updated, Applied: ImportStrategyNewVersion}, nil
+	}
+
+	name, imported, err := c.availableImportName(namespace, workflowTemplate.Name, workflowTemplate.Manifest)
+	if err != nil {
+		return nil, err
+	}
+	if imported != nil {
+		return &ImportResult{WorkflowTemplate: imported, Applied: ImportResultUnchanged}, nil
+	}
+
+	workflowTemplate.Name = name
+	created, err := c.CreateWorkflowTemplate(namespace, workflowTemplate)
+	if err != nil {
+		return nil, err
+	}
+	return &ImportResult{WorkflowTemplate: created, Applied: ImportStrategyRename}, nil
+}
+
+// ImportWorkflowTemplates imports each workflow template with ImportWorkflowTemplate, in order.
+// It stops at the first error, returning the results of the workflow templates imported before it.
+func (c *Client) ImportWorkflowTemplates(namespace string, workflowTemplates []*WorkflowTemplate, strategy string) ([]*ImportResult, error) {
+	results := make([]*ImportResult, 0, len(workflowTemplates))
+	for _, workflowTemplate := range workflowTemplates {
+		result, err := c.ImportWorkflowTemplate(namespace, workflowTemplate, strategy)
+		if err != nil {
+			return results, err
+		}
+		results = append(results, result)
+	}
+
+	return results, nil
+}
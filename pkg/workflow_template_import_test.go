@@ -0,0 +1,67 @@
+package v1
+
+import (
+	"github.com/onepanelio/core/pkg/util"
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/grpc/codes"
+	"strings"
+	"testing"
+)
+
+func TestClient_ImportWorkflowTemplate(t *testing.T) {
+	c := DefaultTestClient()
+	clearDatabase(t)
+
+	namespace := "onepanel"
+	newTemplate := func(manifest string) *WorkflowTemplate {
+		return &WorkflowTemplate{
+			Name:     "test",
+			Manifest: manifest,
+		}
+	}
+
+	result, err := c.ImportWorkflowTemplate(namespace, newTemplate(defaultWorkflowTemplate), "")
+	assert.Nil(t, err)
+	assert.Equal(t, ImportResultCreated, result.Applied)
+	uid := result.WorkflowTemplate.UID
+
+	_, err = c.ImportWorkflowTemplate(namespace, newTemplate(defaultWorkflowTemplate), ImportStrategyFail)
+	userErr, ok := err.(*util.UserError)
+	assert.True(t, ok)
+	assert.Equal(t, codes.AlreadyExists, userErr.Code)
+
+	result, err = c.ImportWorkflowTemplate(namespace, newTemplate(defaultWorkflowTemplate), ImportStrategyNewVersion)
+	assert.Nil(t, err)
+	assert.Equal(t, ImportResultUnchanged, result.Applied)
+
+	updatedManifest := strings.Replace(defaultWorkflowTemplate, "pytorch/pytorch:latest", "pytorch/pytorch:1.6.0", 1)
+	result, err = c.ImportWorkflowTemplate(namespace, newTemplate(updatedManifest), ImportStrategyNewVersion)
+	assert.Nil(t, err)
+	assert.Equal(t, ImportStrategyNewVersion, result.Applied)
+	assert.Equal(t, uid, result.WorkflowTemplate.UID)
+
+	result, err = c.ImportWorkflowTemplate(namespace, newTemplate(defaultWorkflowTemplate), ImportStrategyRename)
+	assert.Nil(t, err)
+	assert.Equal(t, ImportStrategyRename, result.Applied)
+	assert.Equal(t, "test-2", result.WorkflowTemplate.Name)
+
+	// re-running the import finds the renamed workflow template
+	result, err = c.ImportWorkflowTemplate(namespace, newTemplate(defaultWorkflowTemplate), ImportStrategyRename)
+	assert.Nil(t, err)
+	assert.Equal(t, ImportResultUnchanged, result.Applied)
+	assert.Equal(t, "test-2", result.WorkflowTemplate.Name)
+
+	// an exported argo resource has the same manifest once the server managed fields are stripped
+	exported := "apiVersion: argoproj.io/v1alpha1\nkind: WorkflowTemplate\nspec:\n  " +
+		strings.ReplaceAll(strings.TrimSuffix(updatedManifest, "\n"), "\n", "\n  ") + "\n"
+	result, err = c.ImportWorkflowTemplate(namespace, newTemplate(exported), ImportStrategyNewVersion)
+	assert.Nil(t, err)
+	assert.Equal(t, ImportResultUnchanged, result.Applied)
+	assert.Equal(t, uid, result.WorkflowTemplate.UID)
+}
+
+// Test_manifestsEqual makes sure the yaml formatting and key order are ignored
+func Test_manifestsEqual(t *testing.T) {
+	assert.True(t, manifestsEqual("entrypoint: main\narguments: {}\n", "arguments: {}\nentrypoint:   main\n"))
+	assert.False(t, manifestsEqual("entrypoint: main\n", "entrypoint: other\n"))
+}
@@ -0,0 +1,211 @@
+package v1
+
+import (
+	"fmt"
+	"sort"
+	"time"
+
+	sq "github.com/Masterminds/squirrel"
+	"github.com/argoproj/argo/pkg/apis/workflow/v1alpha1"
+	"github.com/onepanelio/core/pkg/util"
+	"github.com/onepanelio/core/pkg/util/label"
+	log "github.com/sirupsen/logrus"
+	"google.golang.org/grpc/codes"
+	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// Integrity issue codes
+const (
+	IntegrityMissingArgoTemplate = "missing-argo-template"
+	IntegrityLatestCount         = "latest-count"
+	IntegrityLatestMismatch      = "latest-mismatch"
+	IntegrityStrayArgoTemplate   = "stray-argo-template"
+	IntegrityVersionOrder        = "version-order"
+)
+
+// IntegrityIssue is an inconsistency between the database and argo for a workflow template.
+// Version is 0 if the issue is about the workflow template as a whole.
+type IntegrityIssue struct {
+	UID     string
+	Version int64
+	Code    string
+	Message string
+}
+
+// workflowTemplateVersionIntegrityRow is a workflow template version as loaded for the integrity check
+type workflowTemplateVersionIntegrityRow struct {
+	UID        string
+	IsArchived bool `db:"is_archived"`
+	Version    int64
+	IsLatest   bool      `db:"is_latest"`
+	CreatedAt  time.Time `db:"created_at"`
+}
+
+// integrityVersionKey identifies a workflow template version
+type integrityVersionKey struct {
+	uid     string
+	version int64
+}
+
+// VerifyWorkflowTemplateIntegrity cross checks the workflow templates in the database with the argo workflow templates.
+// It reports
+// * database versions of non-archived workflow templates without an argo workflow template
+// * non-archived workflow templates without exactly one latest version, in the database or in argo
+// * versions where the database is_latest and the argo latest label do not agree
+// * archived workflow templates that still have argo workflow templates
+// * versions that are not greater than a version created before them, versions must be strictly increasing
+func (c *Client) VerifyWorkflowTemplateIntegrity(namespace string) (issues []IntegrityIssue, err error) {
+	rows := make([]*workflowTemplateVersionIntegrityRow, 0)
+	query := sb.Select("wt.uid", "wt.is_archived", "wtv.version", "wtv.is_latest", "wtv.created_at").
+		From("workflow_templates wt").
+		Join("workflow_template_versions wtv ON wtv.workflow_template_id = wt.id").
+		Where(sq.Eq{
+			"wt.namespace": namespace,
+		}).
+		OrderBy("wt.uid", "wtv.version")
+	if err = c.DB.Selectx(&rows, query); err != nil {
+		log.WithFields(log.Fields{
+			"Namespace": namespace,
+			"Error":     err.Error(),
+		}).Error("Unable to load workflow template versions.")
+		return nil, util.NewUserError(codes.Unknown, "Unable to verify workflow template integrity.")
+	}
+
+	argoWorkflowTemplates, err := c.listArgoWorkflowTemplatesLimited(namespace, v1.ListOptions{
+		LabelSelector: label.WorkflowTemplateUid,
+	})
+	if err != nil {
+		log.WithFields(log.Fields{
+			"Namespace": namespace,
+			"Error":     err.Error(),
+		}).Error("Unable to list argo workflow templates.")
+		return nil, util.NewUserError(codes.Unknown, "Unable to verify workflow template integrity.")
+	}
+
+	argoByVersion := make(map[integrityVersionKey]*v1alpha1.WorkflowTemplate)
+	argoLatestCount := make(map[string]int)
+	for i := range argoWorkflowTemplates.Items {
+		argoWft := &argoWorkflowTemplates.Items[i]
+		uid := argoWft.Labels[label.WorkflowTemplateUid]
+		argoByVersion[integrityVersionKey{uid, argoWorkflowTemplateVersion(argoWft)}] = argoWft
+		if argoWft.Labels[label.VersionLatest] == "true" {
+			argoLatestCount[uid]++
+		}
+	}
+
+	issues = make([]IntegrityIssue, 0)
+	active := make(map[string]bool)
+	dbLatestCount := make(map[string]int)
+	for _, row := range rows {
+		if row.IsArchived {
+			continue
+		}
+		active[row.UID] = true
+
+		if row.IsLatest {
+			dbLatestCount[row.UID]++
+		}
+
+		argoWft, ok := argoByVersion[integrityVersionKey{row.UID, row.Version}]
+		if !ok {
+			issues = append(issues, IntegrityIssue{
+				UID:     row.UID,
+				Version: row.Version,
+				Code:    IntegrityMissingArgoTemplate,
+				Message: "version has no argo workflow template",
+			})
+			continue
+		}
+
+		argoIsLatest := argoWft.Labels[label.VersionLatest] == "true"
+		if argoIsLatest != row.IsLatest {
+			issues = append(issues, IntegrityIssue{
+				UID:     row.UID,
+				Version: row.Version,
+				Code:    IntegrityLatestMismatch,
+				Message: fmt.Sprintf("database is_latest is %v but argo latest label is %v", row.IsLatest, argoIsLatest),
+			})
+		}
+	}
+
+	uids := make([]string, 0, len(active))
+	for uid := range active {
+		uids = append(uids, uid)
+	}
+	sort.Strings(uids)
+
+	for _, uid := range uids {
+		if dbLatestCount[uid] != 1 {
+			issues = append(issues, IntegrityIssue{
+				UID:     uid,
+				Code:    IntegrityLatestCount,
+				Message: fmt.Sprintf("database has %v latest versions, expected 1", dbLatestCount[uid]),
+			})
+		}
+		if argoLatestCount[uid] != 1 {
+			issues = append(issues, IntegrityIssue{
+				UID:     uid,
+				Code:    IntegrityLatestCount,
+				Message: fmt.Sprintf("argo has %v latest workflow templates, expected 1", argoLatestCount[uid]),
+			})
+		}
+	}
+
+	issues = append(issues, versionOrderIssues(rows)...)
+
+	archivedIssues := make(map[string]bool)
+	for _, row := range rows {
+		if !row.IsArchived || active[row.UID] || archivedIssues[row.UID] {
+			continue
+		}
+
+		for key := range argoByVersion {
+			if key.uid == row.UID {
+				archivedIssues[row.UID] = true
+				issues = append(issues, IntegrityIssue{
+					UID:     row.UID,
+					Code:    IntegrityStrayArgoTemplate,
+					Message: "archived workflow template still has argo workflow templates",
+				})
+				break
+			}
+		}
+	}
+
+	return issues, nil
+}
+
+// versionOrderIssues reports the versions of non-archived workflow templates that are not greater than
+// a version of the same workflow template that was created before them.
+func versionOrderIssues(rows []*workflowTemplateVersionIntegrityRow) []IntegrityIssue {
+	byCreation := make([]*workflowTemplateVersionIntegrityRow, 0, len(rows))
+	for _, row := range rows {
+		if !row.IsArchived {
+			byCreation = append(byCreation, row)
+		}
+	}
+	sort.SliceStable(byCreation, func(i, j int) bool {
+		if byCreation[i].UID != byCreation[j].UID {
+			return byCreation[i].UID < byCreation[j].UID
+		}
+		return byCreation[i].CreatedAt.Before(byCreation[j].CreatedAt)
+	})
+
+	issues := make([]IntegrityIssue, 0)
+	maxVersions := make(map[string]int64)
+	for _, row := range byCreation {
+		maxVersion, ok := maxVersions[row.UID]
+		if ok && row.Version <= maxVersion {
+			issues = append(issues, IntegrityIssue{
+				UID:     row.UID,
+				Version: row.Version,
+				Code:    IntegrityVersionOrder,
+				Message: fmt.Sprintf("version is not greater than version %v, which was created before it", maxVersion),
+			})
+			continue
+		}
+		maxVersions[row.UID] = row.Version
+	}
+
+	return issues
+}
@@ -0,0 +1,60 @@
+package v1
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// TestClient_VerifyWorkflowTemplateIntegrity makes sure a missing argo workflow template is reported
+func TestClient_VerifyWorkflowTemplateIntegrity(t *testing.T) {
+	c := DefaultTestClient()
+	clearDatabase(t)
+
+	namespace := "onepanel"
+	created, _ := c.CreateWorkflowTemplate(namespace, &WorkflowTemplate{
+		Name:     "test",
+		Manifest: defaultWorkflowTemplate,
+	})
+
+	issues, err := c.VerifyWorkflowTemplateIntegrity(namespace)
+	assert.Nil(t, err)
+	assert.Empty(t, issues)
+
+	argoName := fmt.Sprintf("%v-v%v", created.UID, created.Version)
+	err = c.ArgoprojV1alpha1().WorkflowTemplates(namespace).Delete(argoName, nil)
+	assert.Nil(t, err)
+
+	issues, err = c.VerifyWorkflowTemplateIntegrity(namespace)
+	assert.Nil(t, err)
+
+	codes := make([]string, 0)
+	for _, issue := range issues {
+		codes = append(codes, issue.Code)
+	}
+	assert.Contains(t, codes, IntegrityMissingArgoTemplate)
+	assert.Contains(t, codes, IntegrityLatestCount)
+}
+
+// Test_versionOrderIssues makes sure a version that is not greater than one created before it is reported
+func Test_versionOrderIssues(t *testing.T) {
+	now := time.Now()
+	rows := []*workflowTemplateVersionIntegrityRow{
+		{UID: "test", Version: 5, CreatedAt: now.Add(time.Minute)},
+		{UID: "test", Version: 7, CreatedAt: now.Add(2 * time.Minute)},
+		{UID: "test", Version: 10, CreatedAt: now},
+		{UID: "other", Version: 1, CreatedAt: now.Add(time.Minute)},
+		{UID: "other", Version: 2, CreatedAt: now, IsArchived: true},
+	}
+
+	issues := versionOrderIssues(rows)
+	assert.Len(t, issues, 2)
+	for _, issue := range issues {
+		assert.Equal(t, "test", issue.UID)
+		assert.Equal(t, IntegrityVersionOrder, issue.Code)
+	}
+	assert.Equal(t, int64(5), issues[0].Version)
+	assert.Equal(t, int64(7), issues[1].Version)
+}
@@ -0,0 +1,98 @@
+package v1
+
+import (
+	"github.com/argoproj/argo/pkg/apis/workflow/v1alpha1"
+)
+
+// Lint warning codes
+const (
+	LintMissingActiveDeadlineSeconds = "missing-active-deadline-seconds"
+	LintMissingTTLStrategy           = "missing-ttl-strategy"
+	LintMissingPodGC                 = "missing-pod-gc"
+	LintMissingLabels                = "missing-labels"
+)
+
+// LintWarning is a non-blocking issue found in a workflow template.
+// Unlike validation errors, warnings do not stop the workflow template from being created.
+type LintWarning struct {
+	Code    string
+	Message string
+}
+
+// lintRule checks a workflow template and its parsed spec, returning a warning if there is an issue.
+type lintRule func(workflowTemplate *WorkflowTemplate, spec *v1alpha1.WorkflowSpec) *LintWarning
+
+// workflowTemplateLintRules are the rules run by LintWorkflowTemplate, in order.
+var workflowTemplateLintRules = []lintRule{
+	lintActiveDeadlineSeconds,
+	lintTTLStrategy,
+	lintPodGC,
+	lintLabels,
+}
+
+// lintActiveDeadlineSeconds warns if the workflow does not have a deadline, as it could run forever.
+func lintActiveDeadlineSeconds(workflowTemplate *WorkflowTemplate, spec *v1alpha1.WorkflowSpec) *LintWarning {
+	if spec.ActiveDeadlineSeconds != nil {
+		return nil
+	}
+
+	return &LintWarning{
+		Code:    LintMissingActiveDeadlineSeconds,
+		Message: "activeDeadlineSeconds is not set, so workflows from this template can run forever",
+	}
+}
+
+// lintTTLStrategy warns if finished workflows are never deleted.
+// The deprecated ttlSecondsAfterFinished is accepted in place of ttlStrategy.
+func lintTTLStrategy(workflowTemplate *WorkflowTemplate, spec *v1alpha1.WorkflowSpec) *LintWarning {
+	if spec.TTLStrategy != nil || spec.TTLSecondsAfterFinished != nil {
+		return nil
+	}
+
+	return &LintWarning{
+		Code:    LintMissingTTLStrategy,
+		Message: "ttlStrategy is not set, so finished workflows from this template are kept until deleted. Recommended: ttlStrategy: {secondsAfterCompletion: 86400}",
+	}
+}
+
+// lintPodGC warns if the pods of workflows are never deleted.
+func lintPodGC(workflowTemplate *WorkflowTemplate, spec *v1alpha1.WorkflowSpec) *LintWarning {
+	if spec.PodGC != nil {
+		return nil
+	}
+
+	return &LintWarning{
+		Code:    LintMissingPodGC,
+		Message: "podGC is not set, so the pods of workflows from this template are kept until the workflow is deleted. Recommended: podGC: {strategy: OnWorkflowSuccess}",
+	}
+}
+
+// lintLabels warns if the workflow template has no tag labels, like a team or owner, which makes it harder to find.
+func lintLabels(workflowTemplate *WorkflowTemplate, spec *v1alpha1.WorkflowSpec) *LintWarning {
+	if len(workflowTemplate.Labels) > 0 {
+		return nil
+	}
+
+	return &LintWarning{
+		Code:    LintMissingLabels,
+		Message: "the template has no labels, so it is harder to find. Recommended: add labels like team and owner",
+	}
+}
+
+// LintWorkflowTemplate returns warnings for the workflow template's manifest.
+// An error is only returned if the manifest can not be parsed.
+func LintWorkflowTemplate(workflowTemplate *WorkflowTemplate) ([]LintWarning, error) {
+	argoWft, err := parseWorkflowTemplateSpec(workflowTemplate)
+	if err != nil {
+		return nil, err
+	}
+
+	warnings := make([]LintWarning, 0)
+	for _, rule := range workflowTemplateLintRules {
+		if warning := rule(workflowTemplate, &argoWft.Spec.WorkflowSpec); warning != nil {
+			warnings = append(warnings, *warning)
+		}
+	}
+
+	return warnings, nil
+}
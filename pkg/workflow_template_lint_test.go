@@ -0,0 +1,59 @@
+package v1
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// TestLintWorkflowTemplate_ActiveDeadlineSeconds makes sure a warning is returned if activeDeadlineSeconds is missing
+func TestLintWorkflowTemplate_ActiveDeadlineSeconds(t *testing.T) {
+	warnings, err := LintWorkflowTemplate(&WorkflowTemplate{Manifest: defaultWorkflowTemplate})
+	assert.Nil(t, err)
+	assert.Contains(t, lintWarningCodes(warnings), LintMissingActiveDeadlineSeconds)
+
+	warnings, err = LintWorkflowTemplate(&WorkflowTemplate{Manifest: "activeDeadlineSeconds: 3600\n" + defaultWorkflowTemplate})
+	assert.Nil(t, err)
+	assert.NotContains(t, lintWarningCodes(warnings), LintMissingActiveDeadlineSeconds)
+}
+
+// TestLintWorkflowTemplate_CleanupPolicies makes sure warnings are returned if ttlStrategy or podGC are missing
+func TestLintWorkflowTemplate_CleanupPolicies(t *testing.T) {
+	warnings, err := LintWorkflowTemplate(&WorkflowTemplate{Manifest: defaultWorkflowTemplate})
+	assert.Nil(t, err)
+	assert.Contains(t, lintWarningCodes(warnings), LintMissingTTLStrategy)
+	assert.Contains(t, lintWarningCodes(warnings), LintMissingPodGC)
+
+	manifest := "ttlStrategy:\n  secondsAfterCompletion: 86400\npodGC:\n  strategy: OnWorkflowSuccess\n" + defaultWorkflowTemplate
+	warnings, err = LintWorkflowTemplate(&WorkflowTemplate{Manifest: manifest})
+	assert.Nil(t, err)
+	assert.NotContains(t, lintWarningCodes(warnings), LintMissingTTLStrategy)
+	assert.NotContains(t, lintWarningCodes(warnings), LintMissingPodGC)
+
+	warnings, err = LintWorkflowTemplate(&WorkflowTemplate{Manifest: "ttlSecondsAfterFinished: 60\n" + defaultWorkflowTemplate})
+	assert.Nil(t, err)
+	assert.NotContains(t, lintWarningCodes(warnings), LintMissingTTLStrategy)
+}
+
+// TestLintWorkflowTemplate_Labels makes sure a warning is returned if the workflow template has no labels
+func TestLintWorkflowTemplate_Labels(t *testing.T) {
+	warnings, err := LintWorkflowTemplate(&WorkflowTemplate{Manifest: defaultWorkflowTemplate})
+	assert.Nil(t, err)
+	assert.Contains(t, lintWarningCodes(warnings), LintMissingLabels)
+
+	warnings, err = LintWorkflowTemplate(&WorkflowTemplate{
+		Manifest: defaultWorkflowTemplate,
+		Labels:   map[string]string{"team": "vision"},
+	})
+	assert.Nil(t, err)
+	assert.NotContains(t, lintWarningCodes(warnings), LintMissingLabels)
+}
+
+// lintWarningCodes returns the codes of the warnings
+func lintWarningCodes(warnings []LintWarning) (codes []string) {
+	for _, warning := range warnings {
+		codes = append(codes, warning.Code)
+	}
+
+	return
+}
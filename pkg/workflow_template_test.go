@@ -2,11 +2,25 @@ package v1
 
 import (
 	"database/sql"
+	"encoding/json"
+	"errors"
 	"fmt"
+	"github.com/argoproj/argo/pkg/apis/workflow/v1alpha1"
+	argoFakeV1alpha1 "github.com/argoproj/argo/pkg/client/clientset/versioned/typed/workflow/v1alpha1/fake"
 	"github.com/onepanelio/core/pkg/util"
+	"github.com/onepanelio/core/pkg/util/label"
+	"github.com/onepanelio/core/pkg/util/ptr"
+	"github.com/onepanelio/core/pkg/util/request"
+	"github.com/onepanelio/core/pkg/util/request/sort"
+	"github.com/onepanelio/core/pkg/util/types"
 	"github.com/stretchr/testify/assert"
 	"google.golang.org/grpc/codes"
+	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	k8stesting "k8s.io/client-go/testing"
+	"strings"
 	"testing"
+	"time"
 )
 
 const defaultWorkflowTemplate = `entrypoint: main
@@ -302,10 +316,57 @@ func testClientCreateWorkflowTemplateVersionMarkOldNotLatest(t *testing.T) {
 	assert.False(t, updated.IsLatest)
 }
 
+// testClientCreateWorkflowTemplateVersionReturnsVersion makes sure the returned workflow template has the new version
+func testClientCreateWorkflowTemplateVersionReturnsVersion(t *testing.T) {
+	c := DefaultTestClient()
+	clearDatabase(t)
+
+	namespace := "onepanel"
+	workflowTemplate := &WorkflowTemplate{
+		Name:     "test",
+		Manifest: defaultWorkflowTemplate,
+	}
+
+	original, _ := c.CreateWorkflowTemplate(namespace, workflowTemplate)
+	originalVersion := original.Version
+
+	created, err := c.CreateWorkflowTemplateVersion(namespace, workflowTemplate)
+	assert.Nil(t, err)
+	assert.NotEqual(t, originalVersion, created.Version)
+
+	latest, err := c.GetLatestWorkflowTemplate(namespace, original.UID)
+	assert.Nil(t, err)
+	assert.Equal(t, latest.Version, created.Version)
+}
+
+// testClientCreateWorkflowTemplateVersionStripsServerManagedFields makes sure an exported argo resource can be used as a new version
+func testClientCreateWorkflowTemplateVersionStripsServerManagedFields(t *testing.T) {
+	c := DefaultTestClient()
+	clearDatabase(t)
+
+	namespace := "onepanel"
+	original, _ := c.CreateWorkflowTemplate(namespace, &WorkflowTemplate{
+		Name:     "test",
+		Manifest: defaultWorkflowTemplate,
+	})
+
+	exported := "apiVersion: argoproj.io/v1alpha1\nkind: WorkflowTemplate\nspec:\n  " +
+		strings.ReplaceAll(strings.TrimSuffix(defaultWorkflowTemplate, "\n"), "\n", "\n  ") + "\n"
+	created, err := c.CreateWorkflowTemplateVersion(namespace, &WorkflowTemplate{
+		UID:      original.UID,
+		Name:     original.Name,
+		Manifest: exported,
+	})
+	assert.Nil(t, err)
+	assert.NotContains(t, created.Manifest, "apiVersion")
+}
+
 // Test_getWorkflowTemplate_SuccessVersion tests cases for creating a workflow template version
 func TestClient_CreateWorkflowTemplateVersion(t *testing.T) {
 	testClientCreateWorkflowTemplateVersionNew(t)
 	testClientCreateWorkflowTemplateVersionMarkOldNotLatest(t)
+	testClientCreateWorkflowTemplateVersionReturnsVersion(t)
+	testClientCreateWorkflowTemplateVersionStripsServerManagedFields(t)
 }
 
 // testGetWorkflowTemplateSuccess gets a workflow template with no error conditions encountered
@@ -343,3 +404,1774 @@ func TestClient_GetWorkflowTemplate(t *testing.T) {
 	testClientGetWorkflowTemplateSuccess(t)
 	testClientGetWorkflowTemplateNotFound(t)
 }
+
+// TestClient_GetWorkflowTemplateParameters makes sure the parameters of a specific version are returned
+func TestClient_GetWorkflowTemplateParameters(t *testing.T) {
+	c := DefaultTestClient()
+	clearDatabase(t)
+
+	namespace := "onepanel"
+	workflowTemplate := &WorkflowTemplate{
+		Name:     "test",
+		Manifest: defaultWorkflowTemplate,
+	}
+	created, _ := c.CreateWorkflowTemplate(namespace, workflowTemplate)
+
+	parameters, err := c.GetWorkflowTemplateParameters(namespace, created.UID, created.Version)
+	assert.Nil(t, err)
+	assert.Len(t, parameters, 2)
+
+	_, err = c.GetWorkflowTemplateParameters(namespace, "uid-not-found", 0)
+	userErr, ok := err.(*util.UserError)
+	assert.True(t, ok)
+	assert.Equal(t, codes.NotFound, userErr.Code)
+}
+
+// TestClient_WorkflowTemplateVersionExists checks existing and missing workflow template versions
+func TestClient_WorkflowTemplateVersionExists(t *testing.T) {
+	c := DefaultTestClient()
+	clearDatabase(t)
+
+	namespace := "onepanel"
+	workflowTemplate := &WorkflowTemplate{
+		Name:     "test",
+		Manifest: defaultWorkflowTemplate,
+	}
+	created, _ := c.CreateWorkflowTemplate(namespace, workflowTemplate)
+
+	exists, err := c.WorkflowTemplateVersionExists(namespace, created.UID, created.Version)
+	assert.Nil(t, err)
+	assert.True(t, exists)
+
+	exists, err = c.WorkflowTemplateVersionExists(namespace, created.UID, created.Version+1)
+	assert.Nil(t, err)
+	assert.False(t, exists)
+}
+
+// TestClient_ClonePublicWorkflowTemplate makes sure public templates are listed and can be cloned
+func TestClient_ClonePublicWorkflowTemplate(t *testing.T) {
+	c := DefaultTestClient()
+	clearDatabase(t)
+
+	namespace := "onepanel"
+	workflowTemplate := &WorkflowTemplate{
+		Name:     "test",
+		Manifest: defaultWorkflowTemplate,
+		IsPublic: true,
+	}
+	created, _ := c.CreateWorkflowTemplate(namespace, workflowTemplate)
+
+	publicTemplates, err := c.ListPublicWorkflowTemplates()
+	assert.Nil(t, err)
+	assert.Len(t, publicTemplates, 1)
+
+	cloned, err := c.ClonePublicWorkflowTemplate(namespace, created.UID, namespace, "test-clone")
+	assert.Nil(t, err)
+	assert.Equal(t, "test-clone", cloned.Name)
+	assert.False(t, cloned.IsPublic)
+}
+
+// TestClient_CloneWorkflowTemplateVersion makes sure a historical version can be cloned as a new workflow template
+func TestClient_CloneWorkflowTemplateVersion(t *testing.T) {
+	c := DefaultTestClient()
+	clearDatabase(t)
+
+	namespace := "onepanel"
+	workflowTemplate := &WorkflowTemplate{
+		Name:     "test",
+		Manifest: defaultWorkflowTemplate,
+		Labels:   map[string]string{"stage": "stable"},
+	}
+	created, err := c.CreateWorkflowTemplate(namespace, workflowTemplate)
+	assert.Nil(t, err)
+	firstVersion := created.Version
+
+	workflowTemplate.Labels = map[string]string{"stage": "regressed"}
+	_, err = c.CreateWorkflowTemplateVersion(namespace, workflowTemplate)
+	assert.Nil(t, err)
+
+	cloned, err := c.CloneWorkflowTemplateVersion(namespace, created.UID, firstVersion, namespace, "test-fork")
+	assert.Nil(t, err)
+	assert.Equal(t, "test-fork", cloned.Name)
+	assert.NotEqual(t, created.UID, cloned.UID)
+	assert.Equal(t, "stable", cloned.Labels["stage"])
+
+	_, err = c.CloneWorkflowTemplateVersion(namespace, created.UID, 1, namespace, "test-missing")
+	assert.NotNil(t, err)
+}
+
+// TestClient_GetWorkflowTemplateLabelHistory makes sure labels are returned for each version
+func TestClient_GetWorkflowTemplateLabelHistory(t *testing.T) {
+	c := DefaultTestClient()
+	clearDatabase(t)
+
+	namespace := "onepanel"
+	workflowTemplate := &WorkflowTemplate{
+		Name:     "test",
+		Manifest: defaultWorkflowTemplate,
+		Labels:   map[string]string{"stage": "dev"},
+	}
+	c.CreateWorkflowTemplate(namespace, workflowTemplate)
+
+	workflowTemplate.Labels = map[string]string{"stage": "prod"}
+	c.CreateWorkflowTemplateVersion(namespace, workflowTemplate)
+
+	history, err := c.GetWorkflowTemplateLabelHistory(namespace, workflowTemplate.UID)
+	assert.Nil(t, err)
+	assert.Len(t, history, 2)
+	assert.Equal(t, "prod", history[0].Labels["stage"])
+	assert.Equal(t, "dev", history[1].Labels["stage"])
+
+	_, err = c.GetWorkflowTemplateLabelHistory(namespace, "missing")
+	userErr, ok := err.(*util.UserError)
+	assert.True(t, ok)
+	assert.Equal(t, codes.NotFound, userErr.Code)
+}
+
+// TestClient_GetWorkflowTemplateByUIDOrName makes sure a workflow template can be found by uid or name
+func TestClient_GetWorkflowTemplateByUIDOrName(t *testing.T) {
+	c := DefaultTestClient()
+	clearDatabase(t)
+
+	namespace := "onepanel"
+	workflowTemplate := &WorkflowTemplate{
+		Name:     "Test Template",
+		Manifest: defaultWorkflowTemplate,
+	}
+	created, _ := c.CreateWorkflowTemplate(namespace, workflowTemplate)
+
+	byUID, err := c.GetWorkflowTemplateByUIDOrName(namespace, created.UID, 0)
+	assert.Nil(t, err)
+	assert.Equal(t, created.UID, byUID.UID)
+
+	byName, err := c.GetWorkflowTemplateByUIDOrName(namespace, "Test Template", 0)
+	assert.Nil(t, err)
+	assert.Equal(t, created.UID, byName.UID)
+
+	_, err = c.GetWorkflowTemplateByUIDOrName(namespace, "not-found", 0)
+	userErr, ok := err.(*util.UserError)
+	assert.True(t, ok)
+	assert.Equal(t, codes.NotFound, userErr.Code)
+
+	_, err = c.DB.Exec(`INSERT INTO workflow_templates (uid, name, namespace) VALUES ('test-template-2', 'Test Template', $1)`, namespace)
+	assert.Nil(t, err)
+
+	_, err = c.GetWorkflowTemplateByUIDOrName(namespace, "Test Template", 0)
+	userErr, ok = err.(*util.UserError)
+	assert.True(t, ok)
+	assert.Equal(t, codes.FailedPrecondition, userErr.Code)
+}
+
+// TestClient_CreateWorkflowTemplateVersionWithOptions_InheritLabels makes sure tag labels are carried over to the new version
+func TestClient_CreateWorkflowTemplateVersionWithOptions_InheritLabels(t *testing.T) {
+	c := DefaultTestClient()
+	clearDatabase(t)
+
+	namespace := "onepanel"
+	workflowTemplate := &WorkflowTemplate{
+		Name:     "test",
+		Manifest: defaultWorkflowTemplate,
+		Labels: map[string]string{
+			"environment": "dev",
+			"owner":       "research",
+		},
+	}
+	created, _ := c.CreateWorkflowTemplate(namespace, workflowTemplate)
+
+	newVersion := &WorkflowTemplate{
+		UID:      created.UID,
+		Name:     created.Name,
+		Manifest: defaultWorkflowTemplate,
+		Labels: map[string]string{
+			"environment": "prod",
+		},
+	}
+	_, err := c.CreateWorkflowTemplateVersionWithOptions(namespace, newVersion, &WorkflowTemplateVersionOptions{InheritLabels: true})
+	assert.Nil(t, err)
+
+	labels, err := c.GetWorkflowTemplateLabels(namespace, created.UID, label.TagPrefix, 0)
+	assert.Nil(t, err)
+	assert.Equal(t, "prod", labels["environment"])
+	assert.Equal(t, "research", labels["owner"])
+}
+
+// TestClient_SetArgoListConcurrencyLimit makes sure argo list calls release the limiter when done
+func TestClient_SetArgoListConcurrencyLimit(t *testing.T) {
+	c := DefaultTestClient()
+	c.SetArgoListConcurrencyLimit(1)
+
+	for i := 0; i < 3; i++ {
+		_, err := c.listArgoWorkflowTemplates("onepanel", "test")
+		assert.Nil(t, err)
+	}
+	assert.Len(t, c.argoListLimiter, 0)
+
+	c.SetArgoListConcurrencyLimit(0)
+	assert.Nil(t, c.argoListLimiter)
+}
+
+// TestClient_GetWorkflowTemplateStatistics makes sure archived and non-archived templates are counted separately,
+// and system templates are not counted
+func TestClient_GetWorkflowTemplateStatistics(t *testing.T) {
+	c := DefaultTestClient()
+	clearDatabase(t)
+
+	namespace := "onepanel"
+	created, _ := c.CreateWorkflowTemplate(namespace, &WorkflowTemplate{
+		Name:     "test",
+		Manifest: defaultWorkflowTemplate,
+	})
+	c.CreateWorkflowTemplateVersion(namespace, &WorkflowTemplate{
+		UID:      created.UID,
+		Name:     created.Name,
+		Manifest: defaultWorkflowTemplate,
+	})
+
+	archived, _ := c.CreateWorkflowTemplate(namespace, &WorkflowTemplate{
+		Name:     "test-archived",
+		Manifest: defaultWorkflowTemplate,
+	})
+	c.ArchiveWorkflowTemplate(namespace, archived.UID)
+
+	// system workflow templates are not counted
+	_, err := c.CreateWorkflowTemplate(namespace, &WorkflowTemplate{
+		Name:     "test-system",
+		Manifest: defaultWorkflowTemplate,
+		IsSystem: true,
+	})
+	assert.Nil(t, err)
+
+	statistics, err := c.GetWorkflowTemplateStatistics(namespace)
+	assert.Nil(t, err)
+	assert.Equal(t, 1, statistics.TotalTemplates)
+	assert.Equal(t, 2, statistics.TotalVersions)
+	assert.Equal(t, 0, statistics.TotalExecutions)
+	assert.Equal(t, 1, statistics.ArchivedTemplates)
+	assert.Equal(t, 1, statistics.ArchivedVersions)
+}
+
+// TestClient_CreateWorkflowTemplateVersion_Archived makes sure versions can not be added to archived workflow templates
+func TestClient_CreateWorkflowTemplateVersion_Archived(t *testing.T) {
+	c := DefaultTestClient()
+	clearDatabase(t)
+
+	namespace := "onepanel"
+	created, _ := c.CreateWorkflowTemplate(namespace, &WorkflowTemplate{
+		Name:     "test",
+		Manifest: defaultWorkflowTemplate,
+	})
+	c.ArchiveWorkflowTemplate(namespace, created.UID)
+
+	_, err := c.CreateWorkflowTemplateVersion(namespace, &WorkflowTemplate{
+		UID:      created.UID,
+		Name:     created.Name,
+		Manifest: defaultWorkflowTemplate,
+	})
+	userErr, ok := err.(*util.UserError)
+	assert.True(t, ok)
+	assert.Equal(t, codes.FailedPrecondition, userErr.Code)
+}
+
+// TestClient_UnarchiveWorkflowTemplate makes sure an archived workflow template and its argo workflow templates are restored
+func TestClient_UnarchiveWorkflowTemplate(t *testing.T) {
+	c := DefaultTestClient()
+	clearDatabase(t)
+
+	namespace := "onepanel"
+	created, _ := c.CreateWorkflowTemplate(namespace, &WorkflowTemplate{
+		Name:     "test",
+		Manifest: defaultWorkflowTemplate,
+	})
+	version, _ := c.CreateWorkflowTemplateVersion(namespace, &WorkflowTemplate{
+		UID:      created.UID,
+		Name:     created.Name,
+		Manifest: defaultWorkflowTemplate,
+	})
+	c.ArchiveWorkflowTemplate(namespace, created.UID)
+
+	unarchived, err := c.UnarchiveWorkflowTemplate(namespace, created.UID)
+	assert.Nil(t, err)
+	if assert.NotNil(t, unarchived) {
+		assert.Equal(t, version.Version, unarchived.Version)
+	}
+
+	argoWorkflowTemplates, err := c.listArgoWorkflowTemplates(namespace, created.UID)
+	assert.Nil(t, err)
+	assert.Len(t, *argoWorkflowTemplates, 2)
+
+	_, err = c.UnarchiveWorkflowTemplate(namespace, created.UID)
+	userErr, ok := err.(*util.UserError)
+	if assert.True(t, ok) {
+		assert.Equal(t, codes.NotFound, userErr.Code)
+	}
+
+	c.ArchiveWorkflowTemplate(namespace, created.UID)
+	c.CreateWorkflowTemplate(namespace, &WorkflowTemplate{
+		Name:     "test",
+		Manifest: defaultWorkflowTemplate,
+	})
+
+	_, err = c.UnarchiveWorkflowTemplate(namespace, created.UID)
+	userErr, ok = err.(*util.UserError)
+	if assert.True(t, ok) {
+		assert.Equal(t, codes.AlreadyExists, userErr.Code)
+	}
+}
+
+// TestClient_DiffWorkflowTemplateVersionFromLatest makes sure the diff from a version to the latest version is returned
+func TestClient_DiffWorkflowTemplateVersionFromLatest(t *testing.T) {
+	c := DefaultTestClient()
+	clearDatabase(t)
+
+	namespace := "onepanel"
+	created, _ := c.CreateWorkflowTemplate(namespace, &WorkflowTemplate{
+		Name:     "test",
+		Manifest: defaultWorkflowTemplate,
+	})
+	firstVersion := created.Version
+
+	diff, err := c.DiffWorkflowTemplateVersionFromLatest(namespace, created.UID, firstVersion)
+	assert.Nil(t, err)
+	assert.Empty(t, diff)
+
+	updatedManifest := strings.Replace(defaultWorkflowTemplate, "pytorch/pytorch:latest", "pytorch/pytorch:1.6.0", 1)
+	c.CreateWorkflowTemplateVersion(namespace, &WorkflowTemplate{
+		UID:      created.UID,
+		Name:     created.Name,
+		Manifest: updatedManifest,
+	})
+
+	diff, err = c.DiffWorkflowTemplateVersionFromLatest(namespace, created.UID, firstVersion)
+	assert.Nil(t, err)
+	assert.Contains(t, diff, "-      image: pytorch/pytorch:latest")
+	assert.Contains(t, diff, "+      image: pytorch/pytorch:1.6.0")
+}
+
+// TestClient_TouchWorkflowTemplate makes sure touched workflow templates are listed first when sorting by updatedAt
+func TestClient_TouchWorkflowTemplate(t *testing.T) {
+	c := DefaultTestClient()
+	clearDatabase(t)
+
+	namespace := "onepanel"
+	first, _ := c.CreateWorkflowTemplate(namespace, &WorkflowTemplate{
+		Name:     "first",
+		Manifest: defaultWorkflowTemplate,
+	})
+	c.CreateWorkflowTemplate(namespace, &WorkflowTemplate{
+		Name:     "second",
+		Manifest: defaultWorkflowTemplate,
+	})
+
+	err := c.TouchWorkflowTemplate(namespace, first.UID)
+	assert.Nil(t, err)
+
+	criteria, _ := sort.New("updatedAt,desc")
+	workflowTemplates, err := c.ListWorkflowTemplates(namespace, &request.Request{Sort: criteria})
+	assert.Nil(t, err)
+	assert.Len(t, workflowTemplates, 2)
+	assert.Equal(t, first.UID, workflowTemplates[0].UID)
+}
+
+// TestClient_AddLabels_WorkflowTemplate makes sure adding and deleting labels of a workflow template updates its updatedAt
+func TestClient_AddLabels_WorkflowTemplate(t *testing.T) {
+	c := DefaultTestClient()
+	clearDatabase(t)
+
+	namespace := "onepanel"
+	first, _ := c.CreateWorkflowTemplate(namespace, &WorkflowTemplate{
+		Name:     "first",
+		Manifest: defaultWorkflowTemplate,
+	})
+	second, _ := c.CreateWorkflowTemplate(namespace, &WorkflowTemplate{
+		Name:     "second",
+		Manifest: defaultWorkflowTemplate,
+	})
+	criteria, _ := sort.New("updatedAt,desc")
+
+	assert.Nil(t, c.AddLabels(namespace, TypeWorkflowTemplate, first.UID, map[string]string{"a": "b", "c": "d"}))
+	labels, err := c.ListLabels(TypeWorkflowTemplate, first.UID)
+	assert.Nil(t, err)
+	assert.Len(t, labels, 2)
+
+	workflowTemplates, err := c.ListWorkflowTemplates(namespace, &request.Request{Sort: criteria})
+	assert.Nil(t, err)
+	assert.Equal(t, first.UID, workflowTemplates[0].UID)
+
+	assert.Nil(t, c.AddLabels(namespace, TypeWorkflowTemplate, second.UID, map[string]string{"a": "b"}))
+	assert.Nil(t, c.DeleteLabels(namespace, TypeWorkflowTemplate, first.UID, map[string]string{"a": ""}))
+	labels, err = c.ListLabels(TypeWorkflowTemplate, first.UID)
+	assert.Nil(t, err)
+	if assert.Len(t, labels, 1) {
+		assert.Equal(t, "c", labels[0].Key)
+	}
+
+	workflowTemplates, err = c.ListWorkflowTemplates(namespace, &request.Request{Sort: criteria})
+	assert.Nil(t, err)
+	assert.Equal(t, first.UID, workflowTemplates[0].UID)
+
+	err = c.AddLabels(namespace, TypeWorkflowTemplate, "missing", map[string]string{"a": "b"})
+	userErr, ok := err.(*util.UserError)
+	if assert.True(t, ok) {
+		assert.Equal(t, codes.NotFound, userErr.Code)
+	}
+}
+
+// TestClient_ListWorkflowTemplates_CreatedRange makes sure workflow templates can be filtered by when they were created
+func TestClient_ListWorkflowTemplates_CreatedRange(t *testing.T) {
+	c := DefaultTestClient()
+	clearDatabase(t)
+
+	namespace := "onepanel"
+	old, _ := c.CreateWorkflowTemplate(namespace, &WorkflowTemplate{
+		Name:     "old",
+		Manifest: defaultWorkflowTemplate,
+	})
+	recent, _ := c.CreateWorkflowTemplate(namespace, &WorkflowTemplate{
+		Name:     "recent",
+		Manifest: defaultWorkflowTemplate,
+	})
+
+	_, err := c.DB.Exec(`UPDATE workflow_templates SET created_at = created_at - INTERVAL '60 days' WHERE uid = $1`, old.UID)
+	assert.Nil(t, err)
+
+	createdAfter := time.Now().UTC().Add(-30 * 24 * time.Hour)
+	resourceRequest := &request.Request{
+		Filter: WorkflowTemplateFilter{
+			CreatedAfter: &createdAfter,
+		},
+	}
+
+	workflowTemplates, err := c.ListWorkflowTemplates(namespace, resourceRequest)
+	assert.Nil(t, err)
+	assert.Len(t, workflowTemplates, 1)
+	assert.Equal(t, recent.UID, workflowTemplates[0].UID)
+
+	count, err := c.CountWorkflowTemplates(namespace, resourceRequest)
+	assert.Nil(t, err)
+	assert.Equal(t, 1, count)
+}
+
+// TestClient_RenameWorkflowTemplate makes sure the name and argo label are updated, the rename is audited, and name collisions are rejected
+func TestClient_RenameWorkflowTemplate(t *testing.T) {
+	c := DefaultTestClient()
+	clearDatabase(t)
+
+	namespace := "onepanel"
+	created, _ := c.CreateWorkflowTemplate(namespace, &WorkflowTemplate{
+		Name:     "test",
+		Manifest: defaultWorkflowTemplate,
+	})
+	c.CreateWorkflowTemplate(namespace, &WorkflowTemplate{
+		Name:     "taken",
+		Manifest: defaultWorkflowTemplate,
+	})
+
+	err := c.RenameWorkflowTemplate(namespace, created.UID, "Renamed Template")
+	assert.Nil(t, err)
+
+	renamed, err := c.GetWorkflowTemplate(namespace, created.UID, 0)
+	assert.Nil(t, err)
+	assert.Equal(t, "Renamed Template", renamed.Name)
+	assert.Equal(t, "renamed-template", renamed.ArgoWorkflowTemplate.Labels[label.WorkflowTemplate])
+
+	err = c.RenameWorkflowTemplate(namespace, created.UID, "taken")
+	userErr, ok := err.(*util.UserError)
+	assert.True(t, ok)
+	assert.Equal(t, codes.AlreadyExists, userErr.Code)
+
+	// only the rename that was done is recorded
+	entries, err := c.GetWorkflowTemplateAuditLog(namespace, created.UID)
+	assert.Nil(t, err)
+	if assert.Len(t, entries, 2) {
+		assert.Equal(t, AuditActionRename, entries[1].Action)
+	}
+}
+
+// TestClient_RenameWorkflowTemplate_ArgoFailure makes sure the argo labels and the name are kept when an argo update fails
+func TestClient_RenameWorkflowTemplate_ArgoFailure(t *testing.T) {
+	c := DefaultTestClient()
+	clearDatabase(t)
+
+	namespace := "onepanel"
+	created, _ := c.CreateWorkflowTemplate(namespace, &WorkflowTemplate{
+		Name:     "test",
+		Manifest: defaultWorkflowTemplate,
+	})
+	_, err := c.CreateWorkflowTemplateVersion(namespace, &WorkflowTemplate{
+		UID:      created.UID,
+		Name:     created.Name,
+		Manifest: defaultWorkflowTemplate,
+	})
+	assert.Nil(t, err)
+
+	// the first version is relabeled, the second fails
+	failArgoWorkflowTemplateUpdate(c, 1)
+	err = c.RenameWorkflowTemplate(namespace, created.UID, "Renamed Template")
+	assert.NotNil(t, err)
+
+	workflowTemplate, err := c.GetWorkflowTemplate(namespace, created.UID, 0)
+	assert.Nil(t, err)
+	assert.Equal(t, "test", workflowTemplate.Name)
+
+	argoWorkflowTemplates, err := c.ArgoprojV1alpha1().WorkflowTemplates(namespace).List(v1.ListOptions{})
+	assert.Nil(t, err)
+	assert.Len(t, argoWorkflowTemplates.Items, 2)
+	for _, argoWft := range argoWorkflowTemplates.Items {
+		assert.Equal(t, created.UID, argoWft.Labels[label.WorkflowTemplate])
+	}
+}
+
+// TestClient_GetWorkflowTemplateByManifestHash makes sure the version with the manifest is returned
+func TestClient_GetWorkflowTemplateByManifestHash(t *testing.T) {
+	c := DefaultTestClient()
+	clearDatabase(t)
+
+	namespace := "onepanel"
+	created, _ := c.CreateWorkflowTemplate(namespace, &WorkflowTemplate{
+		Name:     "test",
+		Manifest: defaultWorkflowTemplate,
+	})
+	firstVersion := created.Version
+
+	updatedManifest := strings.Replace(defaultWorkflowTemplate, "pytorch/pytorch:latest", "pytorch/pytorch:1.6.0", 1)
+	c.CreateWorkflowTemplateVersion(namespace, &WorkflowTemplate{
+		UID:      created.UID,
+		Name:     created.Name,
+		Manifest: updatedManifest,
+	})
+
+	found, err := c.GetWorkflowTemplateByManifestHash(namespace, created.UID, ManifestHash(defaultWorkflowTemplate))
+	assert.Nil(t, err)
+	assert.Equal(t, firstVersion, found.Version)
+	assert.Equal(t, defaultWorkflowTemplate, found.Manifest)
+
+	_, err = c.GetWorkflowTemplateByManifestHash(namespace, created.UID, ManifestHash("missing"))
+	userErr, ok := err.(*util.UserError)
+	assert.True(t, ok)
+	assert.Equal(t, codes.NotFound, userErr.Code)
+}
+
+// TestClient_SetLatestWorkflowTemplateVersion makes sure an older version can be made the latest one
+func TestClient_SetLatestWorkflowTemplateVersion(t *testing.T) {
+	c := DefaultTestClient()
+	clearDatabase(t)
+
+	namespace := "onepanel"
+	created, _ := c.CreateWorkflowTemplate(namespace, &WorkflowTemplate{
+		Name:     "test",
+		Manifest: defaultWorkflowTemplate,
+	})
+	firstVersion := created.Version
+
+	c.CreateWorkflowTemplateVersion(namespace, &WorkflowTemplate{
+		UID:      created.UID,
+		Name:     created.Name,
+		Manifest: defaultWorkflowTemplate,
+	})
+
+	err := c.SetLatestWorkflowTemplateVersion(namespace, created.UID, firstVersion)
+	assert.Nil(t, err)
+
+	latest, err := c.GetLatestWorkflowTemplate(namespace, created.UID)
+	assert.Nil(t, err)
+	assert.Equal(t, firstVersion, latest.Version)
+
+	count, _ := c.CountWorkflowTemplateVersions(namespace, created.UID)
+	assert.Equal(t, uint64(2), count)
+
+	err = c.SetLatestWorkflowTemplateVersion(namespace, created.UID, 1)
+	userErr, ok := err.(*util.UserError)
+	assert.True(t, ok)
+	assert.Equal(t, codes.NotFound, userErr.Code)
+}
+
+// TestClient_SetLatestWorkflowTemplateVersion_ArgoFailure makes sure the latest version is kept when an argo update fails
+func TestClient_SetLatestWorkflowTemplateVersion_ArgoFailure(t *testing.T) {
+	c := DefaultTestClient()
+	clearDatabase(t)
+
+	namespace := "onepanel"
+	created, _ := c.CreateWorkflowTemplate(namespace, &WorkflowTemplate{
+		Name:     "test",
+		Manifest: defaultWorkflowTemplate,
+	})
+	firstVersion := created.Version
+
+	secondVersion, err := c.CreateWorkflowTemplateVersion(namespace, &WorkflowTemplate{
+		UID:      created.UID,
+		Name:     created.Name,
+		Manifest: defaultWorkflowTemplate,
+	})
+	assert.Nil(t, err)
+
+	// one version is relabeled, the other fails
+	failArgoWorkflowTemplateUpdate(c, 1)
+	err = c.SetLatestWorkflowTemplateVersion(namespace, created.UID, firstVersion)
+	assert.NotNil(t, err)
+
+	latest, err := c.GetLatestWorkflowTemplate(namespace, created.UID)
+	assert.Nil(t, err)
+	assert.Equal(t, secondVersion.Version, latest.Version)
+
+	argoWorkflowTemplates, err := c.ArgoprojV1alpha1().WorkflowTemplates(namespace).List(v1.ListOptions{})
+	assert.Nil(t, err)
+	for _, argoWft := range argoWorkflowTemplates.Items {
+		_, isLatest := argoWft.Labels[label.VersionLatest]
+		assert.Equal(t, argoWorkflowTemplateVersion(&argoWft) == secondVersion.Version, isLatest)
+	}
+}
+
+// TestClient_StreamWorkflowTemplates makes sure every template is visited and that an error stops the iteration
+func TestClient_StreamWorkflowTemplates(t *testing.T) {
+	c := DefaultTestClient()
+	clearDatabase(t)
+
+	namespace := "onepanel"
+	for _, name := range []string{"test-1", "test-2", "test-3"} {
+		c.CreateWorkflowTemplate(namespace, &WorkflowTemplate{
+			Name:     name,
+			Manifest: defaultWorkflowTemplate,
+		})
+	}
+
+	names := make([]string, 0)
+	err := c.StreamWorkflowTemplates(namespace, func(wt *WorkflowTemplate) error {
+		names = append(names, wt.Name)
+		return nil
+	})
+	assert.Nil(t, err)
+	assert.ElementsMatch(t, []string{"test-1", "test-2", "test-3"}, names)
+
+	stop := errors.New("stop")
+	visited := 0
+	err = c.StreamWorkflowTemplates(namespace, func(wt *WorkflowTemplate) error {
+		visited++
+		return stop
+	})
+	assert.Equal(t, stop, err)
+	assert.Equal(t, 1, visited)
+}
+
+// TestClient_ListWorkflowTemplatesForUser makes sure only the actor's own and public templates are returned
+func TestClient_ListWorkflowTemplatesForUser(t *testing.T) {
+	c := DefaultTestClient()
+	clearDatabase(t)
+
+	namespace := "onepanel"
+	create := func(actor, name string, isPublic bool) {
+		c.Actor = actor
+		c.CreateWorkflowTemplate(namespace, &WorkflowTemplate{
+			Name:     name,
+			Manifest: defaultWorkflowTemplate,
+			IsPublic: isPublic,
+		})
+	}
+	create("alice", "alice-private", false)
+	create("bob", "bob-private", false)
+	create("bob", "bob-public", true)
+
+	names := func() []string {
+		workflowTemplates, err := c.ListWorkflowTemplatesForUser(namespace)
+		assert.Nil(t, err)
+
+		names := make([]string, 0)
+		for _, wt := range workflowTemplates {
+			names = append(names, wt.Name)
+		}
+		return names
+	}
+
+	c.Actor = "alice"
+	assert.ElementsMatch(t, []string{"alice-private", "bob-public"}, names())
+
+	// a client without an actor only gets the public templates
+	c.Actor = ""
+	assert.ElementsMatch(t, []string{"bob-public"}, names())
+}
+
+// TestClient_GetWorkflowTemplateVersionAsOf makes sure the version that was latest at the time is returned
+func TestClient_GetWorkflowTemplateVersionAsOf(t *testing.T) {
+	c := DefaultTestClient()
+	clearDatabase(t)
+
+	namespace := "onepanel"
+	created, _ := c.CreateWorkflowTemplate(namespace, &WorkflowTemplate{
+		Name:     "test",
+		Manifest: defaultWorkflowTemplate,
+	})
+	firstVersion := created.Version
+
+	_, err := c.GetWorkflowTemplateVersionAsOf(namespace, created.UID, time.Now().Add(-time.Hour))
+	userErr, ok := err.(*util.UserError)
+	assert.True(t, ok)
+	assert.Equal(t, codes.NotFound, userErr.Code)
+
+	time.Sleep(10 * time.Millisecond)
+	betweenVersions := time.Now()
+	time.Sleep(10 * time.Millisecond)
+
+	c.CreateWorkflowTemplateVersion(namespace, &WorkflowTemplate{
+		UID:      created.UID,
+		Name:     created.Name,
+		Manifest: defaultWorkflowTemplate,
+	})
+
+	asOf, err := c.GetWorkflowTemplateVersionAsOf(namespace, created.UID, betweenVersions)
+	assert.Nil(t, err)
+	assert.Equal(t, firstVersion, asOf.Version)
+
+	asOf, err = c.GetWorkflowTemplateVersionAsOf(namespace, created.UID, time.Now())
+	assert.Nil(t, err)
+	assert.NotEqual(t, firstVersion, asOf.Version)
+}
+
+// TestClient_GetWorkflowTemplateVersionManifestStream makes sure the streamed manifest is the stored one
+func TestClient_GetWorkflowTemplateVersionManifestStream(t *testing.T) {
+	c := DefaultTestClient()
+	clearDatabase(t)
+
+	namespace := "onepanel"
+	created, _ := c.CreateWorkflowTemplate(namespace, &WorkflowTemplate{
+		Name:     "test",
+		Manifest: defaultWorkflowTemplate,
+	})
+
+	manifest, err := c.GetWorkflowTemplateVersionManifest(namespace, created.UID, created.Version)
+	assert.Nil(t, err)
+	assert.Equal(t, defaultWorkflowTemplate, string(manifest))
+
+	buffer := &strings.Builder{}
+	err = c.GetWorkflowTemplateVersionManifestStream(namespace, created.UID, 0, buffer)
+	assert.Nil(t, err)
+	assert.Equal(t, defaultWorkflowTemplate, buffer.String())
+
+	err = c.GetWorkflowTemplateVersionManifestStream(namespace, created.UID, 1, buffer)
+	userErr, ok := err.(*util.UserError)
+	assert.True(t, ok)
+	assert.Equal(t, codes.NotFound, userErr.Code)
+}
+
+// TestClient_WorkflowTemplateMatchesArgo makes sure edits made to the argo workflow template directly are detected
+func TestClient_WorkflowTemplateMatchesArgo(t *testing.T) {
+	c := DefaultTestClient()
+	clearDatabase(t)
+
+	namespace := "onepanel"
+	created, _ := c.CreateWorkflowTemplate(namespace, &WorkflowTemplate{
+		Name:     "test",
+		Manifest: defaultWorkflowTemplate,
+	})
+
+	matches, diff, err := c.WorkflowTemplateMatchesArgo(namespace, created.UID, 0)
+	assert.Nil(t, err)
+	assert.True(t, matches)
+	assert.Empty(t, diff)
+
+	argoWft, _ := c.getArgoWorkflowTemplate(namespace, created.UID, "latest")
+	argoWft.Spec.Entrypoint = "edited"
+	c.ArgoprojV1alpha1().WorkflowTemplates(namespace).Update(argoWft)
+
+	matches, diff, err = c.WorkflowTemplateMatchesArgo(namespace, created.UID, 0)
+	assert.Nil(t, err)
+	assert.False(t, matches)
+	assert.Contains(t, diff, "+entrypoint: edited")
+}
+
+// TestClient_GetWorkflowTemplateLabelsOrEmpty makes sure a missing argo workflow template results in empty labels
+func TestClient_GetWorkflowTemplateLabelsOrEmpty(t *testing.T) {
+	c := DefaultTestClient()
+
+	_, err := c.GetWorkflowTemplateLabels("onepanel", "missing", label.TagPrefix, 0)
+	assert.NotNil(t, err)
+
+	labels, err := c.GetWorkflowTemplateLabelsOrEmpty("onepanel", "missing", label.TagPrefix, 0)
+	assert.Nil(t, err)
+	assert.NotNil(t, labels)
+	assert.Empty(t, labels)
+}
+
+// TestClient_CreateWorkflowTemplate_EmptyManifest makes sure empty manifests are rejected before they are parsed
+func TestClient_CreateWorkflowTemplate_EmptyManifest(t *testing.T) {
+	c := DefaultTestClient()
+
+	_, err := c.CreateWorkflowTemplate("onepanel", &WorkflowTemplate{
+		Name:     "test",
+		Manifest: " \n\t",
+	})
+	userErr, ok := err.(*util.UserError)
+	assert.True(t, ok)
+	assert.Equal(t, codes.InvalidArgument, userErr.Code)
+	assert.Equal(t, "manifest is empty", userErr.Message)
+
+	_, err = c.CreateWorkflowTemplateVersion("onepanel", &WorkflowTemplate{
+		UID:      "test",
+		Name:     "test",
+		Manifest: "",
+	})
+	userErr, ok = err.(*util.UserError)
+	assert.True(t, ok)
+	assert.Equal(t, codes.InvalidArgument, userErr.Code)
+}
+
+// TestClient_ListWorkflowTemplates_LastVersionedAt makes sure the creation time of the newest version is listed
+func TestClient_ListWorkflowTemplates_LastVersionedAt(t *testing.T) {
+	c := DefaultTestClient()
+	clearDatabase(t)
+
+	namespace := "onepanel"
+	created, _ := c.CreateWorkflowTemplate(namespace, &WorkflowTemplate{
+		Name:     "test",
+		Manifest: defaultWorkflowTemplate,
+	})
+
+	time.Sleep(10 * time.Millisecond)
+	created.Manifest = defaultWorkflowTemplate
+	_, err := c.CreateWorkflowTemplateVersion(namespace, created)
+	assert.Nil(t, err)
+
+	workflowTemplates, err := c.ListWorkflowTemplates(namespace, &request.Request{})
+	assert.Nil(t, err)
+	assert.Len(t, workflowTemplates, 1)
+
+	latest, err := c.GetWorkflowTemplate(namespace, created.UID, 0)
+	assert.Nil(t, err)
+	if assert.NotNil(t, workflowTemplates[0].LastVersionedAt) {
+		assert.True(t, workflowTemplates[0].LastVersionedAt.After(workflowTemplates[0].CreatedAt))
+		assert.Equal(t, latest.CreatedAt.Unix(), workflowTemplates[0].LastVersionedAt.Unix())
+	}
+}
+
+// TestClient_SetWorkflowTemplateDeprecated makes sure deprecated templates are flagged and listed last
+func TestClient_SetWorkflowTemplateDeprecated(t *testing.T) {
+	c := DefaultTestClient()
+	clearDatabase(t)
+
+	namespace := "onepanel"
+	deprecated, _ := c.CreateWorkflowTemplate(namespace, &WorkflowTemplate{
+		Name:     "deprecated",
+		Manifest: defaultWorkflowTemplate,
+	})
+	c.CreateWorkflowTemplate(namespace, &WorkflowTemplate{
+		Name:     "current",
+		Manifest: defaultWorkflowTemplate,
+	})
+
+	assert.Nil(t, c.SetWorkflowTemplateDeprecated(namespace, deprecated.UID, true))
+
+	workflowTemplate, err := c.GetWorkflowTemplate(namespace, deprecated.UID, 0)
+	assert.Nil(t, err)
+	assert.True(t, workflowTemplate.IsDeprecated)
+
+	workflowTemplates, err := c.ListWorkflowTemplates(namespace, &request.Request{})
+	assert.Nil(t, err)
+	if assert.Len(t, workflowTemplates, 2) {
+		assert.Equal(t, "current", workflowTemplates[0].Name)
+		assert.Equal(t, "deprecated", workflowTemplates[1].Name)
+		assert.True(t, workflowTemplates[1].IsDeprecated)
+	}
+
+	err = c.SetWorkflowTemplateDeprecated(namespace, "missing", true)
+	userErr, ok := err.(*util.UserError)
+	assert.True(t, ok)
+	assert.Equal(t, codes.NotFound, userErr.Code)
+}
+
+// TestClient_GetWorkflowTemplate_VersionResolution makes sure callers can tell latest from pinned versions
+func TestClient_GetWorkflowTemplate_VersionResolution(t *testing.T) {
+	c := DefaultTestClient()
+	clearDatabase(t)
+
+	namespace := "onepanel"
+	created, _ := c.CreateWorkflowTemplate(namespace, &WorkflowTemplate{
+		Name:     "test",
+		Manifest: defaultWorkflowTemplate,
+	})
+
+	latest, err := c.GetWorkflowTemplate(namespace, created.UID, 0)
+	assert.Nil(t, err)
+	assert.True(t, latest.ResolvedLatest)
+	assert.Equal(t, int64(0), latest.RequestedVersion)
+	assert.Equal(t, created.Version, latest.Version)
+
+	pinned, err := c.GetWorkflowTemplate(namespace, created.UID, created.Version)
+	assert.Nil(t, err)
+	assert.False(t, pinned.ResolvedLatest)
+	assert.Equal(t, created.Version, pinned.RequestedVersion)
+	assert.Equal(t, created.Version, pinned.LatestVersion)
+
+	newer, err := c.CreateWorkflowTemplateVersion(namespace, created)
+	assert.Nil(t, err)
+
+	pinned, err = c.GetWorkflowTemplate(namespace, created.UID, pinned.Version)
+	assert.Nil(t, err)
+	assert.Equal(t, newer.Version, pinned.LatestVersion)
+	assert.Less(t, pinned.RequestedVersion, pinned.LatestVersion)
+
+	metadata, err := c.GetWorkflowTemplateWithOptions(namespace, created.UID, pinned.Version, &GetWorkflowTemplateOptions{MetadataOnly: true})
+	assert.Nil(t, err)
+	assert.Equal(t, newer.Version, metadata.LatestVersion)
+}
+
+// TestClient_CreateWorkflowTemplatesBatch makes sure a failing workflow template does not stop the others
+func TestClient_CreateWorkflowTemplatesBatch(t *testing.T) {
+	c := DefaultTestClient()
+	clearDatabase(t)
+
+	namespace := "onepanel"
+	workflowTemplates := []*WorkflowTemplate{
+		{Name: "first", Manifest: defaultWorkflowTemplate},
+		{Name: "invalid", Manifest: "entrypoint: [invalid"},
+		{Name: "second", Manifest: defaultWorkflowTemplate},
+		{Name: "empty", Manifest: ""},
+	}
+
+	results, errs := c.CreateWorkflowTemplatesBatch(namespace, workflowTemplates)
+	assert.Len(t, results, 4)
+	assert.Len(t, errs, 4)
+
+	assert.Nil(t, errs[0])
+	assert.Equal(t, "first", results[0].Name)
+	assert.NotNil(t, errs[1])
+	assert.Nil(t, results[1])
+	assert.Nil(t, errs[2])
+	assert.Equal(t, "second", results[2].Name)
+	assert.NotNil(t, errs[3])
+
+	count, err := c.CountWorkflowTemplates(namespace, &request.Request{})
+	assert.Nil(t, err)
+	assert.Equal(t, 2, count)
+}
+
+// TestClient_GetWorkflowTemplateWithOptions_MetadataOnly makes sure only the database data is loaded
+func TestClient_GetWorkflowTemplateWithOptions_MetadataOnly(t *testing.T) {
+	c := DefaultTestClient()
+	clearDatabase(t)
+
+	namespace := "onepanel"
+	created, _ := c.CreateWorkflowTemplate(namespace, &WorkflowTemplate{
+		Name:     "test",
+		Manifest: defaultWorkflowTemplate,
+		Labels:   types.JSONLabels{"team": "ml"},
+	})
+
+	workflowTemplate, err := c.GetWorkflowTemplateWithOptions(namespace, created.UID, 0, &GetWorkflowTemplateOptions{MetadataOnly: true})
+	assert.Nil(t, err)
+	assert.Equal(t, "test", workflowTemplate.Name)
+	assert.Equal(t, created.Version, workflowTemplate.Version)
+	assert.Equal(t, "ml", workflowTemplate.Labels["team"])
+	assert.Empty(t, workflowTemplate.Manifest)
+	assert.Nil(t, workflowTemplate.ArgoWorkflowTemplate)
+
+	_, err = c.GetWorkflowTemplateWithOptions(namespace, "missing", 0, &GetWorkflowTemplateOptions{MetadataOnly: true})
+	userErr, ok := err.(*util.UserError)
+	assert.True(t, ok)
+	assert.Equal(t, codes.NotFound, userErr.Code)
+
+	// a workflow template missing from the database is not self healed, as that calls argo
+	clearDatabase(t)
+	c.WorkflowTemplateSelfHeal = SelfHealReinsert
+	argoCalls := 0
+	c.argoprojV1alpha1.(*argoFakeV1alpha1.FakeArgoprojV1alpha1).PrependReactor("*", "workflowtemplates",
+		func(action k8stesting.Action) (bool, runtime.Object, error) {
+			argoCalls++
+			return false, nil, nil
+		})
+
+	_, err = c.GetWorkflowTemplateWithOptions(namespace, created.UID, 0, &GetWorkflowTemplateOptions{MetadataOnly: true})
+	userErr, ok = err.(*util.UserError)
+	if assert.True(t, ok) {
+		assert.Equal(t, codes.NotFound, userErr.Code)
+	}
+	assert.Equal(t, 0, argoCalls)
+}
+
+// TestClient_GetWorkflowTemplateWithOptions_MaskSensitiveParameters makes sure the sensitive values are masked everywhere they are returned
+func TestClient_GetWorkflowTemplateWithOptions_MaskSensitiveParameters(t *testing.T) {
+	c := DefaultTestClient()
+	clearDatabase(t)
+
+	namespace := "onepanel"
+	secret := "raw-secret-token"
+	created, err := c.CreateWorkflowTemplate(namespace, &WorkflowTemplate{
+		Name: "test",
+		Manifest: strings.Replace(defaultWorkflowTemplate, "    parameters:\n",
+			"    parameters:\n    - name: api-token\n      value: "+secret+"\n      sensitive: true\n", 1),
+	})
+	assert.Nil(t, err)
+
+	workflowTemplate, err := c.GetWorkflowTemplateWithOptions(namespace, created.UID, 0, &GetWorkflowTemplateOptions{
+		IncludeParameters:       true,
+		MaskSensitiveParameters: true,
+	})
+	assert.Nil(t, err)
+
+	data, err := json.Marshal(workflowTemplate)
+	assert.Nil(t, err)
+	assert.NotContains(t, string(data), secret)
+
+	manifest, err := workflowTemplate.FormatManifest()
+	assert.Nil(t, err)
+	assert.Contains(t, manifest, "api-token")
+	assert.NotContains(t, manifest, secret)
+
+	manifestBytes, err := workflowTemplate.GetWorkflowManifestBytes()
+	assert.Nil(t, err)
+	assert.NotContains(t, string(manifestBytes), secret)
+}
+
+// TestNextWorkflowTemplateVersion makes sure versions are strictly increasing even if the clock goes backwards
+func TestNextWorkflowTemplateVersion(t *testing.T) {
+	assert.Equal(t, int64(200), nextWorkflowTemplateVersion(200, 100))
+	assert.Equal(t, int64(101), nextWorkflowTemplateVersion(100, 100))
+	assert.Equal(t, int64(101), nextWorkflowTemplateVersion(50, 100))
+	assert.Equal(t, int64(1), nextWorkflowTemplateVersion(0, 0))
+}
+
+// TestClient_GetWorkflowTemplateWithOptions_IncludeParameters makes sure parameters are loaded with and without the manifest
+func TestClient_GetWorkflowTemplateWithOptions_IncludeParameters(t *testing.T) {
+	c := DefaultTestClient()
+	clearDatabase(t)
+
+	namespace := "onepanel"
+	created, _ := c.CreateWorkflowTemplate(namespace, &WorkflowTemplate{
+		Name:     "test",
+		Manifest: defaultWorkflowTemplate,
+	})
+
+	expected, err := ParseParametersFromManifest([]byte(defaultWorkflowTemplate))
+	assert.Nil(t, err)
+
+	for _, metadataOnly := range []bool{false, true} {
+		workflowTemplate, err := c.GetWorkflowTemplateWithOptions(namespace, created.UID, 0, &GetWorkflowTemplateOptions{
+			MetadataOnly:      metadataOnly,
+			IncludeParameters: true,
+		})
+		assert.Nil(t, err)
+		assert.Equal(t, expected, workflowTemplate.Parameters)
+	}
+}
+
+// TestClient_ListWorkflowTemplatesByCategory makes sure templates are grouped by their category
+func TestClient_ListWorkflowTemplatesByCategory(t *testing.T) {
+	c := DefaultTestClient()
+	clearDatabase(t)
+
+	namespace := "onepanel"
+	create := func(name, category string) {
+		c.CreateWorkflowTemplate(namespace, &WorkflowTemplate{
+			Name:     name,
+			Manifest: defaultWorkflowTemplate,
+			Category: category,
+		})
+	}
+	create("train", "training")
+	create("tune", "training")
+	create("misc", "")
+
+	categories, err := c.ListWorkflowTemplateCategories(namespace)
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"", "training"}, categories)
+
+	workflowTemplates, err := c.ListWorkflowTemplatesByCategory(namespace, "training")
+	assert.Nil(t, err)
+	names := make([]string, 0)
+	for _, wt := range workflowTemplates {
+		names = append(names, wt.Name)
+		assert.Equal(t, "training", wt.Category)
+	}
+	assert.ElementsMatch(t, []string{"train", "tune"}, names)
+
+	workflowTemplates, err = c.ListWorkflowTemplatesByCategory(namespace, "")
+	assert.Nil(t, err)
+	if assert.Len(t, workflowTemplates, 1) {
+		assert.Equal(t, "misc", workflowTemplates[0].Name)
+	}
+}
+
+// TestClient_GetWorkflowTemplateInjectionReport makes sure authored and injected data are told apart
+func TestClient_GetWorkflowTemplateInjectionReport(t *testing.T) {
+	c := DefaultTestClient()
+
+	wt := &WorkflowTemplate{
+		Manifest: `entrypoint: main
+arguments:
+  parameters:
+  - name: sys-node-pool
+    value: default
+  - name: sys-custom
+    value: custom
+  - name: epochs
+    value: "10"
+templates:
+- name: main
+  container:
+    image: alpine
+`,
+		ArgoWorkflowTemplate: &v1alpha1.WorkflowTemplate{
+			ObjectMeta: v1.ObjectMeta{
+				Annotations: map[string]string{
+					label.AnnotationPrefix + "owner": "data-team",
+					"epochs":                         "{}",
+					"kubectl.kubernetes.io/last-applied-configuration": "{}",
+				},
+			},
+		},
+	}
+
+	report, err := c.GetWorkflowTemplateInjectionReport("onepanel", wt)
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"sys-node-pool", "sys-custom", "epochs"}, report.AuthoredParameters)
+	assert.Equal(t, []string{"sys-node-pool"}, report.SystemParameters)
+	assert.Empty(t, report.DefaultedParameters)
+	assert.Equal(t, []string{"owner"}, report.AuthoredAnnotations)
+	assert.Equal(t, []string{label.AnnotationPrefix + "owner", "epochs"}, report.InjectedAnnotations)
+}
+
+// TestClient_WaitForWorkflowTemplate makes sure waiting stops once the argo workflow template is visible, or times out
+func TestClient_WaitForWorkflowTemplate(t *testing.T) {
+	c := DefaultTestClient()
+	namespace := "onepanel"
+
+	err := c.WaitForWorkflowTemplate(namespace, "missing", 0, 100*time.Millisecond)
+	userErr, ok := err.(*util.UserError)
+	assert.True(t, ok)
+	assert.Equal(t, codes.DeadlineExceeded, userErr.Code)
+
+	argoWft, err := createArgoWorkflowTemplate(&WorkflowTemplate{
+		UID:      "test",
+		Name:     "test",
+		Manifest: defaultWorkflowTemplate,
+	}, 1, nil)
+	assert.Nil(t, err)
+
+	go func() {
+		time.Sleep(50 * time.Millisecond)
+		c.ArgoprojV1alpha1().WorkflowTemplates(namespace).Create(argoWft)
+	}()
+
+	assert.Nil(t, c.WaitForWorkflowTemplate(namespace, "test", 1, time.Second))
+}
+
+// TestClient_PurgeArchivedWorkflowTemplates makes sure only templates archived long enough ago are deleted
+func TestClient_PurgeArchivedWorkflowTemplates(t *testing.T) {
+	c := DefaultTestClient()
+	clearDatabase(t)
+
+	namespace := "onepanel"
+	old, _ := c.CreateWorkflowTemplate(namespace, &WorkflowTemplate{
+		Name:     "old",
+		Manifest: defaultWorkflowTemplate,
+	})
+	recent, _ := c.CreateWorkflowTemplate(namespace, &WorkflowTemplate{
+		Name:     "recent",
+		Manifest: defaultWorkflowTemplate,
+	})
+	c.CreateWorkflowTemplate(namespace, &WorkflowTemplate{
+		Name:     "active",
+		Manifest: defaultWorkflowTemplate,
+	})
+
+	_, err := c.ArchiveWorkflowTemplate(namespace, old.UID)
+	assert.Nil(t, err)
+	_, err = c.ArchiveWorkflowTemplate(namespace, recent.UID)
+	assert.Nil(t, err)
+
+	_, err = c.DB.Exec("UPDATE workflow_templates SET archived_at = $1 WHERE id = $2", time.Now().UTC().Add(-48*time.Hour), old.ID)
+	assert.Nil(t, err)
+
+	purged, err := c.PurgeArchivedWorkflowTemplates(namespace, 24*time.Hour)
+	assert.Nil(t, err)
+	assert.Equal(t, 1, purged)
+
+	count := 0
+	err = c.DB.Get(&count, "SELECT COUNT(*) FROM workflow_templates WHERE namespace = $1", namespace)
+	assert.Nil(t, err)
+	assert.Equal(t, 2, count)
+
+	_, err = c.ArgoprojV1alpha1().WorkflowTemplates(namespace).Get(old.ArgoWorkflowTemplate.Name, v1.GetOptions{})
+	assert.NotNil(t, err)
+
+	// the audit log outlives the purged workflow template
+	entries, err := c.GetWorkflowTemplateAuditLog(namespace, old.UID)
+	assert.Nil(t, err)
+	assert.NotEmpty(t, entries)
+	for _, entry := range entries {
+		assert.Nil(t, entry.WorkflowTemplateID)
+	}
+}
+
+// TestClient_ListWorkflowTemplatesByFailureRate makes sure templates with more failing executions are first
+func TestClient_ListWorkflowTemplatesByFailureRate(t *testing.T) {
+	c := DefaultTestClient()
+	clearDatabase(t)
+
+	namespace := "onepanel"
+	reliable, _ := c.CreateWorkflowTemplate(namespace, &WorkflowTemplate{
+		Name:     "reliable",
+		Manifest: defaultWorkflowTemplate,
+	})
+	flaky, _ := c.CreateWorkflowTemplate(namespace, &WorkflowTemplate{
+		Name:     "flaky",
+		Manifest: defaultWorkflowTemplate,
+	})
+	c.CreateWorkflowTemplate(namespace, &WorkflowTemplate{
+		Name:     "unused",
+		Manifest: defaultWorkflowTemplate,
+	})
+
+	for _, name := range []string{"reliable-1", "reliable-2"} {
+		c.CreateWorkflowExecution(namespace, &WorkflowExecution{Name: name}, reliable)
+	}
+	for _, name := range []string{"flaky-1", "flaky-2"} {
+		c.CreateWorkflowExecution(namespace, &WorkflowExecution{Name: name}, flaky)
+	}
+
+	_, err := c.DB.Exec(`UPDATE workflow_executions SET phase = 'Failed', finished_at = NOW() WHERE name = 'flaky-1'`)
+	assert.Nil(t, err)
+
+	workflowTemplates, err := c.ListWorkflowTemplatesByFailureRate(namespace)
+	assert.Nil(t, err)
+	if assert.Len(t, workflowTemplates, 3) {
+		assert.Equal(t, "flaky", workflowTemplates[0].Name)
+		assert.Equal(t, int32(1), workflowTemplates[0].WorkflowExecutionStatisticReport.Failed)
+		assert.Equal(t, int32(2), workflowTemplates[0].WorkflowExecutionStatisticReport.Total)
+		assert.Equal(t, "reliable", workflowTemplates[1].Name)
+		assert.Equal(t, "unused", workflowTemplates[2].Name)
+		assert.Equal(t, int32(0), workflowTemplates[2].WorkflowExecutionStatisticReport.Total)
+	}
+}
+
+// TestClient_ListWorkflowTemplateSummaries makes sure summaries have the latest version and version count
+func TestClient_ListWorkflowTemplateSummaries(t *testing.T) {
+	c := DefaultTestClient()
+	clearDatabase(t)
+
+	namespace := "onepanel"
+	created, _ := c.CreateWorkflowTemplate(namespace, &WorkflowTemplate{
+		Name:     "test",
+		Manifest: defaultWorkflowTemplate,
+		Labels:   types.JSONLabels{"team": "ml"},
+	})
+	created.Manifest = defaultWorkflowTemplate
+	latest, err := c.CreateWorkflowTemplateVersion(namespace, created)
+	assert.Nil(t, err)
+
+	summaries, err := c.ListWorkflowTemplateSummaries(namespace, &request.Request{})
+	assert.Nil(t, err)
+	if assert.Len(t, summaries, 1) {
+		assert.Equal(t, created.UID, summaries[0].UID)
+		assert.Equal(t, "test", summaries[0].Name)
+		assert.Equal(t, latest.Version, summaries[0].Version)
+		assert.Equal(t, int64(2), summaries[0].Versions)
+		assert.Equal(t, "ml", summaries[0].Labels["team"])
+	}
+}
+
+// TestClient_CreateWorkflowTemplateWithIdempotencyKey makes sure a retried create returns the same template
+func TestClient_CreateWorkflowTemplateWithIdempotencyKey(t *testing.T) {
+	c := DefaultTestClient()
+	clearDatabase(t)
+
+	namespace := "onepanel"
+	created, err := c.CreateWorkflowTemplateWithIdempotencyKey(namespace, "request-1", &WorkflowTemplate{
+		Name:     "test",
+		Manifest: defaultWorkflowTemplate,
+	})
+	assert.Nil(t, err)
+
+	retried, err := c.CreateWorkflowTemplateWithIdempotencyKey(namespace, "request-1", &WorkflowTemplate{
+		Name:     "test",
+		Manifest: defaultWorkflowTemplate,
+	})
+	assert.Nil(t, err)
+	assert.Equal(t, created.UID, retried.UID)
+	assert.Equal(t, created.Version, retried.Version)
+
+	count, err := c.CountWorkflowTemplates(namespace, &request.Request{})
+	assert.Nil(t, err)
+	assert.Equal(t, 1, count)
+
+	// an archived workflow template does not hold on to its key
+	_, err = c.ArchiveWorkflowTemplate(namespace, created.UID)
+	assert.Nil(t, err)
+
+	recreated, err := c.CreateWorkflowTemplateWithIdempotencyKey(namespace, "request-1", &WorkflowTemplate{
+		Name:     "test",
+		Manifest: defaultWorkflowTemplate,
+	})
+	assert.Nil(t, err)
+	assert.NotEqual(t, created.Version, recreated.Version)
+
+	count, err = c.CountWorkflowTemplates(namespace, &request.Request{})
+	assert.Nil(t, err)
+	assert.Equal(t, 1, count)
+}
+
+// TestClient_GetAllWorkflowTemplateVersionManifests makes sure every version's manifest is returned
+func TestClient_GetAllWorkflowTemplateVersionManifests(t *testing.T) {
+	c := DefaultTestClient()
+	clearDatabase(t)
+
+	namespace := "onepanel"
+	created, _ := c.CreateWorkflowTemplate(namespace, &WorkflowTemplate{
+		Name:     "test",
+		Manifest: defaultWorkflowTemplate,
+	})
+	firstVersion := created.Version
+
+	secondManifest := strings.Replace(defaultWorkflowTemplate, "entrypoint: main", "entrypoint: main\nparallelism: 2", 1)
+	created.Manifest = secondManifest
+	second, err := c.CreateWorkflowTemplateVersion(namespace, created)
+	assert.Nil(t, err)
+
+	manifests, err := c.GetAllWorkflowTemplateVersionManifests(namespace, created.UID)
+	assert.Nil(t, err)
+	assert.Len(t, manifests, 2)
+	assert.Equal(t, defaultWorkflowTemplate, string(manifests[firstVersion]))
+	assert.Equal(t, secondManifest, string(manifests[second.Version]))
+
+	_, err = c.GetAllWorkflowTemplateVersionManifests(namespace, "missing")
+	userErr, ok := err.(*util.UserError)
+	assert.True(t, ok)
+	assert.Equal(t, codes.NotFound, userErr.Code)
+}
+
+// TestClient_GetPreviousWorkflowTemplateVersion makes sure the neighboring earlier version is returned
+func TestClient_GetPreviousWorkflowTemplateVersion(t *testing.T) {
+	c := DefaultTestClient()
+	clearDatabase(t)
+
+	namespace := "onepanel"
+	created, _ := c.CreateWorkflowTemplate(namespace, &WorkflowTemplate{
+		Name:     "test",
+		Manifest: defaultWorkflowTemplate,
+	})
+	firstVersion := created.Version
+
+	// CreateWorkflowTemplateVersion updates and returns the workflow template it is given, so the versions are copied
+	second, err := c.CreateWorkflowTemplateVersion(namespace, created)
+	assert.Nil(t, err)
+	secondVersion := second.Version
+	third, err := c.CreateWorkflowTemplateVersion(namespace, created)
+	assert.Nil(t, err)
+	thirdVersion := third.Version
+
+	previous, err := c.GetPreviousWorkflowTemplateVersion(namespace, created.UID, thirdVersion)
+	assert.Nil(t, err)
+	assert.Equal(t, secondVersion, previous.Version)
+
+	previous, err = c.GetPreviousWorkflowTemplateVersion(namespace, created.UID, secondVersion)
+	assert.Nil(t, err)
+	assert.Equal(t, firstVersion, previous.Version)
+
+	_, err = c.GetPreviousWorkflowTemplateVersion(namespace, created.UID, firstVersion)
+	userErr, ok := err.(*util.UserError)
+	assert.True(t, ok)
+	assert.Equal(t, codes.NotFound, userErr.Code)
+}
+
+// TestClient_ValidateAllWorkflowTemplateVersions makes sure each stored version gets its own validation result
+func TestClient_ValidateAllWorkflowTemplateVersions(t *testing.T) {
+	c := DefaultTestClient()
+	clearDatabase(t)
+
+	namespace := "onepanel"
+	created, _ := c.CreateWorkflowTemplate(namespace, &WorkflowTemplate{
+		Name:     "test",
+		Manifest: defaultWorkflowTemplate,
+	})
+	firstVersion := created.Version
+
+	second, err := c.CreateWorkflowTemplateVersion(namespace, created)
+	assert.Nil(t, err)
+
+	// simulate a stored resolved manifest that no longer validates
+	_, err = c.DB.Exec(`UPDATE workflow_template_versions SET resolved_manifest = 'entrypoint: missing' WHERE version = $1`, firstVersion)
+	assert.Nil(t, err)
+	// a version without a resolved manifest is validated with its manifest
+	_, err = c.DB.Exec(`UPDATE workflow_template_versions SET resolved_manifest = '' WHERE version = $1`, second.Version)
+	assert.Nil(t, err)
+
+	results, err := c.ValidateAllWorkflowTemplateVersions(namespace, created.UID)
+	assert.Nil(t, err)
+	assert.Len(t, results, 2)
+	assert.NotNil(t, results[firstVersion])
+	assert.Nil(t, results[second.Version])
+
+	_, err = c.ValidateAllWorkflowTemplateVersions(namespace, "missing")
+	assert.NotNil(t, err)
+}
+
+// TestClient_MoveWorkflowTemplatesToNamespace makes sure workflow templates and their argo workflow templates are moved
+func TestClient_MoveWorkflowTemplatesToNamespace(t *testing.T) {
+	c := DefaultTestClient()
+	clearDatabase(t)
+
+	src := "onepanel"
+	dst := "onepanel-moved"
+	c.NamespaceDefaultLabels = map[string]map[string]string{
+		src: {"team": "research", "cost-center": "src"},
+		dst: {"cost-center": "dst"},
+	}
+	created, err := c.CreateWorkflowTemplate(src, &WorkflowTemplate{
+		Name:     "test",
+		Manifest: defaultWorkflowTemplate,
+		Labels:   map[string]string{"stage": "prod"},
+	})
+	assert.Nil(t, err)
+	_, err = c.CreateWorkflowTemplateVersion(src, created)
+	assert.Nil(t, err)
+
+	moved, err := c.MoveWorkflowTemplatesToNamespace(src, dst)
+	assert.Nil(t, err)
+	assert.Equal(t, 1, moved)
+
+	movedTemplate, err := c.GetWorkflowTemplate(dst, created.UID, 0)
+	assert.Nil(t, err)
+	assert.Equal(t, created.Version, movedTemplate.Version)
+
+	argoWorkflowTemplates, err := c.listArgoWorkflowTemplates(dst, created.UID)
+	assert.Nil(t, err)
+	assert.Len(t, *argoWorkflowTemplates, 2)
+
+	// the default labels of src are replaced by the ones of dst, the workflow template's own labels are kept
+	for _, argoWft := range *argoWorkflowTemplates {
+		assert.Equal(t, "prod", argoWft.Labels[label.TagPrefix+"stage"])
+		assert.Equal(t, "dst", argoWft.Labels[label.TagPrefix+"cost-center"])
+		assert.NotContains(t, argoWft.Labels, label.TagPrefix+"team")
+	}
+
+	argoWorkflowTemplates, err = c.listArgoWorkflowTemplates(src, created.UID)
+	assert.Nil(t, err)
+	assert.Len(t, *argoWorkflowTemplates, 0)
+
+	_, err = c.GetWorkflowTemplate(src, created.UID, 0)
+	assert.NotNil(t, err)
+
+	// moving back into a namespace that has a workflow template with the same uid fails
+	_, err = c.CreateWorkflowTemplate(src, &WorkflowTemplate{
+		Name:     "test",
+		Manifest: defaultWorkflowTemplate,
+	})
+	assert.Nil(t, err)
+	_, err = c.MoveWorkflowTemplatesToNamespace(dst, src)
+	userErr, ok := err.(*util.UserError)
+	assert.True(t, ok)
+	assert.Equal(t, codes.AlreadyExists, userErr.Code)
+}
+
+// TestClient_GetWorkflowTemplate_MalformedVersionLabel makes sure the database version is used if the argo label is malformed
+func TestClient_GetWorkflowTemplate_MalformedVersionLabel(t *testing.T) {
+	c := DefaultTestClient()
+	clearDatabase(t)
+
+	namespace := "onepanel"
+	created, err := c.CreateWorkflowTemplate(namespace, &WorkflowTemplate{
+		Name:     "test",
+		Manifest: defaultWorkflowTemplate,
+	})
+	assert.Nil(t, err)
+
+	argoWft, err := c.getArgoWorkflowTemplate(namespace, created.UID, "latest")
+	assert.Nil(t, err)
+	argoWft.Labels[label.Version] = "not-a-number"
+	_, err = c.ArgoprojV1alpha1().WorkflowTemplates(namespace).Update(argoWft)
+	assert.Nil(t, err)
+
+	workflowTemplate, err := c.GetWorkflowTemplate(namespace, created.UID, 0)
+	assert.Nil(t, err)
+	assert.Equal(t, created.Version, workflowTemplate.Version)
+}
+
+// TestClient_GetWorkflowTemplate_SelfHeal makes sure a workflow template missing from the database is recovered from argo
+func TestClient_GetWorkflowTemplate_SelfHeal(t *testing.T) {
+	c := DefaultTestClient()
+	clearDatabase(t)
+
+	namespace := "onepanel"
+	created, err := c.CreateWorkflowTemplate(namespace, &WorkflowTemplate{
+		Name:     "test",
+		Manifest: defaultWorkflowTemplate,
+		Labels:   map[string]string{"stage": "prod"},
+	})
+	assert.Nil(t, err)
+	firstVersion := created.Version
+	_, err = c.CreateWorkflowTemplateVersion(namespace, created)
+	assert.Nil(t, err)
+
+	// lose the database records, the argo workflow templates remain
+	clearDatabase(t)
+
+	_, err = c.GetWorkflowTemplate(namespace, created.UID, 0)
+	userErr, ok := err.(*util.UserError)
+	assert.True(t, ok)
+	assert.Equal(t, codes.NotFound, userErr.Code)
+
+	c.WorkflowTemplateSelfHeal = SelfHealReadThrough
+	readThrough, err := c.GetWorkflowTemplate(namespace, created.UID, firstVersion)
+	assert.Nil(t, err)
+	assert.Equal(t, firstVersion, readThrough.Version)
+	assert.Equal(t, created.Version, readThrough.LatestVersion)
+	assert.Equal(t, "prod", readThrough.Labels["stage"])
+
+	_, err = c.getWorkflowTemplateDB(namespace, created.UID)
+	assert.Equal(t, sql.ErrNoRows, err)
+
+	c.WorkflowTemplateSelfHeal = SelfHealReinsert
+	reinserted, err := c.GetWorkflowTemplate(namespace, created.UID, 0)
+	assert.Nil(t, err)
+	assert.Equal(t, created.Version, reinserted.Version)
+
+	versions, err := c.GetAllWorkflowTemplateVersionManifests(namespace, created.UID)
+	assert.Nil(t, err)
+	assert.Len(t, versions, 2)
+}
+
+// TestClient_AdoptArgoWorkflowTemplate makes sure a labeled argo workflow template is adopted as is,
+// and that nothing is adopted when the argo update fails
+func TestClient_AdoptArgoWorkflowTemplate(t *testing.T) {
+	c := DefaultTestClient()
+	clearDatabase(t)
+
+	namespace := "onepanel"
+	created, err := c.CreateWorkflowTemplate(namespace, &WorkflowTemplate{
+		Name:     "test",
+		Manifest: defaultWorkflowTemplate,
+	})
+	assert.Nil(t, err)
+
+	// lose the database records and the latest label, so adopting has to update argo
+	clearDatabase(t)
+	argoWft := created.ArgoWorkflowTemplate
+	delete(argoWft.Labels, label.VersionLatest)
+	_, err = c.ArgoprojV1alpha1().WorkflowTemplates(namespace).Update(argoWft)
+	assert.Nil(t, err)
+
+	failArgoWorkflowTemplateUpdate(c, 0)
+	_, err = c.AdoptArgoWorkflowTemplate(namespace, argoWft.Name, "test")
+	assert.NotNil(t, err)
+
+	_, err = c.getWorkflowTemplateDB(namespace, created.UID)
+	assert.Equal(t, sql.ErrNoRows, err)
+
+	c.Actor = "admin"
+	adopted, err := c.AdoptArgoWorkflowTemplate(namespace, argoWft.Name, "test")
+	assert.Nil(t, err)
+	assert.Equal(t, created.UID, adopted.UID)
+	assert.Equal(t, created.Version, adopted.Version)
+	assert.Equal(t, "true", adopted.ArgoWorkflowTemplate.Labels[label.VersionLatest])
+
+	adoptedDB, err := c.getWorkflowTemplateDB(namespace, created.UID)
+	assert.Nil(t, err)
+	assert.Equal(t, "admin", adoptedDB.CreatedBy)
+}
+
+// Test_workflowTemplateFromArgo makes sure the onepanel parameter fields are recovered from the argo annotations
+func Test_workflowTemplateFromArgo(t *testing.T) {
+	argoWft := &v1alpha1.WorkflowTemplate{
+		ObjectMeta: v1.ObjectMeta{
+			Annotations: map[string]string{
+				"epochs":                         "displayName: Epochs\nname: epochs\norder: 0\ntype: input.number\nvalue: \"10\"\n",
+				"mode":                           "name: other\norder: 1\n",
+				label.AnnotationPrefix + "owner": "data-team",
+			},
+		},
+		Spec: v1alpha1.WorkflowTemplateSpec{
+			WorkflowSpec: v1alpha1.WorkflowSpec{
+				Entrypoint: "main",
+				Arguments: v1alpha1.Arguments{
+					Parameters: []v1alpha1.Parameter{
+						{Name: "epochs", Value: ptr.String("10")},
+						{Name: "mode", Value: ptr.String("train")},
+					},
+				},
+			},
+		},
+	}
+
+	workflowTemplate, err := workflowTemplateFromArgo("test", argoWft, nil)
+	assert.Nil(t, err)
+
+	parameters, err := ParseParametersFromManifest([]byte(workflowTemplate.Manifest))
+	assert.Nil(t, err)
+	if assert.Len(t, parameters, 2) {
+		assert.Equal(t, "Epochs", *parameters[0].DisplayName)
+		assert.Equal(t, "input.number", parameters[0].Type)
+		// the annotation is for another parameter, so the argo parameter is kept
+		assert.Equal(t, "mode", parameters[1].Name)
+		assert.Equal(t, "train", *parameters[1].Value)
+	}
+	assert.Equal(t, "data-team", workflowTemplate.Annotations["owner"])
+}
+
+// Test_imagePatternLikeFilter makes sure the database filter only uses a literal part of the pattern
+func Test_imagePatternLikeFilter(t *testing.T) {
+	assert.Equal(t, "%pytorch/pytorch:%", imagePatternLikeFilter("pytorch/pytorch:*"))
+	assert.Equal(t, "%/tensorflow/tensorflow%", imagePatternLikeFilter("*/tensorflow/tensorflow[:@]*"))
+	assert.Equal(t, `%my\_image:1*%`, imagePatternLikeFilter(`my_image:1\*`))
+	assert.Equal(t, "%%", imagePatternLikeFilter("*"))
+}
+
+// Test_compileImagePattern makes sure "*" matches across "/" and the rest of the path.Match syntax is kept
+func Test_compileImagePattern(t *testing.T) {
+	matches := func(imagePattern, image string) bool {
+		imageRegexp, err := compileImagePattern(imagePattern)
+		assert.Nil(t, err)
+		return imageRegexp.MatchString(image)
+	}
+
+	assert.True(t, matches("pytorch/pytorch:*", "pytorch/pytorch:1.5"))
+	assert.True(t, matches("*/pytorch:*", "docker.io/pytorch/pytorch:1.5"))
+	assert.True(t, matches("*tensorflow[:@]*", "gcr.io/tensorflow/tensorflow@sha256:abc"))
+	assert.True(t, matches("alpine:3.1?", "alpine:3.12"))
+	assert.True(t, matches("alpine:[^0-2]*", "alpine:3.12"))
+	assert.True(t, matches(`alpine\*`, "alpine*"))
+	assert.False(t, matches("pytorch/pytorch:*", "pytorch/pytorch-nightly:1.5"))
+	assert.False(t, matches("alpine:[^0-3]*", "alpine:3.12"))
+	assert.False(t, matches("alpine.*", "alpine:3.12"))
+
+	_, err := compileImagePattern("pytorch/[")
+	assert.NotNil(t, err)
+}
+
+// TestClient_FindWorkflowTemplatesUsingImage makes sure only latest versions with a matching image are found
+func TestClient_FindWorkflowTemplatesUsingImage(t *testing.T) {
+	c := DefaultTestClient()
+	clearDatabase(t)
+
+	namespace := "onepanel"
+	manifest := func(image string) string {
+		return fmt.Sprintf(`entrypoint: main
+templates:
+- name: main
+  container:
+    image: %v
+  sidecars:
+  - name: logger
+    image: fluent/fluent-bit:1.5
+`, image)
+	}
+
+	train, err := c.CreateWorkflowTemplate(namespace, &WorkflowTemplate{Name: "train", Manifest: manifest("pytorch/pytorch:1.5")})
+	assert.Nil(t, err)
+	_, err = c.CreateWorkflowTemplate(namespace, &WorkflowTemplate{Name: "tune", Manifest: manifest("tensorflow/tensorflow:2.3")})
+	assert.Nil(t, err)
+
+	workflowTemplates, err := c.FindWorkflowTemplatesUsingImage(namespace, "pytorch/pytorch:*")
+	assert.Nil(t, err)
+	if assert.Len(t, workflowTemplates, 1) {
+		assert.Equal(t, train.UID, workflowTemplates[0].UID)
+	}
+
+	workflowTemplates, err = c.FindWorkflowTemplatesUsingImage(namespace, "fluent/fluent-bit:1.5")
+	assert.Nil(t, err)
+	assert.Len(t, workflowTemplates, 2)
+
+	// the latest version no longer uses the image
+	_, err = c.CreateWorkflowTemplateVersion(namespace, &WorkflowTemplate{UID: train.UID, Name: train.Name, Manifest: manifest("pytorch/pytorch:1.6")})
+	assert.Nil(t, err)
+	workflowTemplates, err = c.FindWorkflowTemplatesUsingImage(namespace, "pytorch/pytorch:1.5")
+	assert.Nil(t, err)
+	assert.Len(t, workflowTemplates, 0)
+
+	// images of fragments are found too
+	_, err = c.CreateWorkflowTemplateFragment(namespace, train.UID, &WorkflowTemplateFragment{Name: "main", Manifest: testFragment})
+	assert.Nil(t, err)
+	_, err = c.CreateWorkflowTemplateVersion(namespace, &WorkflowTemplate{
+		UID:      train.UID,
+		Name:     train.Name,
+		Manifest: "entrypoint: main\ntemplates:\n- fragment: main\n",
+	})
+	assert.Nil(t, err)
+	workflowTemplates, err = c.FindWorkflowTemplatesUsingImage(namespace, "alpine")
+	assert.Nil(t, err)
+	if assert.Len(t, workflowTemplates, 1) {
+		assert.Equal(t, train.UID, workflowTemplates[0].UID)
+	}
+
+	_, err = c.FindWorkflowTemplatesUsingImage(namespace, "pytorch/[")
+	userErr, ok := err.(*util.UserError)
+	assert.True(t, ok)
+	assert.Equal(t, codes.InvalidArgument, userErr.Code)
+}
+
+// TestClient_ScanWorkflowTemplates makes sure only workflow templates whose latest version fails validation are returned
+func TestClient_ScanWorkflowTemplates(t *testing.T) {
+	c := DefaultTestClient()
+	clearDatabase(t)
+
+	namespace := "onepanel"
+	valid, err := c.CreateWorkflowTemplate(namespace, &WorkflowTemplate{
+		Name:     "valid",
+		Manifest: defaultWorkflowTemplate,
+	})
+	assert.Nil(t, err)
+	broken, err := c.CreateWorkflowTemplate(namespace, &WorkflowTemplate{
+		Name:     "broken",
+		Manifest: defaultWorkflowTemplate,
+	})
+	assert.Nil(t, err)
+
+	// simulate a latest version that no longer validates
+	_, err = c.DB.Exec(`UPDATE workflow_template_versions SET manifest = 'entrypoint: missing' WHERE version = $1 AND workflow_template_id = $2`, broken.Version, broken.ID)
+	assert.Nil(t, err)
+
+	workflowTemplates, err := c.ScanWorkflowTemplates(namespace)
+	assert.Nil(t, err)
+	if assert.Len(t, workflowTemplates, 1) {
+		assert.Equal(t, broken.UID, workflowTemplates[0].UID)
+		assert.True(t, workflowTemplates[0].IsBroken)
+		assert.NotEmpty(t, workflowTemplates[0].BrokenReason)
+		assert.NotEqual(t, valid.UID, workflowTemplates[0].UID)
+	}
+
+	// the workflow templates can not be validated without the system config, so none are broken
+	workflowTemplates, err = NewTestClient(database).ScanWorkflowTemplates(namespace)
+	assert.Nil(t, workflowTemplates)
+	userErr, ok := err.(*util.UserError)
+	if assert.True(t, ok) {
+		assert.Equal(t, codes.Unavailable, userErr.Code)
+	}
+}
+
+// TestClient_CreateWorkflowTemplate_ArgoName makes sure created workflow templates and versions have the name of their argo workflow template
+func TestClient_CreateWorkflowTemplate_ArgoName(t *testing.T) {
+	c := DefaultTestClient()
+	clearDatabase(t)
+
+	namespace := "onepanel"
+	created, err := c.CreateWorkflowTemplate(namespace, &WorkflowTemplate{
+		Name:     "test",
+		Manifest: defaultWorkflowTemplate,
+	})
+	assert.Nil(t, err)
+	assert.Equal(t, fmt.Sprintf("%v-v%v", created.UID, created.Version), created.ArgoName)
+
+	version, err := c.CreateWorkflowTemplateVersion(namespace, &WorkflowTemplate{
+		UID:      created.UID,
+		Name:     created.Name,
+		Manifest: defaultWorkflowTemplate,
+	})
+	assert.Nil(t, err)
+	assert.Equal(t, fmt.Sprintf("%v-v%v", created.UID, version.Version), version.ArgoName)
+
+	_, err = c.ArgoprojV1alpha1().WorkflowTemplates(namespace).Get(version.ArgoName, v1.GetOptions{})
+	assert.Nil(t, err)
+
+	loaded, err := c.GetWorkflowTemplate(namespace, created.UID, 0)
+	assert.Nil(t, err)
+	assert.Equal(t, version.ArgoName, loaded.ArgoName)
+}
+
+// TestClient_CreateWorkflowTemplate_SystemConfigUnavailable makes sure a system config that can not be loaded is not reported as an invalid manifest
+func TestClient_CreateWorkflowTemplate_SystemConfigUnavailable(t *testing.T) {
+	c := NewTestClient(database)
+
+	_, err := c.CreateWorkflowTemplate("onepanel", &WorkflowTemplate{
+		Name:     "test",
+		Manifest: defaultWorkflowTemplate,
+	})
+	userErr, ok := err.(*util.UserError)
+	assert.True(t, ok)
+	assert.Equal(t, codes.Unavailable, userErr.Code)
+}
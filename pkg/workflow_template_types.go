@@ -2,7 +2,11 @@ package v1
 
 import (
 	"encoding/json"
+	"errors"
+	"fmt"
 	wfv1 "github.com/argoproj/argo/pkg/apis/workflow/v1alpha1"
+	lru "github.com/hashicorp/golang-lru"
+	"github.com/onepanelio/core/pkg/util/label"
 	"github.com/onepanelio/core/pkg/util/mapping"
 	"github.com/onepanelio/core/pkg/util/sql"
 	"github.com/onepanelio/core/pkg/util/types"
@@ -10,9 +14,58 @@ import (
 	log "github.com/sirupsen/logrus"
 	"gopkg.in/yaml.v2"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/rand"
+	"strings"
 	"time"
 )
 
+// ErrEmptyManifest is returned when a workflow template's manifest is empty or only whitespace
+var ErrEmptyManifest = errors.New("manifest is empty")
+
+// WorkflowTemplateLogEntry is a compact representation of a WorkflowTemplate for log fields.
+// It leaves out the manifest, which can be large.
+type WorkflowTemplateLogEntry struct {
+	UID       string
+	Name      string
+	Version   int64
+	CreatedAt time.Time
+}
+
+// LogEntry returns the compact representation of the workflow template to use in log fields.
+func (wt *WorkflowTemplate) LogEntry() *WorkflowTemplateLogEntry {
+	if wt == nil {
+		return nil
+	}
+
+	return &WorkflowTemplateLogEntry{
+		UID:       wt.UID,
+		Name:      wt.Name,
+		Version:   wt.Version,
+		CreatedAt: wt.CreatedAt,
+	}
+}
+
+// logManifest logs the full manifest of the workflow template at debug level.
+// Use it alongside error logs that only have the LogEntry.
+func (wt *WorkflowTemplate) logManifest() {
+	if wt == nil {
+		return
+	}
+
+	log.WithFields(log.Fields{
+		"UID":      wt.UID,
+		"Manifest": wt.Manifest,
+	}).Debug("Workflow template manifest.")
+}
+
+// parametersKeyStringCacheSize is the number of manifests whose parameters are cached by parametersKeyStringCache
+const parametersKeyStringCacheSize = 1024
+
+// parametersKeyStringCache caches the result of GetParametersKeyStringOrdered keyed by the sha256 of the manifest.
+// Identical manifests always yield identical parameters, so entries never need to be invalidated.
+// Once parametersKeyStringCacheSize manifests are cached, the least recently used one is dropped.
+var parametersKeyStringCache, _ = lru.New(parametersKeyStringCacheSize)
+
 // WorkflowTemplate represents a Workflow Template backed by a database row
 // it stores information required to run an execution
 // A Workflow template is uniquely identified by
@@ -22,23 +75,119 @@ type WorkflowTemplate struct {
 	ID                               uint64
 	CreatedAt                        time.Time  `db:"created_at"`
 	ModifiedAt                       *time.Time `db:"modified_at"`
+	UpdatedAt                        time.Time  `db:"updated_at"`        // Last time a version was created or the labels were changed.
+	CreatedBy                        string     `db:"created_by"`        // The Actor of the client that created the template, if any.
+	LastVersionedAt                  *time.Time `db:"last_versioned_at"` // When the newest version was created. Only set when listing.
 	UID                              string
 	Namespace                        string
 	Name                             string
+	Category                         string `db:"category"` // Folder the template is organized under, empty if it is uncategorized.
 	Manifest                         string
 	Version                          int64 // The latest version, unix timestamp
 	Versions                         int64 `db:"versions"` // How many versions there are of this template total.
 	IsLatest                         bool
 	IsArchived                       bool `db:"is_archived"`
 	IsSystem                         bool `db:"is_system"`
+	IsPublic                         bool `db:"is_public"`     // Public templates can be listed and cloned from any namespace.
+	InUse                            bool `db:"in_use"`        // True if a non-archived execution or cron workflow references the template.
+	IsDeprecated                     bool `db:"is_deprecated"` // Deprecated templates can still run, but should not be used for new work.
 	ArgoWorkflowTemplate             *wfv1.WorkflowTemplate
+	ArgoName                         string                           // The name of the argo workflow template of the version, like "my-template-v1600000000".
+	InjectionReport                  *WorkflowTemplateInjectionReport // What Onepanel adds to the template. Only set if asked for, see GetWorkflowTemplateOptions.
 	Labels                           types.JSONLabels
+	Annotations                      map[string]string // User annotations, stored on the argo workflow template under label.AnnotationPrefix.
 	WorkflowExecutionStatisticReport *WorkflowExecutionStatisticReport
 	CronWorkflowsStatisticsReport    *CronWorkflowStatisticReport
 	WorkflowTemplateVersionID        uint64  `db:"workflow_template_version_id"` // Reference to the associated workflow template version.
 	Resource                         *string // utility in case we are specifying a workflow template for a specific resource
 	ResourceUID                      *string // see Resource field
 	Parameters                       []Parameter
+	Fragments                        map[string]string // Named sub-manifests, by name, that "templates" entries can reference. See WrapSpec.
+	RequestedVersion                 int64             // The version asked for when getting the template, 0 if the latest version was asked for.
+	ResolvedLatest                   bool              // True if the latest version was asked for and Version is the version it resolved to.
+	LatestVersion                    int64             `db:"latest_version"` // The latest version, even if an older version was asked for.
+	IdempotencyKey                   string            // Set by CreateWorkflowTemplateWithIdempotencyKey, so a retried create returns the same template.
+	BaseTemplateUID                  string            `db:"base_template_uid"` // The workflow template this one extends, empty if there is none.
+	BaseManifest                     string            // The manifest of the base template, with its own bases merged in. See WrapSpec.
+	IsBroken                         bool              // True if the version no longer passes validation. Only set by ScanWorkflowTemplates.
+	BrokenReason                     string            // The validation error of a broken version.
+}
+
+// WorkflowTemplateSummary is the data of a workflow template needed to list it, without its manifest,
+// argo workflow template or statistics. See ListWorkflowTemplateSummaries.
+type WorkflowTemplateSummary struct {
+	ID        uint64           `db:"id"`
+	UID       string           `db:"uid"`
+	Name      string           `db:"name"`
+	Version   int64            `db:"version"`  // The latest version.
+	Versions  int64            `db:"versions"` // How many versions there are of this template total.
+	CreatedAt time.Time        `db:"created_at"`
+	Labels    types.JSONLabels `db:"labels"`
+}
+
+// WorkflowTemplateStatistics are aggregate counts of the workflow templates in a namespace.
+// The Total counts are for non-archived workflow templates, the Archived counts for archived ones.
+type WorkflowTemplateStatistics struct {
+	TotalTemplates     int `db:"total_templates"`
+	TotalVersions      int `db:"total_versions"`
+	TotalExecutions    int `db:"total_executions"`
+	ArchivedTemplates  int `db:"archived_templates"`
+	ArchivedVersions   int `db:"archived_versions"`
+	ArchivedExecutions int `db:"archived_executions"`
+}
+
+// WorkflowTemplateInjectionReport describes what in a workflow template comes from the user
+// and what Onepanel adds, so users can see why what runs differs from what they submitted.
+type WorkflowTemplateInjectionReport struct {
+	AuthoredParameters  []string // Names of the parameters in the stored manifest.
+	DefaultedParameters []string // Parameters without a value that get the namespace's default, see ApplyNamespaceParameterDefaults.
+	SystemParameters    []string // Parameters whose options are set by Onepanel, e.g. sys-node-pool gets the node pools of the system config.
+	AuthoredAnnotations []string // Keys of the user annotations, without their prefix.
+	InjectedAnnotations []string // Keys of the annotations Onepanel wrote on the argo workflow template: the prefixed user annotations and the parameters.
+}
+
+// GetWorkflowTemplateOptions are options for getting a workflow template
+type GetWorkflowTemplateOptions struct {
+	// MetadataOnly loads just the database data, like the name, version and labels. The manifest, parameters,
+	// annotations and argo workflow template are not loaded, so argo is not called. A workflow template that is
+	// missing from the database is not self healed, see Client.WorkflowTemplateSelfHeal.
+	MetadataOnly bool
+	// IncludeParameters parses the parameters from the manifest of the version, so they include the onepanel fields
+	// like displayName and options. The manifest is loaded for this even if MetadataOnly is set.
+	IncludeParameters bool
+	// IncludeInjectionReport sets the InjectionReport of the workflow template. It requires the manifest, so it
+	// has no effect if MetadataOnly is set.
+	IncludeInjectionReport bool
+	// MaskSensitiveParameters replaces the values of parameters marked as sensitive with SensitiveParameterPlaceholder
+	// in the returned manifest, parameters and argo workflow template. The stored manifest keeps the real values.
+	MaskSensitiveParameters bool
+}
+
+// WorkflowTemplateSelfHeal is what GetWorkflowTemplate does when the database record of a workflow template is missing,
+// but argo workflow templates labeled with its uid still exist. See Client.WorkflowTemplateSelfHeal.
+type WorkflowTemplateSelfHeal string
+
+const (
+	// SelfHealOff returns NotFound, as if the workflow template did not exist. This is the default.
+	SelfHealOff WorkflowTemplateSelfHeal = ""
+	// SelfHealReadThrough returns the workflow template built from the argo workflow template. The database is not changed.
+	SelfHealReadThrough WorkflowTemplateSelfHeal = "read-through"
+	// SelfHealReinsert recreates the database records of the workflow template and its versions from the argo workflow templates.
+	SelfHealReinsert WorkflowTemplateSelfHeal = "reinsert"
+)
+
+// WorkflowTemplateVersionOptions are options for creating a new workflow template version
+type WorkflowTemplateVersionOptions struct {
+	InheritLabels bool // Keep the tag labels of the previous latest version that are not set on the new version.
+	// ClearBaseTemplate removes the base template of the workflow template. Without it, an empty BaseTemplateUID
+	// keeps the current base template, so callers that do not know about base templates do not remove them.
+	ClearBaseTemplate bool
+}
+
+// WorkflowTemplateVersionIndex identifies the latest version of a workflow template without any of its data
+type WorkflowTemplateVersionIndex struct {
+	UID     string
+	Version int64
 }
 
 // GenerateUID generates a uid from the input name and sets it on the workflow template
@@ -53,20 +202,153 @@ func (wt *WorkflowTemplate) GenerateUID(name string) error {
 	return nil
 }
 
+// GenerateUIDWithSuffix generates a uid from the input name with a random suffix and sets it on the workflow template.
+// The name part is shortened, if needed, so the uid is at most 30 characters.
+func (wt *WorkflowTemplate) GenerateUIDWithSuffix(name string) error {
+	suffix := "-" + rand.String(5)
+	result, err := uid2.GenerateUID(name, len(name))
+	if err != nil {
+		return err
+	}
+
+	if maxLength := 30 - len(suffix); len(result) > maxLength {
+		result = result[:maxLength]
+	}
+
+	wt.UID = result + suffix
+
+	return nil
+}
+
+// serverManagedFields are fields set by kubernetes or argo that are not part of a workflow template spec.
+var serverManagedFields = []string{"status", "resourceVersion", "selfLink", "creationTimestamp", "uid", "generation", "managedFields"}
+
+// StripServerManagedFields removes the fields set by kubernetes or argo from the manifest,
+// so a manifest exported from argo can be used as is.
+// If the manifest is a full argo resource, with apiVersion, kind, and spec, only the spec is kept.
+// The manifest is only rewritten if something was removed.
+func (wt *WorkflowTemplate) StripServerManagedFields() error {
+	manifest, err := mapping.NewFromYamlString(wt.Manifest)
+	if err != nil {
+		return err
+	}
+
+	changed := false
+	_, hasKind := manifest["kind"]
+	_, hasAPIVersion := manifest["apiVersion"]
+	if spec, ok := manifest["spec"].(mapping.Mapping); ok && (hasKind || hasAPIVersion) {
+		manifest = spec
+		changed = true
+	}
+
+	for _, field := range serverManagedFields {
+		if _, ok := manifest[field]; ok {
+			delete(manifest, field)
+			changed = true
+		}
+	}
+
+	if !changed {
+		return nil
+	}
+
+	data, err := manifest.ToYamlBytes()
+	if err != nil {
+		return err
+	}
+	wt.Manifest = string(data)
+
+	return nil
+}
+
+// getWorkflowTemplateSortColumnsMap returns the properties workflow templates can be sorted by, mapped to their column.
+func getWorkflowTemplateSortColumnsMap() map[string]string {
+	return map[string]string{
+		"name":      "name",
+		"createdAt": "created_at",
+		"updatedAt": "updated_at",
+	}
+}
+
 // GetManifestBytes returns the manifest as []byte
 func (wt *WorkflowTemplate) GetManifestBytes() []byte {
 	return []byte(wt.Manifest)
 }
 
+// ParameterKeyString is a parameter name along with the parameter as yaml, see GetParametersKeyString.
+type ParameterKeyString struct {
+	Name  string
+	Value string
+}
+
 // GetParametersKeyString loads Parameters from the manifest and returns it as a map,
 // where the key is the name, and the value is the parameters as yaml.
 func (wt *WorkflowTemplate) GetParametersKeyString() (map[string]string, error) {
+	parameters, err := wt.GetParametersKeyStringOrdered()
+	if err != nil || parameters == nil {
+		return nil, err
+	}
+
+	result := make(map[string]string, len(parameters))
+	for _, parameter := range parameters {
+		result[parameter.Name] = parameter.Value
+	}
+
+	return result, nil
+}
+
+// GetParametersKeyStringOrdered is like GetParametersKeyString, but the parameters are returned
+// in the order they are declared in the manifest.
+// Results are cached by manifest content, so repeated calls for the same manifest do not re-parse it.
+// Parameters of the BaseManifest, if there is one, are included.
+func (wt *WorkflowTemplate) GetParametersKeyStringOrdered() ([]ParameterKeyString, error) {
+	key := ManifestHash(wt.Manifest)
+	if wt.BaseManifest != "" {
+		key = ManifestHash(wt.BaseManifest + "\n---\n" + wt.Manifest)
+	}
+
+	if cached, ok := parametersKeyStringCache.Get(key); ok {
+		return copyParametersKeyString(cached.([]ParameterKeyString)), nil
+	}
+
+	result, err := wt.parseParametersKeyString()
+	if err != nil {
+		return nil, err
+	}
+
+	parametersKeyStringCache.Add(key, result)
+
+	return copyParametersKeyString(result), nil
+}
+
+// copyParametersKeyString returns a copy of the parameters so callers can not modify cached values
+func copyParametersKeyString(parameters []ParameterKeyString) []ParameterKeyString {
+	if parameters == nil {
+		return nil
+	}
+
+	result := make([]ParameterKeyString, len(parameters))
+	copy(result, parameters)
+
+	return result
+}
+
+// parseParametersKeyString parses the manifest and returns the parameters as described in GetParametersKeyStringOrdered
+func (wt *WorkflowTemplate) parseParametersKeyString() ([]ParameterKeyString, error) {
 	root := make(map[interface{}]interface{})
 
 	if err := yaml.Unmarshal(wt.GetManifestBytes(), root); err != nil {
 		return nil, err
 	}
 
+	if wt.BaseManifest != "" {
+		base := make(map[interface{}]interface{})
+		if err := yaml.Unmarshal([]byte(wt.BaseManifest), base); err != nil {
+			return nil, err
+		}
+		root = mergeWorkflowTemplateSpecs(base, root)
+	}
+
 	arguments, ok := root["arguments"]
 	if !ok {
 		return nil, nil
@@ -91,7 +373,7 @@ func (wt *WorkflowTemplate) GetParametersKeyString() (map[string]string, error)
 		delete(root, arguments)
 	}
 
-	result := make(map[string]string)
+	result := make([]ParameterKeyString, 0)
 	for index, parameter := range parametersAsArray {
 		parameterMap, ok := parameter.(map[interface{}]interface{})
 		if !ok {
@@ -110,12 +392,57 @@ func (wt *WorkflowTemplate) GetParametersKeyString() (map[string]string, error)
 			continue
 		}
 
-		result[keyAsString] = string(remainingParameters)
+		result = append(result, ParameterKeyString{
+			Name:  keyAsString,
+			Value: string(remainingParameters),
+		})
 	}
 
 	return result, nil
 }
 
+// SetAnnotationsFromArgoWorkflowTemplate sets the user Annotations from the ArgoWorkflowTemplate, removing their prefix.
+func (wt *WorkflowTemplate) SetAnnotationsFromArgoWorkflowTemplate() {
+	if wt.ArgoWorkflowTemplate == nil {
+		return
+	}
+
+	annotations := label.FilterByPrefix(label.AnnotationPrefix, wt.ArgoWorkflowTemplate.Annotations)
+	wt.Annotations = label.RemovePrefix(label.AnnotationPrefix, annotations)
+}
+
+// isCronPopulatedParameter returns true if the parameter gets its value when a cron workflow runs,
+// so the user does not need to supply it.
+// These are the reserved "sys-" parameters and parameters whose value is an argo workflow variable, like {{workflow.creationTimestamp}}.
+func isCronPopulatedParameter(parameter Parameter) bool {
+	if strings.HasPrefix(parameter.Name, "sys-") {
+		return true
+	}
+
+	return parameter.Value != nil && strings.Contains(*parameter.Value, "{{workflow.")
+}
+
+// GetCronParameters splits the manifest parameters into the ones populated automatically
+// when the template is run by a cron workflow, and the ones the user must supply.
+func (wt *WorkflowTemplate) GetCronParameters() (automatic []Parameter, user []Parameter, err error) {
+	parameters, err := ParseParametersFromManifest(wt.GetManifestBytes())
+	if err != nil {
+		return nil, nil, err
+	}
+
+	automatic = make([]Parameter, 0)
+	user = make([]Parameter, 0)
+	for _, parameter := range parameters {
+		if isCronPopulatedParameter(parameter) {
+			automatic = append(automatic, parameter)
+		} else {
+			user = append(user, parameter)
+		}
+	}
+
+	return
+}
+
 // ReplaceManifestParameters updates the parameters in the manifest to the ones in the argument
 func (wt *WorkflowTemplate) ReplaceManifestParameters(params []Parameter) error {
 	manifestMap, err := mapping.NewFromYamlString(wt.Manifest)
@@ -140,6 +467,58 @@ func (wt *WorkflowTemplate) ReplaceManifestParameters(params []Parameter) error
 	return nil
 }
 
+// InjectParameterDefaults sets the value of each manifest parameter that has no value, or an empty one,
+// to the value for its name in defaults, if there is one. The names of the parameters that were set are returned.
+// The manifest is only rewritten if a parameter was set.
+func (wt *WorkflowTemplate) InjectParameterDefaults(defaults map[string]string) (injected []string, err error) {
+	if len(defaults) == 0 {
+		return nil, nil
+	}
+
+	manifestMap, err := mapping.NewFromYamlString(wt.Manifest)
+	if err != nil {
+		return nil, err
+	}
+
+	arguments, ok := manifestMap["arguments"].(mapping.Mapping)
+	if !ok {
+		return nil, nil
+	}
+	parameters, ok := arguments["parameters"].([]interface{})
+	if !ok {
+		return nil, nil
+	}
+
+	for _, parameter := range parameters {
+		parameterMap, ok := parameter.(mapping.Mapping)
+		if !ok {
+			continue
+		}
+
+		if value, ok := parameterMap["value"]; ok && value != nil && fmt.Sprintf("%v", value) != "" {
+			continue
+		}
+
+		name := fmt.Sprintf("%v", parameterMap["name"])
+		if defaultValue, ok := defaults[name]; ok {
+			parameterMap["value"] = defaultValue
+			injected = append(injected, name)
+		}
+	}
+
+	if len(injected) == 0 {
+		return nil, nil
+	}
+
+	manifestBytes, err := manifestMap.ToYamlBytes()
+	if err != nil {
+		return nil, err
+	}
+	wt.Manifest = string(manifestBytes)
+
+	return injected, nil
+}
+
 // GetWorkflowManifestBytes returns the ArgoWorkflowTemplate but with
 // Kind set to workflow
 // ObjectMeta remove all fields but GenerateName and Labels
@@ -197,6 +576,75 @@ func (wt *WorkflowTemplate) FormatManifest() (string, error) {
 	return string(manifestBytes), nil
 }
 
+// IsManifestEmpty returns true if the manifest is empty or only whitespace
+func (wt *WorkflowTemplate) IsManifestEmpty() bool {
+	return strings.TrimSpace(wt.Manifest) == ""
+}
+
+// resolveFragments replaces the entries of the spec's templates that reference a fragment, like
+// - fragment: name
+// with the fragment's manifest, which must be a single argo template.
+func (wt *WorkflowTemplate) resolveFragments(spec map[interface{}]interface{}) error {
+	templates, ok := spec["templates"].([]interface{})
+	if !ok {
+		return nil
+	}
+
+	for i, template := range templates {
+		templateMap, ok := template.(map[interface{}]interface{})
+		if !ok {
+			continue
+		}
+
+		fragmentName, ok := templateMap["fragment"]
+		if !ok {
+			continue
+		}
+
+		name := fmt.Sprintf("%v", fragmentName)
+		fragmentManifest, ok := wt.Fragments[name]
+		if !ok {
+			return fmt.Errorf("fragment '%v' not found", name)
+		}
+
+		fragment := make(map[interface{}]interface{})
+		if err := yaml.Unmarshal([]byte(fragmentManifest), fragment); err != nil {
+			return fmt.Errorf("fragment '%v' is invalid: %v", name, err)
+		}
+
+		templates[i] = fragment
+	}
+
+	return nil
+}
+
+// resolveSpec parses the manifest, resolves its fragments and merges it over the BaseManifest, if there is one
+func (wt *WorkflowTemplate) resolveSpec() (map[interface{}]interface{}, error) {
+	if wt.IsManifestEmpty() {
+		return nil, ErrEmptyManifest
+	}
+
+	spec := make(map[interface{}]interface{})
+	if err := yaml.Unmarshal(wt.GetManifestBytes(), spec); err != nil {
+		return nil, err
+	}
+
+	if err := wt.resolveFragments(spec); err != nil {
+		return nil, err
+	}
+
+	if strings.TrimSpace(wt.BaseManifest) == "" {
+		return spec, nil
+	}
+
+	base := make(map[interface{}]interface{})
+	if err := yaml.Unmarshal([]byte(wt.BaseManifest), base); err != nil {
+		return nil, fmt.Errorf("base template '%v' is invalid: %v", wt.BaseTemplateUID, err)
+	}
+
+	return mergeWorkflowTemplateSpecs(base, spec), nil
+}
+
 // WrapSpec takes the manifest from the workflow template, which is just the "spec" contents
 // and wrap it so we have
 // {
@@ -204,12 +652,11 @@ func (wt *WorkflowTemplate) FormatManifest() (string, error) {
 //    spec: spec_data
 // }
 // the above wrapping is what is returned.
+// Templates referencing a fragment are replaced with the fragment, see resolveFragments.
+// If the workflow template has a BaseManifest, the manifest is merged over it, see mergeWorkflowTemplateSpecs.
 func (wt *WorkflowTemplate) WrapSpec() ([]byte, error) {
-	data := wt.GetManifestBytes()
-
-	spec := make(map[interface{}]interface{})
-
-	if err := yaml.Unmarshal(data, spec); err != nil {
+	spec, err := wt.resolveSpec()
+	if err != nil {
 		return nil, err
 	}
 
@@ -226,6 +673,22 @@ func (wt *WorkflowTemplate) WrapSpec() ([]byte, error) {
 	return finalBytes, nil
 }
 
+// resolvedManifest returns the manifest with its fragments resolved and merged over the BaseManifest, see resolveSpec.
+// It is stored with each version, so the version keeps the spec it was created with when its fragments or base change.
+func (wt *WorkflowTemplate) resolvedManifest() (string, error) {
+	spec, err := wt.resolveSpec()
+	if err != nil {
+		return "", err
+	}
+
+	manifest, err := yaml.Marshal(spec)
+	if err != nil {
+		return "", err
+	}
+
+	return string(manifest), nil
+}
+
 // AddWorkflowTemplateParametersFromAnnotations sets the parameters in the input spec
 // to the parameters from the WorkflowTemplate's ArgoWorkflowTemplate annotations
 func (wt *WorkflowTemplate) AddWorkflowTemplateParametersFromAnnotations(spec mapping.Mapping) {
@@ -275,6 +738,6 @@ func (wt *WorkflowTemplate) AddWorkflowTemplateParametersFromAnnotations(spec ma
 // getWorkflowTemplateColumns returns all of the columns for workflowTemplate modified by alias, destination.
 // see formatColumnSelect
 func getWorkflowTemplateColumns(aliasAndDestination ...string) []string {
-	columns := []string{"id", "created_at", "uid", "name", "namespace", "modified_at", "is_archived", "labels"}
+	columns := []string{"id", "created_at", "uid", "name", "namespace", "modified_at", "is_archived", "is_public", "labels", "updated_at", "created_by", "is_deprecated", "category", "base_template_uid"}
 	return sql.FormatColumnSelect(columns, aliasAndDestination...)
 }
@@ -0,0 +1,238 @@
+package v1
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/onepanelio/core/pkg/util/label"
+	"github.com/stretchr/testify/assert"
+)
+
+// TestWorkflowTemplate_GenerateUIDWithSuffix makes sure the generated uid has a suffix and is not too long
+func TestWorkflowTemplate_GenerateUIDWithSuffix(t *testing.T) {
+	wt := &WorkflowTemplate{}
+
+	err := wt.GenerateUIDWithSuffix("My Template")
+	assert.Nil(t, err)
+	assert.True(t, strings.HasPrefix(wt.UID, "my-template-"))
+	assert.Len(t, wt.UID, len("my-template-")+5)
+
+	err = wt.GenerateUIDWithSuffix("a workflow template with a really long name")
+	assert.Nil(t, err)
+	assert.Len(t, wt.UID, 30)
+}
+
+// TestWorkflowTemplate_GetParametersKeyString_Cache makes sure cached results are returned and can not be modified by callers
+func TestWorkflowTemplate_GetParametersKeyString_Cache(t *testing.T) {
+	wt := &WorkflowTemplate{
+		Manifest: `arguments:
+  parameters:
+  - name: message
+    value: hello
+`,
+	}
+
+	first, err := wt.GetParametersKeyString()
+	assert.Nil(t, err)
+	assert.Contains(t, first, "message")
+
+	delete(first, "message")
+
+	second, err := wt.GetParametersKeyString()
+	assert.Nil(t, err)
+	assert.Contains(t, second, "message")
+}
+
+// TestWorkflowTemplate_GetParametersKeyStringOrdered makes sure parameters are returned in declaration order
+func TestWorkflowTemplate_GetParametersKeyStringOrdered(t *testing.T) {
+	wt := &WorkflowTemplate{
+		Manifest: `arguments:
+  parameters:
+  - name: zeta
+    value: "1"
+  - name: alpha
+    value: "2"
+  - name: mid
+    value: "3"
+`,
+	}
+
+	parameters, err := wt.GetParametersKeyStringOrdered()
+	assert.Nil(t, err)
+
+	names := make([]string, 0)
+	for _, parameter := range parameters {
+		names = append(names, parameter.Name)
+	}
+	assert.Equal(t, []string{"zeta", "alpha", "mid"}, names)
+}
+
+// TestWorkflowTemplate_Annotations makes sure user annotations are stored on the argo workflow template and read back
+func TestWorkflowTemplate_Annotations(t *testing.T) {
+	wt := &WorkflowTemplate{
+		UID:  "test",
+		Name: "test",
+		Manifest: `entrypoint: main
+templates:
+- name: main
+  container:
+    image: alpine
+`,
+		Annotations: map[string]string{
+			"owner": "data-team",
+		},
+	}
+
+	argoWft, err := createArgoWorkflowTemplate(wt, 1, nil)
+	assert.Nil(t, err)
+	assert.Equal(t, "data-team", argoWft.Annotations[label.AnnotationPrefix+"owner"])
+
+	result := &WorkflowTemplate{ArgoWorkflowTemplate: argoWft}
+	result.SetAnnotationsFromArgoWorkflowTemplate()
+	assert.Equal(t, wt.Annotations, result.Annotations)
+}
+
+// Test_createArgoWorkflowTemplate_DefaultLabels makes sure default labels are added without overriding template labels
+func Test_createArgoWorkflowTemplate_DefaultLabels(t *testing.T) {
+	wt := &WorkflowTemplate{
+		UID:      "test",
+		Name:     "test",
+		Manifest: defaultWorkflowTemplate,
+		Labels: map[string]string{
+			"environment": "dev",
+		},
+	}
+
+	defaultLabels := map[string]string{
+		"environment": "prod",
+		"cost-center": "research",
+	}
+
+	argoWft, err := createArgoWorkflowTemplate(wt, 1, defaultLabels)
+	assert.Nil(t, err)
+	assert.Equal(t, "dev", argoWft.Labels[label.TagPrefix+"environment"])
+	assert.Equal(t, "research", argoWft.Labels[label.TagPrefix+"cost-center"])
+
+	// read back from argo, the default labels are not labels of the workflow template
+	assert.Equal(t, map[string]string{"environment": "dev"}, tagLabelsFromArgo(argoWft.Labels, defaultLabels))
+}
+
+// TestWorkflowTemplate_StripServerManagedFields makes sure exported argo resources are reduced to their spec
+func TestWorkflowTemplate_StripServerManagedFields(t *testing.T) {
+	wt := &WorkflowTemplate{
+		Manifest: `apiVersion: argoproj.io/v1alpha1
+kind: WorkflowTemplate
+metadata:
+  name: test
+  resourceVersion: "123"
+spec:
+  entrypoint: main
+  templates:
+  - name: main
+    container:
+      image: alpine
+status: {}
+`,
+	}
+
+	err := wt.StripServerManagedFields()
+	assert.Nil(t, err)
+	assert.NotContains(t, wt.Manifest, "resourceVersion")
+	assert.NotContains(t, wt.Manifest, "kind")
+	assert.True(t, strings.HasPrefix(wt.Manifest, "entrypoint: main"))
+
+	unchanged := &WorkflowTemplate{
+		Manifest: defaultWorkflowTemplate,
+	}
+	err = unchanged.StripServerManagedFields()
+	assert.Nil(t, err)
+	assert.Equal(t, defaultWorkflowTemplate, unchanged.Manifest)
+}
+
+// TestWorkflowTemplate_GetCronParameters makes sure parameters populated by crons are separated from user parameters
+func TestWorkflowTemplate_GetCronParameters(t *testing.T) {
+	wt := &WorkflowTemplate{
+		Manifest: `arguments:
+  parameters:
+  - name: sys-node-pool
+    value: default
+  - name: run-date
+    value: "{{workflow.creationTimestamp}}"
+  - name: dataset
+    value: mnist
+`,
+	}
+
+	automatic, user, err := wt.GetCronParameters()
+	assert.Nil(t, err)
+	assert.Len(t, automatic, 2)
+	assert.Equal(t, "sys-node-pool", automatic[0].Name)
+	assert.Equal(t, "run-date", automatic[1].Name)
+	assert.Len(t, user, 1)
+	assert.Equal(t, "dataset", user[0].Name)
+}
+
+func TestWorkflowTemplate_LogEntry(t *testing.T) {
+	var missing *WorkflowTemplate
+	assert.Nil(t, missing.LogEntry())
+
+	wt := &WorkflowTemplate{
+		UID:      "test",
+		Name:     "Test",
+		Version:  1,
+		Manifest: defaultWorkflowTemplate,
+	}
+	assert.Equal(t, &WorkflowTemplateLogEntry{UID: "test", Name: "Test", Version: 1}, wt.LogEntry())
+}
+
+func TestWorkflowTemplate_WrapSpec_Fragments(t *testing.T) {
+	wt := &WorkflowTemplate{
+		Manifest: `entrypoint: main
+templates:
+- fragment: main
+`,
+		Fragments: map[string]string{
+			"main": `name: main
+container:
+  image: alpine
+`,
+		},
+	}
+
+	argoWft, err := parseWorkflowTemplateSpec(wt)
+	assert.Nil(t, err)
+	assert.Len(t, argoWft.Spec.Templates, 1)
+	assert.Equal(t, "main", argoWft.Spec.Templates[0].Name)
+	assert.Equal(t, "alpine", argoWft.Spec.Templates[0].Container.Image)
+
+	wt.Fragments = nil
+	_, err = wt.WrapSpec()
+	assert.NotNil(t, err)
+	assert.Contains(t, err.Error(), "'main'")
+}
+
+func TestWorkflowTemplate_InjectParameterDefaults(t *testing.T) {
+	wt := &WorkflowTemplate{
+		Manifest: `entrypoint: main
+arguments:
+  parameters:
+  - name: node-pool
+  - name: source
+    value: https://github.com/onepanelio/pytorch-examples.git
+  - name: unknown
+    value: ""
+`,
+	}
+
+	injected, err := wt.InjectParameterDefaults(map[string]string{
+		"node-pool": "Standard_D2s_v3",
+		"source":    "ignored",
+	})
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"node-pool"}, injected)
+
+	parameters, err := ParseParametersFromManifest(wt.GetManifestBytes())
+	assert.Nil(t, err)
+	assert.Equal(t, "Standard_D2s_v3", *parameters[0].Value)
+	assert.Equal(t, "https://github.com/onepanelio/pytorch-examples.git", *parameters[1].Value)
+}
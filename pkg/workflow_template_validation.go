@@ -0,0 +1,910 @@
+package v1
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+
+	sq "github.com/Masterminds/squirrel"
+	"github.com/argoproj/argo/pkg/apis/workflow/v1alpha1"
+	"github.com/ghodss/yaml"
+	"github.com/onepanelio/core/pkg/util"
+	"github.com/onepanelio/core/pkg/util/jsonschema"
+	log "github.com/sirupsen/logrus"
+	"google.golang.org/grpc/codes"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// ValidationArgoVersion is the argo minor version workflows are validated against.
+// It is the version of the argo module onepanel is built with, which the validation rules come from.
+// The module is required by a commit, not a release, so the version can not be read from it at runtime.
+// Test_ValidationArgoVersion fails when the module in go.mod changes, so this is updated with it.
+//
+// Validating against other argo versions is not supported, as only this version's rules are built in.
+// Callers, like CI, can compare it with the argo version of the cluster a workflow will run on.
+const ValidationArgoVersion = "v2.7"
+
+// onepanelParameterFields are the parameter fields onepanel adds that argo does not know about.
+var onepanelParameterFields = []string{"visibility", "type", "displayName", "hint", "options", "required", "displayOrder", "sensitive", "group", "groupOrder"}
+
+// isJSONManifest returns true if the manifest is a JSON object rather than YAML.
+func isJSONManifest(manifest []byte) bool {
+	return bytes.HasPrefix(bytes.TrimSpace(manifest), []byte("{"))
+}
+
+// validateJSONManifest returns field level errors for JSON manifests, like unknown fields or wrong types.
+// YAML manifests are not checked as they are validated when they are parsed.
+// The onepanel specific parameter fields, like displayName, are allowed.
+func validateJSONManifest(manifest []byte) error {
+	if !isJSONManifest(manifest) {
+		return nil
+	}
+
+	data := make(map[string]interface{})
+	if err := json.Unmarshal(manifest, &data); err != nil {
+		return fmt.Errorf("invalid JSON manifest: %v", err)
+	}
+
+	if arguments, ok := data["arguments"].(map[string]interface{}); ok {
+		if parameters, ok := arguments["parameters"].([]interface{}); ok {
+			for _, parameter := range parameters {
+				if parameterMap, ok := parameter.(map[string]interface{}); ok {
+					for _, key := range onepanelParameterFields {
+						delete(parameterMap, key)
+					}
+				}
+			}
+		}
+	}
+
+	// Fragment references are not argo templates, they are checked once resolved by WrapSpec
+	if templates, ok := data["templates"].([]interface{}); ok {
+		argoTemplates := make([]interface{}, 0, len(templates))
+		for _, template := range templates {
+			if templateMap, ok := template.(map[string]interface{}); ok {
+				if _, ok := templateMap["fragment"]; ok {
+					continue
+				}
+			}
+			argoTemplates = append(argoTemplates, template)
+		}
+		data["templates"] = argoTemplates
+	}
+
+	argoManifest, err := json.Marshal(data)
+	if err != nil {
+		return err
+	}
+
+	decoder := json.NewDecoder(bytes.NewReader(argoManifest))
+	decoder.DisallowUnknownFields()
+
+	spec := &v1alpha1.WorkflowTemplateSpec{}
+	if err := decoder.Decode(spec); err != nil {
+		return fmt.Errorf("invalid JSON manifest: %v", strings.TrimPrefix(err.Error(), "json: "))
+	}
+
+	return nil
+}
+
+// parseWorkflowTemplateSpec parses the workflow template manifest into an argo workflow template.
+func parseWorkflowTemplateSpec(workflowTemplate *WorkflowTemplate) (*v1alpha1.WorkflowTemplate, error) {
+	finalBytes, err := workflowTemplate.WrapSpec()
+	if err != nil {
+		return nil, err
+	}
+
+	argoWft := &v1alpha1.WorkflowTemplate{}
+	if err := yaml.Unmarshal(finalBytes, argoWft); err != nil {
+		return nil, err
+	}
+
+	return argoWft, nil
+}
+
+// getTemplateImages returns the images of all the containers in the template, including scripts,
+// init containers and sidecars.
+func getTemplateImages(template *v1alpha1.Template) (images []string) {
+	if template.Container != nil {
+		images = append(images, template.Container.Image)
+	}
+	if template.Script != nil {
+		images = append(images, template.Script.Image)
+	}
+	for _, container := range template.InitContainers {
+		images = append(images, container.Image)
+	}
+	for _, container := range template.Sidecars {
+		images = append(images, container.Image)
+	}
+
+	return
+}
+
+// validateAllowedImages returns an error listing all of the images in the spec that do not start with one of the
+// allowed prefixes. If there are no prefixes, all images are allowed.
+//
+// Images are compared as written in the manifest, so an image that is set via a parameter is not allowed.
+func validateAllowedImages(spec *v1alpha1.WorkflowSpec, allowedPrefixes []string) error {
+	if len(allowedPrefixes) == 0 {
+		return nil
+	}
+
+	disallowed := make([]string, 0)
+	for i := range spec.Templates {
+		for _, image := range getTemplateImages(&spec.Templates[i]) {
+			allowed := false
+			for _, prefix := range allowedPrefixes {
+				if strings.HasPrefix(image, prefix) {
+					allowed = true
+					break
+				}
+			}
+
+			if !allowed {
+				disallowed = append(disallowed, image)
+			}
+		}
+	}
+
+	if len(disallowed) > 0 {
+		return fmt.Errorf("images are not from an allowed registry: %v", strings.Join(disallowed, ", "))
+	}
+
+	return nil
+}
+
+// hasOutputArtifacts returns true if any template in the spec declares output artifacts.
+func hasOutputArtifacts(spec *v1alpha1.WorkflowSpec) bool {
+	for _, template := range spec.Templates {
+		if len(template.Outputs.Artifacts) > 0 {
+			return true
+		}
+	}
+
+	return false
+}
+
+// validateArtifactRepository returns an error if the spec declares output artifacts
+// but the namespace does not have an artifact repository configured.
+// Errors loading the namespace config, other than it not being found, are returned as is.
+func (c *Client) validateArtifactRepository(namespace string, spec *v1alpha1.WorkflowSpec) error {
+	if !hasOutputArtifacts(spec) {
+		return nil
+	}
+
+	_, err := c.GetNamespaceConfig(namespace)
+	if err == nil {
+		return nil
+	}
+
+	if userErr, ok := err.(*util.UserError); errors.IsNotFound(err) || (ok && userErr.Code == codes.NotFound) {
+		return fmt.Errorf("template declares output artifacts but namespace '%v' has no artifact repository configured. "+
+			"Add an artifactRepository to the onepanel config map in the namespace or remove the output artifacts", namespace)
+	}
+
+	return err
+}
+
+// getTemplateContainers returns all of the containers in the template, including scripts, init containers and sidecars.
+func getTemplateContainers(template *v1alpha1.Template) (containers []*corev1.Container) {
+	if template.Container != nil {
+		containers = append(containers, template.Container)
+	}
+	if template.Script != nil {
+		containers = append(containers, &template.Script.Container)
+	}
+	for i := range template.InitContainers {
+		containers = append(containers, &template.InitContainers[i].Container)
+	}
+	for i := range template.Sidecars {
+		containers = append(containers, &template.Sidecars[i].Container)
+	}
+
+	return
+}
+
+// getReferencedSecretsAndConfigMaps returns the names of the secrets and config maps the containers in the spec
+// reference through env valueFrom or envFrom. Names set through a parameter, like {{inputs.parameters.secret}}, are skipped.
+func getReferencedSecretsAndConfigMaps(spec *v1alpha1.WorkflowSpec) (secrets, configMaps []string) {
+	seenSecrets := make(map[string]bool)
+	seenConfigMaps := make(map[string]bool)
+	addName := func(names *[]string, seen map[string]bool, name string) {
+		if name == "" || strings.Contains(name, "{{") || seen[name] {
+			return
+		}
+		seen[name] = true
+		*names = append(*names, name)
+	}
+
+	for i := range spec.Templates {
+		for _, container := range getTemplateContainers(&spec.Templates[i]) {
+			for _, env := range container.Env {
+				if env.ValueFrom == nil {
+					continue
+				}
+				if env.ValueFrom.SecretKeyRef != nil {
+					addName(&secrets, seenSecrets, env.ValueFrom.SecretKeyRef.Name)
+				}
+				if env.ValueFrom.ConfigMapKeyRef != nil {
+					addName(&configMaps, seenConfigMaps, env.ValueFrom.ConfigMapKeyRef.Name)
+				}
+			}
+
+			for _, envFrom := range container.EnvFrom {
+				if envFrom.SecretRef != nil {
+					addName(&secrets, seenSecrets, envFrom.SecretRef.Name)
+				}
+				if envFrom.ConfigMapRef != nil {
+					addName(&configMaps, seenConfigMaps, envFrom.ConfigMapRef.Name)
+				}
+			}
+		}
+	}
+
+	return
+}
+
+// validateReferencedSecretsAndConfigMaps returns an error listing the secrets and config maps referenced by the spec
+// that do not exist in the namespace. It is only checked if the client has StrictValidation set,
+// as some secrets are created after the workflow template.
+func (c *Client) validateReferencedSecretsAndConfigMaps(namespace string, spec *v1alpha1.WorkflowSpec) error {
+	if !c.StrictValidation {
+		return nil
+	}
+
+	return c.checkReferencedSecretsAndConfigMaps(namespace, spec)
+}
+
+// checkReferencedSecretsAndConfigMaps is validateReferencedSecretsAndConfigMaps without the StrictValidation check
+func (c *Client) checkReferencedSecretsAndConfigMaps(namespace string, spec *v1alpha1.WorkflowSpec) error {
+	secrets, configMaps := getReferencedSecretsAndConfigMaps(spec)
+
+	missingSecrets := make([]string, 0)
+	for _, name := range secrets {
+		if _, err := c.CoreV1().Secrets(namespace).Get(name, metav1.GetOptions{}); err != nil {
+			if !errors.IsNotFound(err) {
+				return err
+			}
+			missingSecrets = append(missingSecrets, name)
+		}
+	}
+
+	missingConfigMaps := make([]string, 0)
+	for _, name := range configMaps {
+		if _, err := c.CoreV1().ConfigMaps(namespace).Get(name, metav1.GetOptions{}); err != nil {
+			if !errors.IsNotFound(err) {
+				return err
+			}
+			missingConfigMaps = append(missingConfigMaps, name)
+		}
+	}
+
+	missing := make([]string, 0)
+	if len(missingSecrets) > 0 {
+		missing = append(missing, fmt.Sprintf("secrets %v", strings.Join(missingSecrets, ", ")))
+	}
+	if len(missingConfigMaps) > 0 {
+		missing = append(missing, fmt.Sprintf("config maps %v", strings.Join(missingConfigMaps, ", ")))
+	}
+	if len(missing) > 0 {
+		return fmt.Errorf("referenced resources do not exist in namespace '%v': %v", namespace, strings.Join(missing, "; "))
+	}
+
+	return nil
+}
+
+// validateImagePullSecrets returns an error listing the imagePullSecrets of the spec that do not exist in the namespace,
+// as pods using them fail with ImagePullBackOff. Like validateReferencedSecretsAndConfigMaps, it is only checked
+// if the client has StrictValidation set.
+func (c *Client) validateImagePullSecrets(namespace string, spec *v1alpha1.WorkflowSpec) error {
+	if !c.StrictValidation {
+		return nil
+	}
+
+	missing := make([]string, 0)
+	for _, reference := range spec.ImagePullSecrets {
+		if reference.Name == "" || strings.Contains(reference.Name, "{{") {
+			continue
+		}
+		if _, err := c.CoreV1().Secrets(namespace).Get(reference.Name, metav1.GetOptions{}); err != nil {
+			if !errors.IsNotFound(err) {
+				return err
+			}
+			missing = append(missing, reference.Name)
+		}
+	}
+
+	if len(missing) > 0 {
+		return fmt.Errorf("image pull secrets do not exist in namespace '%v': %v", namespace, strings.Join(missing, ", "))
+	}
+
+	return nil
+}
+
+// validateServiceAccount returns an error if the spec's serviceAccountName does not exist in the namespace,
+// as the pods of the workflow could not be created. It is only checked if the client has StrictValidation set.
+func (c *Client) validateServiceAccount(namespace string, spec *v1alpha1.WorkflowSpec) error {
+	if !c.StrictValidation || spec.ServiceAccountName == "" || strings.Contains(spec.ServiceAccountName, "{{") {
+		return nil
+	}
+
+	if _, err := c.CoreV1().ServiceAccounts(namespace).Get(spec.ServiceAccountName, metav1.GetOptions{}); err != nil {
+		if !errors.IsNotFound(err) {
+			return err
+		}
+		return fmt.Errorf("service account '%v' does not exist in namespace '%v'", spec.ServiceAccountName, namespace)
+	}
+
+	return nil
+}
+
+// validateEntrypoint returns an error if the spec's entrypoint is not the name of one of its templates.
+func validateEntrypoint(spec *v1alpha1.WorkflowSpec) error {
+	if spec.Entrypoint == "" {
+		return nil
+	}
+
+	for i := range spec.Templates {
+		if spec.Templates[i].Name == spec.Entrypoint {
+			return nil
+		}
+	}
+
+	return fmt.Errorf("entrypoint '%v' does not match the name of any template", spec.Entrypoint)
+}
+
+// validateUniqueTemplateNames returns an error naming the first template name that is used more than once in the spec.
+func validateUniqueTemplateNames(spec *v1alpha1.WorkflowSpec) error {
+	names := make(map[string]bool)
+	for i := range spec.Templates {
+		name := spec.Templates[i].Name
+		if names[name] {
+			return fmt.Errorf("template name '%v' is used more than once", name)
+		}
+		names[name] = true
+	}
+
+	return nil
+}
+
+// validateDAGTasks returns an error for the first dag that has two tasks with the same name,
+// or a task that depends on a task that is not in the dag. Argo would silently use one of the duplicate tasks.
+func validateDAGTasks(spec *v1alpha1.WorkflowSpec) error {
+	for _, template := range spec.Templates {
+		if template.DAG == nil {
+			continue
+		}
+
+		tasks := make(map[string]bool)
+		for _, task := range template.DAG.Tasks {
+			if tasks[task.Name] {
+				return fmt.Errorf("task name '%v' is used more than once in template '%v'", task.Name, template.Name)
+			}
+			tasks[task.Name] = true
+		}
+
+		for _, task := range template.DAG.Tasks {
+			for _, dependency := range task.Dependencies {
+				if !tasks[dependency] {
+					return fmt.Errorf("task '%v' of template '%v' depends on '%v', which is not a task in the template", task.Name, template.Name, dependency)
+				}
+			}
+		}
+	}
+
+	return nil
+}
+
+// validateMutuallyExclusiveFields returns an error for the first template that sets more than one template type,
+// like both container and script, or the first step or dag task that loops with more than one of withItems, withParam and withSequence.
+// Argo only rejects these when the workflow is submitted, with errors that are hard to relate to the manifest.
+func validateMutuallyExclusiveFields(spec *v1alpha1.WorkflowSpec) error {
+	for i := range spec.Templates {
+		template := &spec.Templates[i]
+
+		types := make([]string, 0)
+		if template.Container != nil {
+			types = append(types, "container")
+		}
+		if template.Script != nil {
+			types = append(types, "script")
+		}
+		if template.Resource != nil {
+			types = append(types, "resource")
+		}
+		if template.Steps != nil {
+			types = append(types, "steps")
+		}
+		if template.DAG != nil {
+			types = append(types, "dag")
+		}
+		if template.Suspend != nil {
+			types = append(types, "suspend")
+		}
+		if len(types) > 1 {
+			return fmt.Errorf("template '%v' can only have one of container, script, resource, steps, dag and suspend, it has %v", template.Name, strings.Join(types, " and "))
+		}
+
+		for _, parallelSteps := range template.Steps {
+			for _, step := range parallelSteps.Steps {
+				if err := validateLoopFields("step", step.Name, template.Name, step.WithItems, step.WithParam, step.WithSequence); err != nil {
+					return err
+				}
+			}
+		}
+		if template.DAG != nil {
+			for _, task := range template.DAG.Tasks {
+				if err := validateLoopFields("task", task.Name, template.Name, task.WithItems, task.WithParam, task.WithSequence); err != nil {
+					return err
+				}
+			}
+		}
+	}
+
+	return nil
+}
+
+// validateLoopFields returns an error if more than one of the loop fields of a step or dag task is set.
+func validateLoopFields(kind, name, templateName string, withItems []v1alpha1.Item, withParam string, withSequence *v1alpha1.Sequence) error {
+	loops := make([]string, 0)
+	if len(withItems) > 0 {
+		loops = append(loops, "withItems")
+	}
+	if withParam != "" {
+		loops = append(loops, "withParam")
+	}
+	if withSequence != nil {
+		loops = append(loops, "withSequence")
+	}
+	if len(loops) > 1 {
+		return fmt.Errorf("%v '%v' of template '%v' can only have one of withItems, withParam and withSequence, it has %v", kind, name, templateName, strings.Join(loops, " and "))
+	}
+
+	return nil
+}
+
+// workflowParameterReference matches a reference to a workflow parameter, like {{workflow.parameters.name}}
+var workflowParameterReference = regexp.MustCompile(`\{\{\s*workflow\.parameters\.([^\s{}]+)\s*\}\}`)
+
+// validateDeclaredParameterReferences returns an error listing the workflow parameters that the workflow template references,
+// like {{workflow.parameters.name}}, but does not declare in its arguments. Argo only fails on these when the workflow runs.
+func validateDeclaredParameterReferences(workflowTemplate *WorkflowTemplate) error {
+	argoWft, err := parseWorkflowTemplateSpec(workflowTemplate)
+	if err != nil {
+		return err
+	}
+
+	spec := argoWft.Spec.WorkflowSpec
+	declared := make(map[string]bool)
+	for _, parameter := range spec.Arguments.Parameters {
+		declared[parameter.Name] = true
+	}
+
+	specJSON, err := json.Marshal(spec)
+	if err != nil {
+		return err
+	}
+
+	undeclared := make([]string, 0)
+	found := make(map[string]bool)
+	for _, match := range workflowParameterReference.FindAllStringSubmatch(string(specJSON), -1) {
+		name := match[1]
+		if declared[name] || found[name] {
+			continue
+		}
+		found[name] = true
+		undeclared = append(undeclared, name)
+	}
+
+	if len(undeclared) > 0 {
+		sort.Strings(undeclared)
+		return fmt.Errorf("referenced parameters are not declared in arguments.parameters: %v", strings.Join(undeclared, ", "))
+	}
+
+	return nil
+}
+
+// validateVolumeClaimStorage returns an error listing the volume claim templates of the spec that request more storage
+// than the namespace allows, see GetNamespaceMaxVolumeClaimStorage.
+func (c *Client) validateVolumeClaimStorage(namespace string, workflowTemplate *WorkflowTemplate) error {
+	maxStorage, err := c.GetNamespaceMaxVolumeClaimStorage(namespace)
+	if err != nil || maxStorage == nil {
+		return err
+	}
+
+	argoWft, err := parseWorkflowTemplateSpec(workflowTemplate)
+	if err != nil {
+		return err
+	}
+
+	exceeded := make([]string, 0)
+	for _, claim := range argoWft.Spec.VolumeClaimTemplates {
+		storage, ok := claim.Spec.Resources.Requests[corev1.ResourceStorage]
+		if ok && storage.Cmp(*maxStorage) > 0 {
+			exceeded = append(exceeded, fmt.Sprintf("%v (%v)", claim.Name, storage.String()))
+		}
+	}
+
+	if len(exceeded) > 0 {
+		return fmt.Errorf("volume claim templates request more than the namespace maximum of %v storage: %v", maxStorage.String(), strings.Join(exceeded, ", "))
+	}
+
+	return nil
+}
+
+// validateCreatedWorkflowTemplate runs the checks done when a workflow template or a version is created, in addition to validateWorkflowTemplate
+func (c *Client) validateCreatedWorkflowTemplate(namespace string, workflowTemplate *WorkflowTemplate) error {
+	if err := validateDeclaredParameterReferences(workflowTemplate); err != nil {
+		return err
+	}
+
+	return c.validateVolumeClaimStorage(namespace, workflowTemplate)
+}
+
+// validateParameterOptions returns an error naming the first parameter with options whose default value is not one of them.
+func validateParameterOptions(parameters []Parameter) error {
+	for _, parameter := range parameters {
+		if len(parameter.Options) == 0 || parameter.Value == nil || *parameter.Value == "" {
+			continue
+		}
+
+		found := false
+		for _, option := range parameter.Options {
+			if option != nil && option.Value == *parameter.Value {
+				found = true
+				break
+			}
+		}
+
+		if !found {
+			return fmt.Errorf("default value '%v' of parameter '%v' is not one of its options", *parameter.Value, parameter.Name)
+		}
+	}
+
+	return nil
+}
+
+// validateParameterDisplayOrder returns an error if some, but not all, parameters have a display order,
+// or if two parameters have the same display order.
+func validateParameterDisplayOrder(parameters []Parameter) error {
+	ordered := 0
+	seen := make(map[int]string)
+	for _, parameter := range parameters {
+		if parameter.DisplayOrder == nil {
+			continue
+		}
+
+		ordered++
+		if name, ok := seen[*parameter.DisplayOrder]; ok {
+			return fmt.Errorf("parameters '%v' and '%v' have the same display order %v", name, parameter.Name, *parameter.DisplayOrder)
+		}
+		seen[*parameter.DisplayOrder] = parameter.Name
+	}
+
+	if ordered == 0 || ordered == len(parameters) {
+		return nil
+	}
+
+	for _, parameter := range parameters {
+		if parameter.DisplayOrder == nil {
+			return fmt.Errorf("parameter '%v' has no display order, it is required once any parameter has one", parameter.Name)
+		}
+	}
+
+	return nil
+}
+
+// validateParameterGroups returns an error if the group orders of the parameters are not consistent:
+// a parameter has a group order but no group, the parameters of a group have different group orders,
+// two groups have the same group order, or some, but not all, groups have a group order.
+func validateParameterGroups(parameters []Parameter) error {
+	groupOrders := make(map[string]*int)
+	groups := make([]string, 0)
+	for _, parameter := range parameters {
+		if parameter.Group == nil {
+			if parameter.GroupOrder != nil {
+				return fmt.Errorf("parameter '%v' has a group order but no group", parameter.Name)
+			}
+			continue
+		}
+
+		group := *parameter.Group
+		order, seen := groupOrders[group]
+		if !seen {
+			groups = append(groups, group)
+			groupOrders[group] = parameter.GroupOrder
+			continue
+		}
+		if parameter.GroupOrder == nil {
+			continue
+		}
+		if order == nil {
+			groupOrders[group] = parameter.GroupOrder
+		} else if *order != *parameter.GroupOrder {
+			return fmt.Errorf("group '%v' has different group orders %v and %v", group, *order, *parameter.GroupOrder)
+		}
+	}
+
+	ordered := 0
+	seen := make(map[int]string)
+	for _, group := range groups {
+		order := groupOrders[group]
+		if order == nil {
+			continue
+		}
+
+		ordered++
+		if name, ok := seen[*order]; ok {
+			return fmt.Errorf("groups '%v' and '%v' have the same group order %v", name, group, *order)
+		}
+		seen[*order] = group
+	}
+
+	if ordered == 0 || ordered == len(groups) {
+		return nil
+	}
+
+	for _, group := range groups {
+		if groupOrders[group] == nil {
+			return fmt.Errorf("group '%v' has no group order, it is required once any group has one", group)
+		}
+	}
+
+	return nil
+}
+
+// validateWorkflowTemplateManifest runs the onepanel specific checks on the workflow template manifest.
+// These are done in addition to the argo validation.
+func (c *Client) validateWorkflowTemplateManifest(namespace string, workflowTemplate *WorkflowTemplate) error {
+	argoWft, err := parseWorkflowTemplateSpec(workflowTemplate)
+	if err != nil {
+		return err
+	}
+
+	sysConfig, err := c.GetSystemConfig()
+	if err != nil {
+		log.WithFields(log.Fields{
+			"Namespace": namespace,
+			"Error":     err.Error(),
+		}).Error("Unable to get the system config.")
+		return fmt.Errorf("%w: %v", ErrSystemConfigUnavailable, err)
+	}
+
+	allowedImageRegistries, err := sysConfig.AllowedImageRegistries()
+	if err != nil {
+		return err
+	}
+
+	parameters, err := ParseParametersFromManifest(workflowTemplate.GetManifestBytes())
+	if err != nil {
+		return err
+	}
+
+	if err := validateParameterOptions(parameters); err != nil {
+		return err
+	}
+
+	if err := validateParameterDisplayOrder(parameters); err != nil {
+		return err
+	}
+
+	if err := validateParameterGroups(parameters); err != nil {
+		return err
+	}
+
+	spec := &argoWft.Spec.WorkflowSpec
+	if err := validateUniqueTemplateNames(spec); err != nil {
+		return err
+	}
+
+	if err := validateMutuallyExclusiveFields(spec); err != nil {
+		return err
+	}
+
+	if err := validateDAGTasks(spec); err != nil {
+		return err
+	}
+
+	if err := validateEntrypoint(spec); err != nil {
+		return err
+	}
+
+	if err := validateAllowedImages(spec, allowedImageRegistries); err != nil {
+		return err
+	}
+
+	if err := c.validateReferencedSecretsAndConfigMaps(namespace, spec); err != nil {
+		return err
+	}
+
+	if err := c.validateImagePullSecrets(namespace, spec); err != nil {
+		return err
+	}
+
+	if err := c.validateServiceAccount(namespace, spec); err != nil {
+		return err
+	}
+
+	return c.validateArtifactRepository(namespace, spec)
+}
+
+// ValidateWorkflowTemplateAgainstSchema validates the manifest of the workflow template against the JSON schema.
+// This is in addition to, not instead of, the argo validation. The manifest may be YAML or JSON.
+// The violations are returned as an InvalidArgument error. See jsonschema.Schema for the supported keywords.
+func ValidateWorkflowTemplateAgainstSchema(wt *WorkflowTemplate, schema []byte) error {
+	parsedSchema, err := jsonschema.Parse(schema)
+	if err != nil {
+		return util.NewUserError(codes.InvalidArgument, err.Error())
+	}
+
+	manifestJSON, err := yaml.YAMLToJSON(wt.GetManifestBytes())
+	if err != nil {
+		return util.NewUserError(codes.InvalidArgument, fmt.Sprintf("unable to parse manifest: %v", err))
+	}
+
+	var document interface{}
+	if err := json.Unmarshal(manifestJSON, &document); err != nil {
+		return util.NewUserError(codes.InvalidArgument, fmt.Sprintf("unable to parse manifest: %v", err))
+	}
+
+	violations, err := parsedSchema.Validate(document)
+	if err != nil {
+		return util.NewUserError(codes.InvalidArgument, err.Error())
+	}
+
+	if len(violations) > 0 {
+		return util.NewUserError(codes.InvalidArgument, "manifest does not match schema: "+strings.Join(violations, "; "))
+	}
+
+	return nil
+}
+
+// ScanWorkflowTemplates validates the latest version of every non-archived workflow template in the namespace
+// against the current validation rules, which come from ValidationArgoVersion, and returns the ones that fail.
+// The returned workflow templates have IsBroken set and the validation error as their BrokenReason.
+// Only codes.InvalidArgument validation errors mark a workflow template as broken. Other errors, like the system config,
+// kubernetes or the database being unavailable, stop the scan and are returned.
+//
+// It is meant to be run after upgrading argo or onepanel, to find the workflow templates the upgrade broke.
+// It does not use any request data, so it can be run in the background.
+func (c *Client) ScanWorkflowTemplates(namespace string) ([]*WorkflowTemplate, error) {
+	workflowTemplates := make([]*WorkflowTemplate, 0)
+	query := c.latestWorkflowTemplateVersionsSelectBuilder(namespace).
+		OrderBy("wt.name")
+	if err := c.DB.Selectx(&workflowTemplates, query); err != nil {
+		log.WithFields(log.Fields{
+			"Namespace": namespace,
+			"Error":     err.Error(),
+		}).Error("Unable to load workflow templates to scan.")
+		return nil, util.NewUserErrorWrap(err, "Workflow templates")
+	}
+
+	broken := make([]*WorkflowTemplate, 0)
+	for _, workflowTemplate := range workflowTemplates {
+		if err := c.validateWorkflowTemplate(namespace, workflowTemplate); err != nil {
+			err = workflowTemplateScanUserError(err)
+			if err.(*util.UserError).Code != codes.InvalidArgument {
+				log.WithFields(log.Fields{
+					"Namespace": namespace,
+					"UID":       workflowTemplate.UID,
+					"Error":     err.Error(),
+				}).Error("Unable to scan workflow template.")
+				return nil, err
+			}
+
+			workflowTemplate.IsBroken = true
+			workflowTemplate.BrokenReason = err.Error()
+			broken = append(broken, workflowTemplate)
+
+			log.WithFields(log.Fields{
+				"Namespace": namespace,
+				"UID":       workflowTemplate.UID,
+				"Version":   workflowTemplate.Version,
+				"Error":     err.Error(),
+			}).Warn("Workflow template no longer passes validation.")
+		}
+	}
+
+	return broken, nil
+}
+
+// CanBeScheduled reports whether the workflow template can run unattended on a cron schedule.
+// Workflows that start suspended, or that have suspend templates without a duration, wait to be resumed manually
+// so every scheduled run would hang. The reason describes the offending constructs when the template is not cron-safe.
+func CanBeScheduled(wt *WorkflowTemplate) (ok bool, reason string, err error) {
+	argoWft, err := parseWorkflowTemplateSpec(wt)
+	if err != nil {
+		return false, "", util.NewUserError(codes.InvalidArgument, fmt.Sprintf("unable to parse manifest: %v", err))
+	}
+
+	reasons := make([]string, 0)
+	if argoWft.Spec.Suspend != nil && *argoWft.Spec.Suspend {
+		reasons = append(reasons, "workflow is suspended on creation")
+	}
+	for _, template := range argoWft.Spec.Templates {
+		if template.Suspend != nil && template.Suspend.Duration == "" {
+			reasons = append(reasons, fmt.Sprintf("template '%v' suspends until it is resumed manually", template.Name))
+		}
+	}
+
+	if len(reasons) > 0 {
+		return false, strings.Join(reasons, "; "), nil
+	}
+
+	return true, "", nil
+}
+
+// ValidationResult is the outcome of validating a single manifest
+type ValidationResult struct {
+	Index int    // Position of the manifest in the input
+	Valid bool   // True if the manifest passed validation
+	Error string // The validation error, if any
+}
+
+// ValidateWorkflowTemplates validates each manifest as a workflow template in the namespace.
+// All manifests are validated, a failure does not stop the remaining ones from being checked.
+func (c *Client) ValidateWorkflowTemplates(namespace string, manifests [][]byte) ([]ValidationResult, error) {
+	results := make([]ValidationResult, len(manifests))
+	for i, manifest := range manifests {
+		results[i].Index = i
+
+		workflowTemplate := &WorkflowTemplate{
+			Manifest: string(manifest),
+		}
+		if err := c.validateWorkflowTemplate(namespace, workflowTemplate); err != nil {
+			results[i].Error = err.Error()
+			continue
+		}
+
+		results[i].Valid = true
+	}
+
+	return results, nil
+}
+
+// ValidateAllWorkflowTemplateVersions validates the stored manifest of every version of the workflow template,
+// for example to find the versions that would fail validation before upgrading argo.
+// Each version is validated with the resolved manifest it was created with, or its manifest if it has none.
+// The result has an entry for each version, with a nil error if that version is valid.
+func (c *Client) ValidateAllWorkflowTemplateVersions(namespace, uid string) (map[int64]error, error) {
+	versions := make([]*WorkflowTemplateVersion, 0)
+	query := sb.Select("wtv.version", "wtv.manifest", "wtv.resolved_manifest").
+		From("workflow_template_versions wtv").
+		Join("workflow_templates wt ON wt.id = wtv.workflow_template_id").
+		Where(sq.Eq{
+			"wt.namespace":   namespace,
+			"wt.uid":         uid,
+			"wt.is_archived": false,
+		})
+	if err := c.DB.Selectx(&versions, query); err != nil {
+		return nil, util.NewUserErrorWrap(err, "Workflow template")
+	}
+
+	// every workflow template has at least one version
+	if len(versions) == 0 {
+		return nil, c.missingWorkflowTemplateUserError(namespace, uid)
+	}
+
+	results := make(map[int64]error, len(versions))
+	for _, version := range versions {
+		workflowTemplate := &WorkflowTemplate{
+			UID:      uid,
+			Version:  version.Version,
+			Manifest: version.Manifest,
+		}
+		if version.ResolvedManifest != "" {
+			// the fragments are already resolved, so the current ones are not loaded
+			workflowTemplate.Manifest = version.ResolvedManifest
+			workflowTemplate.Fragments = make(map[string]string)
+		}
+		results[version.Version] = c.validateWorkflowTemplate(namespace, workflowTemplate)
+	}
+
+	return results, nil
+}
@@ -0,0 +1,563 @@
+package v1
+
+import (
+	"fmt"
+	"io/ioutil"
+	"strings"
+	"testing"
+
+	"github.com/argoproj/argo/pkg/apis/workflow/v1alpha1"
+	"github.com/onepanelio/core/pkg/util"
+	"github.com/onepanelio/core/pkg/util/ptr"
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/grpc/codes"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/kubernetes/fake"
+	k8stesting "k8s.io/client-go/testing"
+)
+
+// mustParseWorkflowTemplateSpec parses the manifest into an argo workflow template, failing the test on error
+func mustParseWorkflowTemplateSpec(t *testing.T, manifest string) *WorkflowTemplate {
+	workflowTemplate := &WorkflowTemplate{
+		Manifest: manifest,
+	}
+
+	argoWft, err := parseWorkflowTemplateSpec(workflowTemplate)
+	if err != nil {
+		t.Fatal(err)
+	}
+	workflowTemplate.ArgoWorkflowTemplate = argoWft
+
+	return workflowTemplate
+}
+
+// Test_validateAllowedImages tests restricting images to allowed registries
+func Test_validateAllowedImages(t *testing.T) {
+	wt := mustParseWorkflowTemplateSpec(t, defaultWorkflowTemplate)
+	spec := &wt.ArgoWorkflowTemplate.Spec.WorkflowSpec
+
+	// No prefixes means everything is allowed
+	assert.Nil(t, validateAllowedImages(spec, nil))
+
+	// All images match
+	assert.Nil(t, validateAllowedImages(spec, []string{"pytorch/", "technosophos/"}))
+
+	// Disallowed images are listed
+	err := validateAllowedImages(spec, []string{"pytorch/"})
+	assert.NotNil(t, err)
+	assert.Contains(t, err.Error(), "technosophos/slack-notify")
+	assert.NotContains(t, err.Error(), "pytorch/pytorch:latest")
+}
+
+// Test_validateEntrypoint makes sure the entrypoint has to be the name of a template
+func Test_validateEntrypoint(t *testing.T) {
+	wt := mustParseWorkflowTemplateSpec(t, defaultWorkflowTemplate)
+	spec := &wt.ArgoWorkflowTemplate.Spec.WorkflowSpec
+
+	assert.Nil(t, validateEntrypoint(spec))
+
+	spec.Entrypoint = "missing"
+	err := validateEntrypoint(spec)
+	assert.NotNil(t, err)
+	assert.Contains(t, err.Error(), "'missing'")
+}
+
+// Test_validateUniqueTemplateNames makes sure duplicate template names are rejected
+func Test_validateUniqueTemplateNames(t *testing.T) {
+	wt := mustParseWorkflowTemplateSpec(t, defaultWorkflowTemplate)
+	spec := &wt.ArgoWorkflowTemplate.Spec.WorkflowSpec
+
+	assert.Nil(t, validateUniqueTemplateNames(spec))
+
+	spec.Templates = append(spec.Templates, spec.Templates[0])
+	err := validateUniqueTemplateNames(spec)
+	assert.NotNil(t, err)
+	assert.Contains(t, err.Error(), "'"+spec.Templates[0].Name+"'")
+}
+
+// Test_validateMutuallyExclusiveFields makes sure conflicting template types and loops are reported
+func Test_validateMutuallyExclusiveFields(t *testing.T) {
+	wt := mustParseWorkflowTemplateSpec(t, defaultWorkflowTemplate)
+	assert.Nil(t, validateMutuallyExclusiveFields(&wt.ArgoWorkflowTemplate.Spec.WorkflowSpec))
+
+	wt = mustParseWorkflowTemplateSpec(t, `entrypoint: main
+templates:
+- name: main
+  container:
+    image: alpine
+  script:
+    image: alpine
+    source: echo hello
+`)
+	err := validateMutuallyExclusiveFields(&wt.ArgoWorkflowTemplate.Spec.WorkflowSpec)
+	assert.NotNil(t, err)
+	assert.Equal(t, "template 'main' can only have one of container, script, resource, steps, dag and suspend, it has container and script", err.Error())
+
+	wt = mustParseWorkflowTemplateSpec(t, `entrypoint: main
+templates:
+- name: main
+  dag:
+    tasks:
+    - name: loop
+      template: work
+      withItems: [1, 2]
+      withParam: "{{inputs.parameters.items}}"
+- name: work
+  container:
+    image: alpine
+`)
+	err = validateMutuallyExclusiveFields(&wt.ArgoWorkflowTemplate.Spec.WorkflowSpec)
+	assert.NotNil(t, err)
+	assert.Equal(t, "task 'loop' of template 'main' can only have one of withItems, withParam and withSequence, it has withItems and withParam", err.Error())
+}
+
+// Test_validateDAGTasks makes sure duplicate task names and unknown dependencies are reported
+func Test_validateDAGTasks(t *testing.T) {
+	wt := mustParseWorkflowTemplateSpec(t, `entrypoint: main
+templates:
+- name: main
+  dag:
+    tasks:
+    - name: prepare
+      template: work
+    - name: train
+      template: work
+      dependencies: [prepare]
+- name: work
+  container:
+    image: alpine
+`)
+	spec := &wt.ArgoWorkflowTemplate.Spec.WorkflowSpec
+	assert.Nil(t, validateDAGTasks(spec))
+
+	tasks := spec.Templates[0].DAG.Tasks
+	tasks[1].Dependencies = []string{"preprocess"}
+	err := validateDAGTasks(spec)
+	assert.NotNil(t, err)
+	assert.Equal(t, "task 'train' of template 'main' depends on 'preprocess', which is not a task in the template", err.Error())
+
+	tasks[1].Name = "prepare"
+	err = validateDAGTasks(spec)
+	assert.NotNil(t, err)
+	assert.Equal(t, "task name 'prepare' is used more than once in template 'main'", err.Error())
+}
+
+// Test_validateJSONManifest makes sure JSON manifests get field level errors
+func Test_validateJSONManifest(t *testing.T) {
+	// YAML is not checked
+	assert.Nil(t, validateJSONManifest([]byte(defaultWorkflowTemplate)))
+
+	valid := `{
+  "entrypoint": "main",
+  "arguments": {"parameters": [{"name": "message", "value": "hi", "displayName": "Message", "type": "input.text"}]},
+  "templates": [{"name": "main", "container": {"image": "alpine"}}]
+}`
+	assert.Nil(t, validateJSONManifest([]byte(valid)))
+
+	sensitive := `{
+  "entrypoint": "main",
+  "arguments": {"parameters": [{"name": "token", "value": "secret", "sensitive": true}]},
+  "templates": [{"name": "main", "container": {"image": "alpine"}}]
+}`
+	assert.Nil(t, validateJSONManifest([]byte(sensitive)))
+
+	grouped := `{
+  "entrypoint": "main",
+  "arguments": {"parameters": [{"name": "epochs", "value": "10", "group": "Training", "groupOrder": 1}]},
+  "templates": [{"name": "main", "container": {"image": "alpine"}}]
+}`
+	assert.Nil(t, validateJSONManifest([]byte(grouped)))
+
+	unknownField := `{"entrypont": "main", "templates": [{"name": "main", "container": {"image": "alpine"}}]}`
+	err := validateJSONManifest([]byte(unknownField))
+	assert.NotNil(t, err)
+	assert.Contains(t, err.Error(), `unknown field "entrypont"`)
+
+	badSyntax := `{"entrypoint": "main",}`
+	err = validateJSONManifest([]byte(badSyntax))
+	assert.NotNil(t, err)
+	assert.Contains(t, err.Error(), "invalid JSON manifest")
+}
+
+// Test_validateParameterOptions makes sure default values have to be one of the options
+func Test_validateParameterOptions(t *testing.T) {
+	options := []*ParameterOption{
+		{Name: "Small", Value: "small"},
+		{Name: "Large", Value: "large"},
+	}
+
+	assert.Nil(t, validateParameterOptions([]Parameter{
+		{Name: "size", Value: ptr.String("small"), Options: options},
+		{Name: "no-default", Options: options},
+		{Name: "no-options", Value: ptr.String("anything")},
+	}))
+
+	err := validateParameterOptions([]Parameter{
+		{Name: "size", Value: ptr.String("medium"), Options: options},
+	})
+	assert.NotNil(t, err)
+	assert.Contains(t, err.Error(), "'size'")
+}
+
+// Test_validateParameterDisplayOrder makes sure display orders are either all set and unique, or not used at all
+func Test_validateParameterDisplayOrder(t *testing.T) {
+	assert.Nil(t, validateParameterDisplayOrder([]Parameter{
+		{Name: "first"},
+		{Name: "second"},
+	}))
+
+	assert.Nil(t, validateParameterDisplayOrder([]Parameter{
+		{Name: "first", DisplayOrder: ptr.Int(1)},
+		{Name: "second", DisplayOrder: ptr.Int(2)},
+	}))
+
+	err := validateParameterDisplayOrder([]Parameter{
+		{Name: "first", DisplayOrder: ptr.Int(1)},
+		{Name: "second"},
+	})
+	assert.NotNil(t, err)
+	assert.Contains(t, err.Error(), "'second'")
+
+	err = validateParameterDisplayOrder([]Parameter{
+		{Name: "first", DisplayOrder: ptr.Int(1)},
+		{Name: "second", DisplayOrder: ptr.Int(1)},
+	})
+	assert.NotNil(t, err)
+	assert.Contains(t, err.Error(), "same display order")
+}
+
+// TestValidateWorkflowTemplateAgainstSchema makes sure schema violations in the manifest are reported
+func TestValidateWorkflowTemplateAgainstSchema(t *testing.T) {
+	wt := &WorkflowTemplate{
+		Manifest: defaultWorkflowTemplate,
+	}
+
+	assert.Nil(t, ValidateWorkflowTemplateAgainstSchema(wt, []byte(`{"type": "object", "required": ["entrypoint"]}`)))
+
+	err := ValidateWorkflowTemplateAgainstSchema(wt, []byte(`{"type": "object", "required": ["onExit"]}`))
+	assert.NotNil(t, err)
+	assert.Contains(t, err.Error(), "'onExit'")
+}
+
+// TestClient_validateReferencedSecretsAndConfigMaps makes sure missing secrets and config maps are reported in strict mode
+func TestClient_validateReferencedSecretsAndConfigMaps(t *testing.T) {
+	c := DefaultTestClient()
+
+	wt := mustParseWorkflowTemplateSpec(t, `entrypoint: main
+templates:
+- name: main
+  container:
+    image: alpine
+    env:
+    - name: EXISTING
+      valueFrom:
+        secretKeyRef:
+          name: onepanel
+          key: key
+    - name: MISSING
+      valueFrom:
+        secretKeyRef:
+          name: missing-secret
+          key: key
+    - name: PARAMETER
+      valueFrom:
+        secretKeyRef:
+          name: "{{inputs.parameters.secret}}"
+          key: key
+    envFrom:
+    - configMapRef:
+        name: missing-config
+`)
+	spec := &wt.ArgoWorkflowTemplate.Spec.WorkflowSpec
+
+	assert.Nil(t, c.validateReferencedSecretsAndConfigMaps("onepanel", spec))
+
+	c.StrictValidation = true
+	err := c.validateReferencedSecretsAndConfigMaps("onepanel", spec)
+	assert.NotNil(t, err)
+	assert.Equal(t, "referenced resources do not exist in namespace 'onepanel': secrets missing-secret; config maps missing-config", err.Error())
+}
+
+// TestClient_validateImagePullSecrets makes sure missing image pull secrets are reported in strict mode
+func TestClient_validateImagePullSecrets(t *testing.T) {
+	c := DefaultTestClient()
+
+	wt := mustParseWorkflowTemplateSpec(t, `entrypoint: main
+imagePullSecrets:
+- name: onepanel
+- name: missing-registry
+- name: other-registry
+templates:
+- name: main
+  container:
+    image: alpine
+`)
+	spec := &wt.ArgoWorkflowTemplate.Spec.WorkflowSpec
+
+	assert.Nil(t, c.validateImagePullSecrets("onepanel", spec))
+
+	c.StrictValidation = true
+	err := c.validateImagePullSecrets("onepanel", spec)
+	assert.NotNil(t, err)
+	assert.Equal(t, "image pull secrets do not exist in namespace 'onepanel': missing-registry, other-registry", err.Error())
+}
+
+// TestClient_validateServiceAccount makes sure a missing service account is reported in strict mode
+func TestClient_validateServiceAccount(t *testing.T) {
+	c := DefaultTestClient()
+
+	wt := mustParseWorkflowTemplateSpec(t, `entrypoint: main
+serviceAccountName: missing-account
+templates:
+- name: main
+  container:
+    image: alpine
+`)
+	spec := &wt.ArgoWorkflowTemplate.Spec.WorkflowSpec
+
+	assert.Nil(t, c.validateServiceAccount("onepanel", spec))
+
+	c.StrictValidation = true
+	err := c.validateServiceAccount("onepanel", spec)
+	assert.NotNil(t, err)
+	assert.Equal(t, "service account 'missing-account' does not exist in namespace 'onepanel'", err.Error())
+
+	_, err = c.CoreV1().ServiceAccounts("onepanel").Create(&corev1.ServiceAccount{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "missing-account",
+			Namespace: "onepanel",
+		},
+	})
+	assert.Nil(t, err)
+	assert.Nil(t, c.validateServiceAccount("onepanel", spec))
+}
+
+// TestClient_ValidateWorkflowTemplates makes sure each manifest gets its own result
+func TestClient_ValidateWorkflowTemplates(t *testing.T) {
+	c := DefaultTestClient()
+
+	manifests := [][]byte{
+		[]byte(defaultWorkflowTemplate),
+		[]byte("entrypoint: [invalid"),
+		[]byte(defaultWorkflowTemplate),
+	}
+
+	results, err := c.ValidateWorkflowTemplates("onepanel", manifests)
+	assert.Nil(t, err)
+	assert.Len(t, results, 3)
+
+	for i, result := range results {
+		assert.Equal(t, i, result.Index)
+	}
+
+	assert.True(t, results[0].Valid)
+	assert.Empty(t, results[0].Error)
+	assert.False(t, results[1].Valid)
+	assert.NotEmpty(t, results[1].Error)
+	assert.True(t, results[2].Valid)
+}
+
+// TestCanBeScheduled makes sure templates waiting on manual approval are not cron-safe
+func TestCanBeScheduled(t *testing.T) {
+	ok, reason, err := CanBeScheduled(&WorkflowTemplate{Manifest: defaultWorkflowTemplate})
+	assert.Nil(t, err)
+	assert.True(t, ok)
+	assert.Empty(t, reason)
+
+	manifest := `entrypoint: main
+templates:
+- name: main
+  steps:
+  - - name: approve
+      template: approve
+    - name: wait
+      template: wait
+- name: approve
+  suspend: {}
+- name: wait
+  suspend:
+    duration: "20"
+`
+	ok, reason, err = CanBeScheduled(&WorkflowTemplate{Manifest: manifest})
+	assert.Nil(t, err)
+	assert.False(t, ok)
+	assert.Equal(t, "template 'approve' suspends until it is resumed manually", reason)
+
+	_, _, err = CanBeScheduled(&WorkflowTemplate{Manifest: "entrypoint: [invalid"})
+	assert.NotNil(t, err)
+}
+
+// Test_validateDeclaredParameterReferences makes sure referenced workflow parameters must be declared
+func Test_validateDeclaredParameterReferences(t *testing.T) {
+	assert.Nil(t, validateDeclaredParameterReferences(&WorkflowTemplate{Manifest: defaultWorkflowTemplate}))
+
+	manifest := `entrypoint: main
+arguments:
+  parameters:
+  - name: epochs
+    value: "1"
+templates:
+- name: main
+  container:
+    image: "{{workflow.parameters.image}}"
+    args: ["{{workflow.parameters.epochs}}", "{{ workflow.parameters.batch-size }}", "{{workflow.parameters.image}}"]
+`
+	err := validateDeclaredParameterReferences(&WorkflowTemplate{Manifest: manifest})
+	if assert.NotNil(t, err) {
+		assert.Equal(t, "referenced parameters are not declared in arguments.parameters: batch-size, image", err.Error())
+	}
+}
+
+// TestClient_validateVolumeClaimStorage makes sure volume claims can not request more storage than the namespace allows
+func TestClient_validateVolumeClaimStorage(t *testing.T) {
+	c := DefaultTestClient()
+	_, err := c.CoreV1().ConfigMaps("limited").Create(&corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "onepanel",
+			Namespace: "limited",
+		},
+		Data: map[string]string{
+			"maxVolumeClaimStorage": "1Gi",
+		},
+	})
+	assert.Nil(t, err)
+
+	wt := &WorkflowTemplate{Manifest: defaultWorkflowTemplate}
+
+	// the onepanel namespace has no limit
+	assert.Nil(t, c.validateVolumeClaimStorage("onepanel", wt))
+
+	err = c.validateVolumeClaimStorage("limited", wt)
+	if assert.NotNil(t, err) {
+		assert.Equal(t, "volume claim templates request more than the namespace maximum of 1Gi storage: data (2Gi), output (2Gi)", err.Error())
+	}
+}
+
+// TestClient_CreateWorkflowTemplateVersion_VolumeClaimStorage makes sure new versions, including those added by
+// imports, can not request more storage than the namespace allows
+func TestClient_CreateWorkflowTemplateVersion_VolumeClaimStorage(t *testing.T) {
+	c := DefaultTestClient()
+	clearDatabase(t)
+
+	namespace := "limited-versions"
+	_, err := c.CoreV1().ConfigMaps(namespace).Create(&corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "onepanel",
+			Namespace: namespace,
+		},
+		Data: map[string]string{
+			"maxVolumeClaimStorage": "5Gi",
+		},
+	})
+	assert.Nil(t, err)
+
+	created, err := c.CreateWorkflowTemplate(namespace, &WorkflowTemplate{
+		Name:     "test",
+		Manifest: defaultWorkflowTemplate,
+	})
+	assert.Nil(t, err)
+
+	tooLarge := strings.Replace(defaultWorkflowTemplate, "storage: 2Gi", "storage: 10Gi", 1)
+	_, err = c.CreateWorkflowTemplateVersion(namespace, &WorkflowTemplate{
+		UID:      created.UID,
+		Name:     created.Name,
+		Manifest: tooLarge,
+	})
+	userErr, ok := err.(*util.UserError)
+	if assert.True(t, ok) {
+		assert.Equal(t, codes.InvalidArgument, userErr.Code)
+	}
+
+	_, err = c.ImportWorkflowTemplate(namespace, &WorkflowTemplate{
+		Name:     created.Name,
+		Manifest: tooLarge,
+	}, ImportStrategyNewVersion)
+	userErr, ok = err.(*util.UserError)
+	if assert.True(t, ok) {
+		assert.Equal(t, codes.InvalidArgument, userErr.Code)
+	}
+}
+
+// TestClient_CreateWorkflowTemplateVersion_DeclaredParameterReferences makes sure new versions are validated like created templates
+func TestClient_CreateWorkflowTemplateVersion_DeclaredParameterReferences(t *testing.T) {
+	c := DefaultTestClient()
+	clearDatabase(t)
+
+	namespace := "onepanel"
+	created, err := c.CreateWorkflowTemplate(namespace, &WorkflowTemplate{
+		Name:     "test",
+		Manifest: defaultWorkflowTemplate,
+	})
+	assert.Nil(t, err)
+
+	undeclared := strings.Replace(defaultWorkflowTemplate, "{{workflow.parameters.command}}", "{{workflow.parameters.undeclared}}", 1)
+	assert.NotEqual(t, defaultWorkflowTemplate, undeclared)
+
+	_, err = c.CreateWorkflowTemplateVersion(namespace, &WorkflowTemplate{
+		UID:      created.UID,
+		Name:     created.Name,
+		Manifest: undeclared,
+	})
+	userErr, ok := err.(*util.UserError)
+	if assert.True(t, ok) {
+		assert.Equal(t, codes.InvalidArgument, userErr.Code)
+	}
+}
+
+// Test_ValidationArgoVersion makes sure ValidationArgoVersion is the version of the argo module in go.mod
+func Test_ValidationArgoVersion(t *testing.T) {
+	// the argo module versions, which are commits, and the argo release they are
+	releases := map[string]string{
+		"v0.0.0-20200331233432-4d1175eb68f6": "v2.7",
+	}
+
+	goMod, err := ioutil.ReadFile("../go.mod")
+	if !assert.Nil(t, err) {
+		return
+	}
+
+	moduleVersion := ""
+	for _, line := range strings.Split(string(goMod), "\n") {
+		fields := strings.Fields(line)
+		if len(fields) >= 2 && fields[0] == "github.com/argoproj/argo" {
+			moduleVersion = fields[1]
+		}
+	}
+
+	release, ok := releases[moduleVersion]
+	if assert.True(t, ok, "the argo module changed to %v, add its release and update ValidationArgoVersion", moduleVersion) {
+		assert.Equal(t, release, ValidationArgoVersion)
+	}
+}
+
+// TestClient_validateArtifactRepository makes sure only a missing namespace config means there is no artifact repository
+func TestClient_validateArtifactRepository(t *testing.T) {
+	spec := &v1alpha1.WorkflowSpec{
+		Templates: []v1alpha1.Template{
+			{
+				Name: "main",
+				Outputs: v1alpha1.Outputs{
+					Artifacts: []v1alpha1.Artifact{{Name: "model", Path: "/mnt/output"}},
+				},
+			},
+		},
+	}
+
+	k8sFake := fake.NewSimpleClientset()
+	c := &Client{Interface: k8sFake}
+	err := c.validateArtifactRepository("onepanel", spec)
+	if assert.NotNil(t, err) {
+		assert.Contains(t, err.Error(), "has no artifact repository configured")
+	}
+
+	k8sFake.PrependReactor("get", "configmaps",
+		func(action k8stesting.Action) (bool, runtime.Object, error) {
+			return true, nil, fmt.Errorf("connection refused")
+		})
+	err = c.validateArtifactRepository("onepanel", spec)
+	if assert.NotNil(t, err) {
+		assert.Equal(t, "connection refused", err.Error())
+	}
+}
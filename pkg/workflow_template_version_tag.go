@@ -0,0 +1,82 @@
+package v1
+
+import (
+	"database/sql"
+	"fmt"
+	"time"
+
+	sq "github.com/Masterminds/squirrel"
+	"github.com/onepanelio/core/pkg/util"
+	log "github.com/sirupsen/logrus"
+	"google.golang.org/grpc/codes"
+)
+
+// TagWorkflowTemplateVersion sets the tag, like "stable", on a version of the non-archived workflow template.
+// A tag is on at most one version of a workflow template, so if it is already set on another version it is moved.
+func (c *Client) TagWorkflowTemplateVersion(namespace, uid, tag string, version int64) error {
+	defer c.InvalidateWorkflowTemplateCache(namespace, uid)
+
+	if tag == "" || len(tag) > 255 {
+		return util.NewUserError(codes.InvalidArgument, "Tag must be between 1 and 255 characters.")
+	}
+	if version <= 0 {
+		return util.NewUserError(codes.InvalidArgument, "Version must be greater than 0.")
+	}
+
+	workflowTemplateVersion, err := c.getWorkflowTemplateVersionByUID(namespace, uid, version)
+	if err != nil {
+		return err
+	}
+
+	workflowTemplateID, err := c.getWorkflowTemplateID(namespace, uid)
+	if err != nil {
+		return err
+	}
+
+	_, err = sb.Insert("workflow_template_version_tags").
+		SetMap(sq.Eq{
+			"workflow_template_id":         workflowTemplateID,
+			"workflow_template_version_id": workflowTemplateVersion.ID,
+			"tag":                          tag,
+		}).
+		Suffix("ON CONFLICT (workflow_template_id, tag) DO UPDATE SET workflow_template_version_id = EXCLUDED.workflow_template_version_id, modified_at = ?", time.Now().UTC()).
+		RunWith(c.DB).
+		Exec()
+	if err != nil {
+		log.WithFields(log.Fields{
+			"Namespace": namespace,
+			"UID":       uid,
+			"Tag":       tag,
+			"Version":   version,
+			"Error":     err.Error(),
+		}).Error("Could not tag workflow template version.")
+		return util.NewUserError(codes.Unknown, "Unable to tag workflow template version.")
+	}
+
+	return nil
+}
+
+// GetWorkflowTemplateByTag returns the version of the non-archived workflow template that has the tag.
+// The result is the same as GetWorkflowTemplate for that version.
+func (c *Client) GetWorkflowTemplateByTag(namespace, uid, tag string) (*WorkflowTemplate, error) {
+	query := sb.Select("wtv.version").
+		From("workflow_template_version_tags t").
+		Join("workflow_template_versions wtv ON wtv.id = t.workflow_template_version_id").
+		Join("workflow_templates wt ON wt.id = t.workflow_template_id").
+		Where(sq.Eq{
+			"wt.namespace":   namespace,
+			"wt.uid":         uid,
+			"wt.is_archived": false,
+			"t.tag":          tag,
+		})
+
+	version := int64(0)
+	if err := c.DB.Getx(&version, query); err != nil {
+		if err == sql.ErrNoRows {
+			return nil, util.NewUserError(codes.NotFound, fmt.Sprintf("No version of the workflow template is tagged '%v'.", tag))
+		}
+		return nil, err
+	}
+
+	return c.GetWorkflowTemplate(namespace, uid, version)
+}
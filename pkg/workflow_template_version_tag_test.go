@@ -0,0 +1,44 @@
+package v1
+
+import (
+	"github.com/onepanelio/core/pkg/util"
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/grpc/codes"
+	"testing"
+)
+
+// TestClient_TagWorkflowTemplateVersion makes sure a tag resolves to the version it was last applied to
+func TestClient_TagWorkflowTemplateVersion(t *testing.T) {
+	c := DefaultTestClient()
+	clearDatabase(t)
+
+	namespace := "onepanel"
+	created, _ := c.CreateWorkflowTemplate(namespace, &WorkflowTemplate{
+		Name:     "test",
+		Manifest: defaultWorkflowTemplate,
+	})
+	firstVersion := created.Version
+
+	_, err := c.GetWorkflowTemplateByTag(namespace, created.UID, "stable")
+	userErr, ok := err.(*util.UserError)
+	assert.True(t, ok)
+	assert.Equal(t, codes.NotFound, userErr.Code)
+
+	assert.Nil(t, c.TagWorkflowTemplateVersion(namespace, created.UID, "stable", firstVersion))
+
+	second, _ := c.CreateWorkflowTemplateVersion(namespace, &WorkflowTemplate{
+		UID:      created.UID,
+		Name:     created.Name,
+		Manifest: defaultWorkflowTemplate,
+	})
+
+	tagged, err := c.GetWorkflowTemplateByTag(namespace, created.UID, "stable")
+	assert.Nil(t, err)
+	assert.Equal(t, firstVersion, tagged.Version)
+
+	assert.Nil(t, c.TagWorkflowTemplateVersion(namespace, created.UID, "stable", second.Version))
+
+	tagged, err = c.GetWorkflowTemplateByTag(namespace, created.UID, "stable")
+	assert.Nil(t, err)
+	assert.Equal(t, second.Version, tagged.Version)
+}
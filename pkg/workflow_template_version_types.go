@@ -1,6 +1,8 @@
 package v1
 
 import (
+	"crypto/sha256"
+	"encoding/hex"
 	"github.com/onepanelio/core/pkg/util/sql"
 	"github.com/onepanelio/core/pkg/util/types"
 	"time"
@@ -19,7 +21,36 @@ type WorkflowTemplateVersion struct {
 	WorkflowTemplate *WorkflowTemplate `db:"workflow_template"`
 	Labels           types.JSONLabels
 	Parameters       []Parameter
-	ParametersBytes  []byte `db:"parameters"` // to load from database
+	ParametersBytes  []byte `db:"parameters"`        // to load from database
+	ResolvedManifest string `db:"resolved_manifest"` // The manifest with its fragments and base resolved when the version was created, see resolvedManifest.
+}
+
+// ManifestHash returns the hex encoded sha256 hash of the manifest, identifying its exact content.
+func ManifestHash(manifest string) string {
+	hash := sha256.Sum256([]byte(manifest))
+	return hex.EncodeToString(hash[:])
+}
+
+// ManifestHash returns the hash of the version's manifest, see ManifestHash
+func (wtv *WorkflowTemplateVersion) ManifestHash() string {
+	return ManifestHash(wtv.Manifest)
+}
+
+// toWorkflowTemplate returns the version as a WorkflowTemplate. The WorkflowTemplate of the version must be loaded.
+func (wtv *WorkflowTemplateVersion) toWorkflowTemplate() *WorkflowTemplate {
+	return &WorkflowTemplate{
+		ID:                        wtv.WorkflowTemplate.ID,
+		CreatedAt:                 wtv.CreatedAt.UTC(),
+		UID:                       wtv.WorkflowTemplate.UID,
+		Namespace:                 wtv.WorkflowTemplate.Namespace,
+		Name:                      wtv.WorkflowTemplate.Name,
+		Manifest:                  wtv.Manifest,
+		Version:                   wtv.Version,
+		IsLatest:                  wtv.IsLatest,
+		IsArchived:                wtv.WorkflowTemplate.IsArchived,
+		Labels:                    wtv.Labels,
+		WorkflowTemplateVersionID: wtv.ID,
+	}
 }
 
 // WorkflowTemplateVersionsToIDs returns an array of ids from the input WorkflowTemplateVersion with no duplicates.
@@ -41,6 +72,6 @@ func WorkflowTemplateVersionsToIDs(resources []*WorkflowTemplateVersion) (ids []
 // getWorkflowTemplateVersionColumns returns all of the columns for workflow template versions modified by alias, destination.
 // see formatColumnSelect
 func getWorkflowTemplateVersionColumns(aliasAndDestination ...string) []string {
-	columns := []string{"id", "created_at", "version", "is_latest", "manifest", "parameters", "labels"}
+	columns := []string{"id", "created_at", "version", "is_latest", "manifest", "parameters", "labels", "resolved_manifest"}
 	return sql.FormatColumnSelect(columns, aliasAndDestination...)
 }
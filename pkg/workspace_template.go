@@ -831,7 +831,7 @@ func (c *Client) createWorkspaceTemplate(namespace string, workspaceTemplate *Wo
 	// validate workflow template
 	if err := c.validateWorkflowTemplate(namespace, workspaceTemplate.WorkflowTemplate); err != nil {
 		message := strings.Replace(err.Error(), "{{workflow.", "{{workspace.", -1)
-		return nil, util.NewUserError(codes.InvalidArgument, message)
+		return nil, workflowTemplateValidationUserError(err, message)
 	}
 	workspaceTemplate.WorkflowTemplate, _, err = c.createWorkflowTemplate(namespace, workspaceTemplate.WorkflowTemplate)
 	if err != nil {
@@ -160,6 +160,48 @@ func TestClient_CreateWorkspace(t *testing.T) {
 	testClientCreateWorkspaceSuccess(t)
 }
 
+// TestClient_CreateWorkspace_RequiredParameters makes sure workspaces are not rejected for required parameters,
+// which are only checked for executions submitted through the API
+func TestClient_CreateWorkspace_RequiredParameters(t *testing.T) {
+	c := DefaultTestClient()
+	clearDatabase(t)
+
+	namespace := "onepanel"
+
+	manifest := `arguments:
+  parameters:
+  - name: dataset
+    value: ""
+    required: true
+` + jupyterLabWorkspaceManifest
+	workspaceTemplate, err := c.CreateWorkspaceTemplate(namespace, &WorkspaceTemplate{
+		Name:     "test",
+		Manifest: manifest,
+	})
+	assert.Nil(t, err)
+
+	workspace, err := c.CreateWorkspace(namespace, &Workspace{
+		Name: "test",
+		WorkspaceTemplate: &WorkspaceTemplate{
+			UID:     workspaceTemplate.UID,
+			Version: workspaceTemplate.Version,
+		},
+		Parameters: []Parameter{
+			{
+				Name:  "workflow-execution-name",
+				Value: ptr.String("test"),
+			},
+		},
+	})
+	assert.Nil(t, err)
+
+	err = c.ArchiveWorkspace(namespace, workspace.UID, Parameter{
+		Name:  "workflow-execution-name",
+		Value: ptr.String("test-archive"),
+	})
+	assert.Nil(t, err)
+}
+
 func TestClient_ArchiveWorkspace(t *testing.T) {
 	c := DefaultTestClient()
 	clearDatabase(t)
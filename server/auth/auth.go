@@ -6,6 +6,7 @@ import (
 	"encoding/hex"
 	"errors"
 	"fmt"
+	"github.com/dgrijalva/jwt-go"
 	"github.com/onepanelio/core/api"
 	"github.com/onepanelio/core/pkg/util"
 	log "github.com/sirupsen/logrus"
@@ -73,6 +74,20 @@ func getBearerToken(ctx context.Context) (*string, bool) {
 	return nil, false
 }
 
+// getActor returns the user the bearer token belongs to, so it can be recorded in audit logs.
+// Service account tokens are JWTs whose subject is the service account. The token is not verified here,
+// kubernetes verifies it on every call the client makes. An empty string is returned for other tokens.
+func getActor(bearerToken string) string {
+	claims := jwt.MapClaims{}
+	if _, _, err := new(jwt.Parser).ParseUnverified(bearerToken, claims); err != nil {
+		return ""
+	}
+
+	subject, _ := claims["sub"].(string)
+
+	return subject
+}
+
 func getClient(ctx context.Context, kubeConfig *v1.Config, db *v1.DB, sysConfig v1.SystemConfig) (context.Context, error) {
 	if kubeConfig == nil {
 		return nil, fmt.Errorf("getClient - nil passed in for kubeConfig")
@@ -96,6 +111,7 @@ func getClient(ctx context.Context, kubeConfig *v1.Config, db *v1.DB, sysConfig
 		return nil, err
 	}
 	client.Token = kubeConfig.BearerToken
+	client.Actor = getActor(client.Token)
 
 	return context.WithValue(ctx, ContextClientKey, client), nil
 }
@@ -95,6 +95,10 @@ func (s *WorkflowServer) CreateWorkflowExecution(ctx context.Context, req *api.C
 		return nil, err
 	}
 
+	if err := client.ValidateRequiredParameters(req.Namespace, workflowTemplate.UID, workflowTemplate.Version, workflow.Parameters); err != nil {
+		return nil, err
+	}
+
 	wf, err := client.CreateWorkflowExecution(req.Namespace, workflow, workflowTemplate)
 	if err != nil {
 		return nil, err